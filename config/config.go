@@ -5,11 +5,21 @@ import (
 )
 
 type Config struct {
-	AppConfig    AppConfig
-	DBConfig     DBConfig
-	PulsarConfig PulsarConfig
-	KafkaConfig  KafkaConfig
-	FFConfig     FFConfig
+	AppConfig        AppConfig
+	DBConfig         DBConfig
+	PulsarConfig     PulsarConfig
+	KafkaConfig      KafkaConfig
+	FFConfig         FFConfig
+	RoutingConfig    RoutingConfig
+	BackfillConfig   BackfillConfig
+	LedgerConfig     LedgerConfig
+	CastEventsConfig CastEventsConfig
+	SourcesConfig    SourcesConfig
+	HistoryConfig    HistoryConfig
+	ImagesConfig     ImagesConfig
+	DedupeConfig     DedupeConfig
+	RateLimitConfig  RateLimitConfig
+	AdminConfig      AdminConfig
 }
 
 type AppConfig struct {
@@ -19,12 +29,16 @@ type AppConfig struct {
 }
 
 type DBConfig struct {
+	// Dialect is one of mysql, postgres or sqlite.
+	Dialect  string `default:"mysql" env:"DBDIALECT"`
 	Host     string `default:"localhost" env:"DBHOST"`
 	DataBase string `default:"weeb" env:"DBNAME"`
 	User     string `default:"weeb" env:"DBUSERNAME"`
 	Password string `required:"true" env:"DBPASSWORD" default:"mysecretpassword"`
 	Port     uint   `default:"3306" env:"DBPORT"`
 	SSLMode  string `default:"false" env:"DBSSL"`
+	// SQLitePath is the database file used by the sqlite dialect.
+	SQLitePath string `default:"weeb.db" env:"DBSQLITEPATH"`
 }
 
 type PulsarConfig struct {
@@ -32,6 +46,12 @@ type PulsarConfig struct {
 	Topic            string `default:"public/default/myanimelist.public.anime" env:"PULSARTOPIC"`
 	SubscribtionName string `default:"my-sub" env:"PULSARSUBSCRIPTIONNAME"`
 	ProducerTopic    string `default:"public/default/myanimelist.public.anime-algolia" env:"PULSARPRODUCERTOPIC"`
+
+	// The concurrency of a pipeline is its number of workers. Messages of one
+	// entity always go to the same worker, 1 processes everything in order.
+	CharacterConcurrency int `default:"1" env:"PULSAR_CHARACTER_CONCURRENCY"`
+	StaffConcurrency     int `default:"1" env:"PULSAR_STAFF_CONCURRENCY"`
+	LinkConcurrency      int `default:"1" env:"PULSAR_LINK_CONCURRENCY"`
 }
 
 type KafkaConfig struct {
@@ -46,6 +66,113 @@ type FFConfig struct {
 	BaseURL string `default:"http://flagsmith-api.weeb.svc.cluster.local" env:"FF_BASE_URL"`
 }
 
+// RoutingConfig maps each outbound event type to a comma separated list of
+// sinks. A sink is written as kind:target, e.g. kafka:image-sync,
+// pulsar:public/default/image-sync, webhook:https://host/path or
+// file:/tmp/events.jsonl.
+type RoutingConfig struct {
+	Enabled               bool   `default:"false" env:"ROUTING_ENABLED"`
+	CharacterSinks        string `default:"" env:"ROUTING_CHARACTER_SINKS"`
+	StaffSinks            string `default:"" env:"ROUTING_STAFF_SINKS"`
+	LinkSinks             string `default:"" env:"ROUTING_LINK_SINKS"`
+	CastSinks             string `default:"" env:"ROUTING_CAST_SINKS"`
+	MaxRetries            uint64 `default:"3" env:"ROUTING_MAX_RETRIES"`
+	WebhookTimeoutSeconds int    `default:"10" env:"ROUTING_WEBHOOK_TIMEOUT_SECONDS"`
+}
+
+type BackfillConfig struct {
+	BatchSize      int     `default:"500" env:"BACKFILL_BATCH_SIZE"`
+	RatePerSecond  float64 `default:"20" env:"BACKFILL_RATE_PER_SECOND"`
+	CheckpointFile string  `default:"backfill-images.checkpoint.json" env:"BACKFILL_CHECKPOINT_FILE"`
+	CDNPrefix      string  `default:"" env:"BACKFILL_CDN_PREFIX"`
+}
+
+// LedgerConfig controls the processed-message ledger used to skip
+// redelivered messages.
+type LedgerConfig struct {
+	Enabled                bool `default:"false" env:"LEDGER_ENABLED"`
+	RetentionHours         int  `default:"168" env:"LEDGER_RETENTION_HOURS"`
+	CleanupIntervalMinutes int  `default:"60" env:"LEDGER_CLEANUP_INTERVAL_MINUTES"`
+}
+
+// CastEventsConfig controls the debounced anime_cast_changed events of the
+// pipelines.
+type CastEventsConfig struct {
+	Enabled   bool `default:"false" env:"CAST_EVENTS_ENABLED"`
+	WindowMs  int  `default:"5000" env:"CAST_EVENTS_WINDOW_MS"`
+	MaxWaitMs int  `default:"30000" env:"CAST_EVENTS_MAX_WAIT_MS"`
+
+	// Events that cannot be sent are retried, the delay doubles from RetryMs
+	// up to MaxRetryMs.
+	RetryMs    int `default:"1000" env:"CAST_EVENTS_RETRY_MS"`
+	MaxRetryMs int `default:"60000" env:"CAST_EVENTS_MAX_RETRY_MS"`
+}
+
+// SourcesConfig names the source each pipeline ingests from and ranks the
+// sources. Priorities is a comma separated list of name:priority, e.g.
+// curated:100,scraper:10, unlisted sources have priority 0. A field last set
+// by a source with a higher priority is not overwritten by a lower one.
+type SourcesConfig struct {
+	Enabled         bool   `default:"false" env:"SOURCES_ENABLED"`
+	Priorities      string `default:"" env:"SOURCES_PRIORITIES"`
+	CharacterSource string `default:"default" env:"SOURCES_CHARACTER"`
+	StaffSource     string `default:"default" env:"SOURCES_STAFF"`
+	LinkSource      string `default:"default" env:"SOURCES_LINK"`
+}
+
+// HistoryConfig controls the entity_history table, the before and after
+// snapshots of every change applied by the kafka and pulsar pipelines,
+// replay, merges and live rebuilds.
+type HistoryConfig struct {
+	Enabled bool `default:"false" env:"HISTORY_ENABLED"`
+}
+
+// ImagesConfig is the allowlist of image urls sent to image-sync, all comma
+// separated. An empty Hosts allows every host, a host also allows its
+// subdomains. TrackingParams are removed from the query, a trailing *
+// matches a prefix.
+type ImagesConfig struct {
+	Schemes        string `default:"https,http" env:"IMAGES_ALLOWED_SCHEMES"`
+	Hosts          string `default:"" env:"IMAGES_ALLOWED_HOSTS"`
+	TrackingParams string `default:"utm_*,fbclid,gclid,dclid,msclkid,yclid,mc_cid,mc_eid,igshid,_ga,ref_src" env:"IMAGES_TRACKING_PARAMS"`
+}
+
+// DedupeConfig controls the cache of image requests sent to
+// image-sync, an image of an entity is not sent again within TTLHours.
+// Backend is one of memory, database or redis.
+type DedupeConfig struct {
+	Enabled                bool   `default:"false" env:"IMAGE_DEDUPE_ENABLED"`
+	Backend                string `default:"memory" env:"IMAGE_DEDUPE_BACKEND"`
+	TTLHours               int    `default:"168" env:"IMAGE_DEDUPE_TTL_HOURS"`
+	MemorySize             int    `default:"100000" env:"IMAGE_DEDUPE_MEMORY_SIZE"`
+	CleanupIntervalMinutes int    `default:"60" env:"IMAGE_DEDUPE_CLEANUP_INTERVAL_MINUTES"`
+	RedisAddr              string `default:"localhost:6379" env:"IMAGE_DEDUPE_REDIS_ADDR"`
+	RedisPassword          string `default:"" env:"IMAGE_DEDUPE_REDIS_PASSWORD"`
+	RedisDB                int    `default:"0" env:"IMAGE_DEDUPE_REDIS_DB"`
+}
+
+// RateLimitConfig are the token-bucket limits of the pipelines, comma
+// separated name=rate[/burst] entries with the rate in messages per second.
+// Consume names a pipeline (character, staff, link), Produce a sink as
+// kind:target (kafka:image-sync). Flag names a feature flag whose JSON value
+// overrides the limits at runtime, polled every FlagPollSeconds. Consume
+// defaults to 20 messages per second, the pace the pulsar consumers kept
+// with a pause after every message.
+type RateLimitConfig struct {
+	Consume         string `default:"character=20,staff=20,link=20" env:"RATE_LIMIT_CONSUME"`
+	Produce         string `default:"" env:"RATE_LIMIT_PRODUCE"`
+	Flag            string `default:"" env:"RATE_LIMIT_FLAG"`
+	FlagPollSeconds int    `default:"30" env:"RATE_LIMIT_FLAG_POLL_SECONDS"`
+}
+
+// AdminConfig controls the admin api of the pipelines, a Port of 0 disables
+// it. Token is the bearer token the rate limit endpoints require, it must
+// be set with a Port.
+type AdminConfig struct {
+	Port  int    `default:"0" env:"ADMIN_PORT"`
+	Token string `default:"" env:"ADMIN_TOKEN"`
+}
+
 func LoadConfigOrPanic() Config {
 	var config = Config{}
 	configor.Load(&config, "config/config.dev.json")
@@ -2,20 +2,29 @@ package db
 
 import (
 	"embed"
+	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
 	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/httpfs"
 	_ "github.com/golang-migrate/migrate/v4/source/httpfs"
 	"github.com/weeb-vip/character-staff-sync/config"
 	"github.com/weeb-vip/character-staff-sync/internal/db"
 	"net/http"
+	"sync"
 )
 
 var (
-	//go:embed migrations/*.sql
+	// migrations are maintained per dialect in migrations/<dialect>
+	//go:embed migrations/*/*.sql
 	migrations embed.FS
+
+	registerOnce sync.Once
+	sourceDir    string
 )
 
 type driver struct {
@@ -23,7 +32,7 @@ type driver struct {
 }
 
 func (d *driver) Open(rawURL string) (source.Driver, error) {
-	err := d.PartialDriver.Init(http.FS(migrations), "migrations")
+	err := d.PartialDriver.Init(http.FS(migrations), sourceDir)
 	if err != nil {
 		return nil, err
 	}
@@ -33,13 +42,33 @@ func (d *driver) Open(rawURL string) (source.Driver, error) {
 func getMigration() (*migrate.Migrate, error) {
 	cfg := config.LoadConfigOrPanic()
 	database := db.NewDB(cfg.DBConfig)
+	return getMigrationForDB(database, cfg.DBConfig.DataBase)
+}
+
+func getMigrationForDB(database *db.DB, databaseName string) (*migrate.Migrate, error) {
 	sqldb, err := database.DB.DB()
 	if err != nil {
 		return nil, err
 	}
-	dbdriver, err := mysql.WithInstance(sqldb, &mysql.Config{})
+
+	var dbdriver migratedb.Driver
+	switch database.Dialect {
+	case db.DialectMySQL:
+		dbdriver, err = mysql.WithInstance(sqldb, &mysql.Config{})
+	case db.DialectPostgres:
+		dbdriver, err = postgres.WithInstance(sqldb, &postgres.Config{})
+	case db.DialectSQLite:
+		dbdriver, err = sqlite3.WithInstance(sqldb, &sqlite3.Config{})
+	default:
+		return nil, fmt.Errorf("no migrations for dialect %q", database.Dialect)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sourceDir = "migrations/" + database.Dialect
 	// log files in migrations folder
-	files, err := migrations.ReadDir("migrations")
+	files, err := migrations.ReadDir(sourceDir)
 	if err != nil {
 		return nil, err
 	}
@@ -47,9 +76,11 @@ func getMigration() (*migrate.Migrate, error) {
 		println(file.Name())
 	}
 
-	source.Register("embed", &driver{})
+	registerOnce.Do(func() {
+		source.Register("embed", &driver{})
+	})
 
-	return migrate.NewWithDatabaseInstance("embed://", cfg.DBConfig.DataBase, dbdriver)
+	return migrate.NewWithDatabaseInstance("embed://", databaseName, dbdriver)
 }
 
 func MigrateUp() error {
@@ -61,6 +92,21 @@ func MigrateUp() error {
 	return m.Up()
 }
 
+// MigrateUpDB migrates an already opened database, used by the pipeline
+// harness to prepare a scratch database.
+func MigrateUpDB(database *db.DB) error {
+	m, err := getMigrationForDB(database, "harness")
+	if err != nil {
+		return err
+	}
+
+	err = m.Up()
+	if err == migrate.ErrNoChange {
+		return nil
+	}
+	return err
+}
+
 func MigrateDown() error {
 	m, err := getMigration()
 	if err != nil {
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS processed_message
+(
+    id              char(64) PRIMARY KEY,
+    pipeline        varchar(32) NOT NULL,
+    message_key     varchar(255),
+    source_position varchar(255),
+    processed_at    datetime(3) NOT NULL,
+    UNIQUE KEY processed_message_message_key (pipeline, message_key),
+    UNIQUE KEY processed_message_source_position (pipeline, source_position),
+    KEY processed_message_processed_at (processed_at)
+);
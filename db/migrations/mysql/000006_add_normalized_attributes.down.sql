@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS anime_staff_hobby;
+
+ALTER TABLE anime_staff DROP INDEX anime_staff_birthday;
+ALTER TABLE anime_staff DROP COLUMN birthday_month;
+ALTER TABLE anime_staff DROP COLUMN birthday_day;
+ALTER TABLE anime_staff DROP COLUMN birthday_year;
+ALTER TABLE anime_staff DROP COLUMN blood_type_code;
+
+ALTER TABLE anime_character DROP INDEX anime_character_height_cm;
+ALTER TABLE anime_character DROP INDEX anime_character_birthday;
+ALTER TABLE anime_character DROP COLUMN height_cm;
+ALTER TABLE anime_character DROP COLUMN weight_kg;
+ALTER TABLE anime_character DROP COLUMN birthday_month;
+ALTER TABLE anime_character DROP COLUMN birthday_day;
+ALTER TABLE anime_character DROP COLUMN birthday_year;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS search_document
+(
+    entity_type varchar(16)  NOT NULL,
+    entity_id   char(36)     NOT NULL,
+    name        varchar(512) NOT NULL,
+    summary     text         NOT NULL,
+    updated_at  datetime(3)  NOT NULL,
+    PRIMARY KEY (entity_type, entity_id),
+    FULLTEXT KEY search_document_name (name),
+    FULLTEXT KEY search_document_name_summary (name, summary)
+);
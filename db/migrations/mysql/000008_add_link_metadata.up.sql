@@ -0,0 +1,6 @@
+ALTER TABLE anime_character_staff_link ADD COLUMN language varchar(30) NOT NULL DEFAULT '';
+ALTER TABLE anime_character_staff_link ADD COLUMN role_type varchar(30) NOT NULL DEFAULT 'voice';
+ALTER TABLE anime_character_staff_link ADD COLUMN notes text;
+
+CREATE INDEX anime_character_staff_link_character_language ON anime_character_staff_link (character_id, language);
+CREATE INDEX anime_character_staff_link_staff_language ON anime_character_staff_link (staff_id, language);
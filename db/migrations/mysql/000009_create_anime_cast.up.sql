@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS anime_cast
+(
+    anime_id          varchar(36)  NOT NULL,
+    character_id      char(36)     NOT NULL,
+    staff_id          char(36)     NOT NULL,
+    language          varchar(30)  NOT NULL,
+    character_name    varchar(255) NOT NULL,
+    character_role    varchar(255) NOT NULL,
+    character_image   text         NOT NULL,
+    staff_given_name  varchar(255) NOT NULL,
+    staff_family_name varchar(255) NOT NULL,
+    staff_image       text         NOT NULL,
+    role_type         varchar(30)  NOT NULL,
+    updated_at        datetime(3)  NOT NULL,
+    PRIMARY KEY (anime_id, character_id, staff_id, language)
+);
+
+CREATE INDEX anime_cast_character_id ON anime_cast (character_id);
+CREATE INDEX anime_cast_staff_id ON anime_cast (staff_id);
+
+INSERT INTO anime_cast (anime_id, character_id, staff_id, language,
+    character_name, character_role, character_image,
+    staff_given_name, staff_family_name, staff_image, role_type, updated_at)
+SELECT c.anime_id, l.character_id, l.staff_id, l.language,
+       MIN(c.name), MIN(c.role), MIN(COALESCE(c.image, '')),
+       MIN(COALESCE(s.given_name, l.staff_given_name)), MIN(COALESCE(s.family_name, l.staff_family_name)),
+       MIN(COALESCE(s.image, '')), MIN(l.role_type), CURRENT_TIMESTAMP
+FROM anime_character_staff_link l
+JOIN anime_character c ON c.id = l.character_id
+LEFT JOIN anime_staff s ON s.id = l.staff_id
+GROUP BY c.anime_id, l.character_id, l.staff_id, l.language;
@@ -0,0 +1,4 @@
+ALTER TABLE anime_character DROP INDEX anime_character_name_key;
+ALTER TABLE anime_staff DROP INDEX anime_staff_name_key;
+ALTER TABLE anime_character DROP COLUMN name_key;
+ALTER TABLE anime_staff DROP COLUMN name_key;
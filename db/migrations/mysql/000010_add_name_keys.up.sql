@@ -0,0 +1,5 @@
+ALTER TABLE anime_character ADD COLUMN name_key varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE anime_staff ADD COLUMN name_key varchar(512) NOT NULL DEFAULT '';
+
+CREATE INDEX anime_character_name_key ON anime_character (name_key);
+CREATE INDEX anime_staff_name_key ON anime_staff (name_key);
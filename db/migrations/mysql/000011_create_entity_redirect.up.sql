@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS entity_redirect
+(
+    entity_type varchar(16) NOT NULL,
+    old_id      char(36)    NOT NULL,
+    new_id      char(36)    NOT NULL,
+    merged_at   datetime(3) NOT NULL,
+    PRIMARY KEY (entity_type, old_id),
+    KEY entity_redirect_new_id (entity_type, new_id)
+);
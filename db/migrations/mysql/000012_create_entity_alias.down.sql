@@ -0,0 +1,8 @@
+ALTER TABLE search_document
+    DROP INDEX search_document_name,
+    DROP INDEX search_document_name_summary,
+    ADD FULLTEXT KEY search_document_name (name),
+    ADD FULLTEXT KEY search_document_name_summary (name, summary);
+ALTER TABLE search_document DROP COLUMN aliases;
+
+DROP TABLE IF EXISTS entity_alias;
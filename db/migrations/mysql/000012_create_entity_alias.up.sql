@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS entity_alias
+(
+    entity_type varchar(16)  NOT NULL,
+    entity_id   char(36)     NOT NULL,
+    language    varchar(16)  NOT NULL,
+    kind        varchar(16)  NOT NULL,
+    position    int          NOT NULL,
+    name        varchar(255) NOT NULL,
+    name_key    varchar(255) NOT NULL,
+    PRIMARY KEY (entity_type, entity_id, language, kind, position),
+    KEY entity_alias_name_key (entity_type, name_key)
+);
+
+ALTER TABLE search_document ADD COLUMN aliases text NOT NULL;
+ALTER TABLE search_document
+    DROP INDEX search_document_name,
+    DROP INDEX search_document_name_summary,
+    ADD FULLTEXT KEY search_document_name (name, aliases),
+    ADD FULLTEXT KEY search_document_name_summary (name, aliases, summary);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS entity_history
+(
+    id             bigint       NOT NULL AUTO_INCREMENT,
+    entity_type    varchar(16)  NOT NULL,
+    entity_id      char(36)     NOT NULL,
+    anime_id       varchar(36)  NOT NULL,
+    character_id   char(36)     NOT NULL,
+    operation      varchar(16)  NOT NULL,
+    `before`       text         NOT NULL,
+    `after`        text         NOT NULL,
+    changed_fields text         NOT NULL,
+    pipeline       varchar(32)  NOT NULL,
+    message_id     varchar(255) NOT NULL,
+    source_tx_id   bigint       NOT NULL,
+    source_lsn     bigint       NOT NULL,
+    source_ts_ms   bigint       NOT NULL,
+    recorded_at    datetime(3)  NOT NULL,
+    PRIMARY KEY (id),
+    KEY entity_history_entity (entity_type, entity_id, id),
+    KEY entity_history_anime (entity_type, anime_id),
+    KEY entity_history_character (entity_type, character_id)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS image_request
+(
+    entity_type varchar(16) NOT NULL,
+    entity_id   char(36)    NOT NULL,
+    url_hash    char(64)    NOT NULL,
+    expires_at  datetime(3) NOT NULL,
+    PRIMARY KEY (entity_type, entity_id, url_hash),
+    KEY image_request_expires_at (expires_at)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS anime_character_staff_link;
+DROP TABLE IF EXISTS anime_staff;
+DROP TABLE IF EXISTS anime_character;
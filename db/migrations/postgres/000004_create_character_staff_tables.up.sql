@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS anime_character
+(
+    id             char(36) PRIMARY KEY,
+    anime_id       varchar(36)  NOT NULL,
+    name           varchar(255) NOT NULL,
+    role           varchar(255) NOT NULL,
+    birthday       varchar(255),
+    zodiac         varchar(255),
+    gender         varchar(255),
+    race           varchar(255),
+    height         varchar(255),
+    weight         varchar(255),
+    title          varchar(255),
+    martial_status varchar(255),
+    summary        text,
+    image          text,
+    created_at     timestamp,
+    updated_at     timestamp
+);
+
+CREATE TABLE IF NOT EXISTS anime_staff
+(
+    id          char(36) PRIMARY KEY,
+    language    varchar(30)  NOT NULL,
+    given_name  varchar(255) NOT NULL,
+    family_name varchar(255) NOT NULL,
+    image       text,
+    birthday    varchar(255),
+    birth_place varchar(255),
+    blood_type  varchar(255),
+    hobbies     varchar(255),
+    summary     text,
+    created_at  timestamp,
+    updated_at  timestamp
+);
+
+CREATE TABLE IF NOT EXISTS anime_character_staff_link
+(
+    id                char(36) PRIMARY KEY,
+    character_id      varchar(36)  NOT NULL,
+    staff_id          varchar(36)  NOT NULL,
+    character_name    varchar(255) NOT NULL,
+    staff_given_name  varchar(255) NOT NULL,
+    staff_family_name varchar(255) NOT NULL,
+    created_at        timestamp,
+    updated_at        timestamp
+);
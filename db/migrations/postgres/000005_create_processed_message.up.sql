@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS processed_message
+(
+    id              char(64) PRIMARY KEY,
+    pipeline        varchar(32) NOT NULL,
+    message_key     varchar(255),
+    source_position varchar(255),
+    processed_at    timestamp NOT NULL,
+    UNIQUE (pipeline, message_key),
+    UNIQUE (pipeline, source_position)
+);
+
+CREATE INDEX IF NOT EXISTS processed_message_processed_at ON processed_message (processed_at);
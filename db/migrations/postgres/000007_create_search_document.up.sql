@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS search_document
+(
+    entity_type varchar(16)  NOT NULL,
+    entity_id   char(36)     NOT NULL,
+    name        varchar(512) NOT NULL,
+    summary     text         NOT NULL,
+    updated_at  timestamp    NOT NULL,
+    PRIMARY KEY (entity_type, entity_id)
+);
+
+CREATE INDEX IF NOT EXISTS search_document_fulltext ON search_document
+    USING GIN ((setweight(to_tsvector('simple', name), 'A') || setweight(to_tsvector('simple', summary), 'B')));
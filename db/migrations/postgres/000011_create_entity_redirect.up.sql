@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS entity_redirect
+(
+    entity_type varchar(16) NOT NULL,
+    old_id      char(36)    NOT NULL,
+    new_id      char(36)    NOT NULL,
+    merged_at   timestamp   NOT NULL,
+    PRIMARY KEY (entity_type, old_id)
+);
+
+CREATE INDEX IF NOT EXISTS entity_redirect_new_id ON entity_redirect (entity_type, new_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS search_document_fulltext;
+CREATE INDEX IF NOT EXISTS search_document_fulltext ON search_document
+    USING GIN ((setweight(to_tsvector('simple', name), 'A') || setweight(to_tsvector('simple', summary), 'B')));
+
+ALTER TABLE search_document DROP COLUMN aliases;
+
+DROP TABLE IF EXISTS entity_alias;
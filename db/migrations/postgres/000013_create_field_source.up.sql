@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS field_source
+(
+    entity_type varchar(16) NOT NULL,
+    entity_id   char(36)    NOT NULL,
+    field       varchar(64) NOT NULL,
+    source      varchar(64) NOT NULL,
+    priority    int         NOT NULL,
+    updated_at  timestamp   NOT NULL,
+    PRIMARY KEY (entity_type, entity_id, field)
+);
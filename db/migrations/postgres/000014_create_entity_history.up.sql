@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS entity_history
+(
+    id             bigserial    NOT NULL,
+    entity_type    varchar(16)  NOT NULL,
+    entity_id      char(36)     NOT NULL,
+    anime_id       varchar(36)  NOT NULL,
+    character_id   char(36)     NOT NULL,
+    operation      varchar(16)  NOT NULL,
+    before         text         NOT NULL,
+    after          text         NOT NULL,
+    changed_fields text         NOT NULL,
+    pipeline       varchar(32)  NOT NULL,
+    message_id     varchar(255) NOT NULL,
+    source_tx_id   bigint       NOT NULL,
+    source_lsn     bigint       NOT NULL,
+    source_ts_ms   bigint       NOT NULL,
+    recorded_at    timestamp    NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS entity_history_entity ON entity_history (entity_type, entity_id, id);
+CREATE INDEX IF NOT EXISTS entity_history_anime ON entity_history (entity_type, anime_id);
+CREATE INDEX IF NOT EXISTS entity_history_character ON entity_history (entity_type, character_id);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS image_request
+(
+    entity_type varchar(16) NOT NULL,
+    entity_id   char(36)    NOT NULL,
+    url_hash    char(64)    NOT NULL,
+    expires_at  timestamp   NOT NULL,
+    PRIMARY KEY (entity_type, entity_id, url_hash)
+);
+
+CREATE INDEX IF NOT EXISTS image_request_expires_at ON image_request (expires_at);
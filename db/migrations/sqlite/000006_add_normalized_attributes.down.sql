@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS anime_staff_hobby;
+
+DROP INDEX IF EXISTS anime_staff_birthday;
+ALTER TABLE anime_staff DROP COLUMN birthday_month;
+ALTER TABLE anime_staff DROP COLUMN birthday_day;
+ALTER TABLE anime_staff DROP COLUMN birthday_year;
+ALTER TABLE anime_staff DROP COLUMN blood_type_code;
+
+DROP INDEX IF EXISTS anime_character_height_cm;
+DROP INDEX IF EXISTS anime_character_birthday;
+ALTER TABLE anime_character DROP COLUMN height_cm;
+ALTER TABLE anime_character DROP COLUMN weight_kg;
+ALTER TABLE anime_character DROP COLUMN birthday_month;
+ALTER TABLE anime_character DROP COLUMN birthday_day;
+ALTER TABLE anime_character DROP COLUMN birthday_year;
@@ -0,0 +1,23 @@
+ALTER TABLE anime_character ADD COLUMN height_cm decimal(7,1);
+ALTER TABLE anime_character ADD COLUMN weight_kg decimal(7,1);
+ALTER TABLE anime_character ADD COLUMN birthday_month smallint;
+ALTER TABLE anime_character ADD COLUMN birthday_day smallint;
+ALTER TABLE anime_character ADD COLUMN birthday_year smallint;
+
+ALTER TABLE anime_staff ADD COLUMN birthday_month smallint;
+ALTER TABLE anime_staff ADD COLUMN birthday_day smallint;
+ALTER TABLE anime_staff ADD COLUMN birthday_year smallint;
+ALTER TABLE anime_staff ADD COLUMN blood_type_code varchar(2) CHECK (blood_type_code IN ('A', 'B', 'AB', 'O'));
+
+CREATE TABLE IF NOT EXISTS anime_staff_hobby
+(
+    staff_id char(36)     NOT NULL,
+    position int          NOT NULL,
+    hobby    varchar(255) NOT NULL,
+    PRIMARY KEY (staff_id, position)
+);
+
+CREATE INDEX anime_staff_hobby_hobby ON anime_staff_hobby (hobby);
+CREATE INDEX anime_character_height_cm ON anime_character (height_cm);
+CREATE INDEX anime_character_birthday ON anime_character (birthday_month, birthday_day);
+CREATE INDEX anime_staff_birthday ON anime_staff (birthday_month, birthday_day);
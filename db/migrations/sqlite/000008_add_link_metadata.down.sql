@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS anime_character_staff_link_character_language;
+DROP INDEX IF EXISTS anime_character_staff_link_staff_language;
+ALTER TABLE anime_character_staff_link DROP COLUMN language;
+ALTER TABLE anime_character_staff_link DROP COLUMN role_type;
+ALTER TABLE anime_character_staff_link DROP COLUMN notes;
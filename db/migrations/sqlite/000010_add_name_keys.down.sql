@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS anime_character_name_key;
+DROP INDEX IF EXISTS anime_staff_name_key;
+ALTER TABLE anime_character DROP COLUMN name_key;
+ALTER TABLE anime_staff DROP COLUMN name_key;
@@ -0,0 +1,3 @@
+ALTER TABLE search_document DROP COLUMN aliases;
+
+DROP TABLE IF EXISTS entity_alias;
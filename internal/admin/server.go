@@ -0,0 +1,161 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server is the admin api of a pipeline process, it adjusts the rate limits
+// at runtime and serves the metrics of the process. The rate limit endpoints
+// require the token as a bearer token, health and metrics stay open for
+// probes and scrapers.
+type Server struct {
+	limits *ratelimit.Registry
+	token  string
+}
+
+func NewServer(limits *ratelimit.Registry, token string) *Server {
+	return &Server{limits: limits, token: token}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rate-limits", s.authorized(s.listRateLimits))
+	mux.HandleFunc("PUT /rate-limits/{direction}/{name...}", s.authorized(s.setRateLimit))
+	mux.HandleFunc("DELETE /rate-limits/{direction}/{name...}", s.authorized(s.deleteRateLimit))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("GET /metrics", promhttp.Handler())
+	return mux
+}
+
+// Serve runs the admin api on the port until ctx is cancelled.
+func Serve(ctx context.Context, port int, token string, limits *ratelimit.Registry) error {
+	log := logger.FromCtx(ctx)
+
+	if token == "" {
+		return fmt.Errorf("admin api needs a token")
+	}
+
+	httpServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           NewServer(limits, token).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Info("Serving admin api", zap.String("addr", httpServer.Addr))
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authorized rejects requests without the bearer token, every request when
+// no token is set.
+func (s *Server) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.token == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing token"))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *Server) listRateLimits(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.limits.Limits())
+}
+
+// setRateLimit replaces the limit of a pipeline or sink, e.g.
+// PUT /rate-limits/produce/kafka:image-sync with {"rate": 10, "burst": 20}.
+func (s *Server) setRateLimit(w http.ResponseWriter, r *http.Request) {
+	direction, name, ok := rateLimitPath(w, r)
+	if !ok {
+		return
+	}
+
+	var limit ratelimit.Limit
+	if err := json.NewDecoder(r.Body).Decode(&limit); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+	if limit.Rate < 0 || limit.Burst < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("rate and burst must not be negative"))
+		return
+	}
+
+	s.limits.Set(direction, name, limit)
+	logger.FromCtx(r.Context()).Info("Rate limit changed", zap.String("direction", direction), zap.String("name", name),
+		zap.Float64("rate", limit.Rate), zap.Int("burst", limit.Burst))
+	writeJSON(w, http.StatusOK, s.limits.Limits())
+}
+
+// deleteRateLimit drops the runtime change of a pipeline or sink, it goes
+// back to its configured limit.
+func (s *Server) deleteRateLimit(w http.ResponseWriter, r *http.Request) {
+	direction, name, ok := rateLimitPath(w, r)
+	if !ok {
+		return
+	}
+
+	s.limits.Reset(direction, name)
+	logger.FromCtx(r.Context()).Info("Rate limit reset", zap.String("direction", direction), zap.String("name", name))
+	writeJSON(w, http.StatusOK, s.limits.Limits())
+}
+
+func rateLimitPath(w http.ResponseWriter, r *http.Request) (ratelimit.Direction, string, bool) {
+	direction, name := r.PathValue("direction"), r.PathValue("name")
+	switch direction {
+	case ratelimit.DirectionConsume, ratelimit.DirectionProduce:
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown direction %q", direction))
+		return "", "", false
+	}
+	if name == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("name is required"))
+		return "", "", false
+	}
+	return direction, name, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	data, _ := json.Marshal(errorResponse{Error: err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,65 @@
+package admin_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/weeb-vip/character-staff-sync/internal/admin"
+	"github.com/weeb-vip/character-staff-sync/internal/ratelimit"
+)
+
+func TestServerRequiresToken(t *testing.T) {
+	handler := admin.NewServer(ratelimit.NewRegistry(), "secret").Handler()
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		want          int
+	}{
+		{name: "no token", method: http.MethodGet, path: "/rate-limits", want: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodGet, path: "/rate-limits", authorization: "Bearer nope", want: http.StatusUnauthorized},
+		{name: "not bearer", method: http.MethodGet, path: "/rate-limits", authorization: "secret", want: http.StatusUnauthorized},
+		{name: "change without token", method: http.MethodPut, path: "/rate-limits/consume/character", want: http.StatusUnauthorized},
+		{name: "token", method: http.MethodGet, path: "/rate-limits", authorization: "Bearer secret", want: http.StatusOK},
+		{name: "health", method: http.MethodGet, path: "/healthz", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"rate": 1}`))
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, recorder.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerDeleteRestoresConfiguredLimit(t *testing.T) {
+	limits := ratelimit.NewRegistry()
+	limits.Configure(ratelimit.Limits{ratelimit.DirectionConsume: {"character": {Rate: 20, Burst: 20}}})
+	handler := admin.NewServer(limits, "secret").Handler()
+
+	for _, request := range []*http.Request{
+		httptest.NewRequest(http.MethodPut, "/rate-limits/consume/character", strings.NewReader(`{"rate": 5, "burst": 5}`)),
+		httptest.NewRequest(http.MethodDelete, "/rate-limits/consume/character", nil),
+	} {
+		request.Header.Set("Authorization", "Bearer secret")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s = %d: %s", request.Method, recorder.Code, recorder.Body)
+		}
+	}
+
+	if got := limits.Limits()[ratelimit.DirectionConsume]["character"]; got.Rate != 20 || got.Burst != 20 {
+		t.Errorf("limit after delete = %+v, want the configured 20/20", got)
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_cast"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"gorm.io/gorm"
+)
+
+var errNotFound = errors.New("not found")
+
+// castKeySeparator joins the key columns of a cast row into its cursor.
+const castKeySeparator = "\n"
+
+func castKey(after string) anime_cast.Key {
+	parts := strings.SplitN(after, castKeySeparator, 3)
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	return anime_cast.Key{CharacterID: parts[0], StaffID: parts[1], Language: parts[2]}
+}
+
+func (s *Server) getCharacter(w http.ResponseWriter, r *http.Request) {
+	character, err := s.characters.FindByID(r.Context(), r.PathValue("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, r, http.StatusNotFound, errNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, newCharacter(*character))
+}
+
+func (s *Server) getStaff(w http.ResponseWriter, r *http.Request) {
+	staff, err := s.staff.FindByID(r.Context(), r.PathValue("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, r, http.StatusNotFound, errNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	hobbies, err := s.staff.ListHobbies(r.Context(), []string{staff.ID})
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, newStaff(*staff, hobbies[staff.ID]))
+}
+
+func (s *Server) listAnimeCharacters(w http.ResponseWriter, r *http.Request) {
+	s.listCharacters(w, r, anime_character.ListFilter{
+		AnimeID:  r.PathValue("animeId"),
+		Language: r.URL.Query().Get("language"),
+	})
+}
+
+func (s *Server) listStaffCharacters(w http.ResponseWriter, r *http.Request) {
+	if !s.staffExists(w, r, r.PathValue("id")) {
+		return
+	}
+	s.listCharacters(w, r, anime_character.ListFilter{
+		StaffID:  r.PathValue("id"),
+		Language: r.URL.Query().Get("language"),
+	})
+}
+
+// listAnimeCast lists the cast of the anime from the anime_cast view,
+// filtered by language and role_type, e.g. the Japanese voice cast.
+func (s *Server) listAnimeCast(w http.ResponseWriter, r *http.Request) {
+	limit, after, err := pageParams(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	cast, err := s.cast.ListByAnime(r.Context(), r.PathValue("animeId"), castKey(after), limit+1, anime_cast.ListFilter{
+		Language: r.URL.Query().Get("language"),
+		RoleType: r.URL.Query().Get("role_type"),
+	})
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	items := make([]CastMember, len(cast))
+	for i, member := range cast {
+		items[i] = newCastMember(member)
+	}
+	writeJSON(w, r, http.StatusOK, newPage(items, limit, func(item CastMember) string {
+		return strings.Join([]string{item.CharacterID, item.StaffID, item.Language}, castKeySeparator)
+	}))
+}
+
+func (s *Server) listCharacterStaff(w http.ResponseWriter, r *http.Request) {
+	if !s.characterExists(w, r, r.PathValue("id")) {
+		return
+	}
+
+	limit, afterID, err := pageParams(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	staff, err := s.staff.ListAfterID(r.Context(), afterID, limit+1, anime_staff.ListFilter{
+		CharacterID: r.PathValue("id"),
+		Language:    r.URL.Query().Get("language"),
+	})
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	ids := make([]string, len(staff))
+	for i, member := range staff {
+		ids[i] = member.ID
+	}
+	hobbies, err := s.staff.ListHobbies(r.Context(), ids)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	items := make([]Staff, len(staff))
+	for i, member := range staff {
+		items[i] = newStaff(member, hobbies[member.ID])
+	}
+	writeJSON(w, r, http.StatusOK, newPage(items, limit, func(item Staff) string { return item.ID }))
+}
+
+func (s *Server) listCharacters(w http.ResponseWriter, r *http.Request, filter anime_character.ListFilter) {
+	limit, afterID, err := pageParams(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	characters, err := s.characters.ListAfterID(r.Context(), afterID, limit+1, filter)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	items := make([]Character, len(characters))
+	for i, character := range characters {
+		items[i] = newCharacter(character)
+	}
+	writeJSON(w, r, http.StatusOK, newPage(items, limit, func(item Character) string { return item.ID }))
+}
+
+// characterExists writes a 404 when the character is unknown, so listing the
+// links of a missing character is not mistaken for an empty list.
+func (s *Server) characterExists(w http.ResponseWriter, r *http.Request, id string) bool {
+	_, err := s.characters.FindByID(r.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, r, http.StatusNotFound, errNotFound)
+		return false
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return false
+	}
+	return true
+}
+
+func (s *Server) staffExists(w http.ResponseWriter, r *http.Request, id string) bool {
+	_, err := s.staff.FindByID(r.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, r, http.StatusNotFound, errNotFound)
+		return false
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return false
+	}
+	return true
+}
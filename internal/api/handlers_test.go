@@ -0,0 +1,156 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	migrations "github.com/weeb-vip/character-staff-sync/db"
+	"github.com/weeb-vip/character-staff-sync/internal/api"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_cast"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/search_document"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+func newTestServer(t *testing.T) (http.Handler, *db.DB) {
+	t.Helper()
+
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(t.TempDir(), "api.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+	server := api.NewServer(
+		anime_character.NewAnimeCharacterRepository(database),
+		anime_staff.NewAnimeStaffRepository(database),
+		anime_cast.NewAnimeCastRepository(database),
+		search_document.NewSearchDocumentRepository(database),
+	)
+	return server.Handler(), database
+}
+
+func get(t *testing.T, handler http.Handler, path string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request = request.WithContext(logger.WithCtx(context.Background(), zap.NewNop()))
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestListPagesWithCursor(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+	handler, database := newTestServer(t)
+
+	characters := anime_character.NewAnimeCharacterRepository(database)
+	for _, id := range []string{"c", "a", "b"} {
+		if err := characters.Upsert(ctx, &anime_character.AnimeCharacter{ID: id, AnimeID: "anime", Name: "Name " + id, Role: "Main"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []string
+	path := "/anime/anime/characters?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages == 3 {
+			t.Fatal("cursor did not reach the last page")
+		}
+		recorder := get(t, handler, path, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d: %s", path, recorder.Code, recorder.Body)
+		}
+		var page api.Page[api.Character]
+		if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		for _, character := range page.Data {
+			ids = append(ids, character.ID)
+		}
+		path = ""
+		if page.NextCursor != "" {
+			path = "/anime/anime/characters?limit=2&cursor=" + page.NextCursor
+		}
+	}
+
+	want := []string{"a", "b", "c"}
+	if len(ids) != len(want) {
+		t.Fatalf("ids = %q, want %q", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("ids[%d] = %q, want %q", i, ids[i], want[i])
+		}
+	}
+
+	for _, path := range []string{
+		"/anime/anime/characters?cursor=not%20base64",
+		"/anime/anime/characters?limit=0",
+		"/anime/anime/characters?limit=201",
+	} {
+		if recorder := get(t, handler, path, nil); recorder.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", path, recorder.Code)
+		}
+	}
+}
+
+func TestGetAnswersNotModified(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+	handler, database := newTestServer(t)
+
+	characters := anime_character.NewAnimeCharacterRepository(database)
+	character := &anime_character.AnimeCharacter{ID: "edward", AnimeID: "anime", Name: "Edward Elric", Role: "Main"}
+	if err := characters.Upsert(ctx, character); err != nil {
+		t.Fatal(err)
+	}
+
+	first := get(t, handler, "/characters/edward", nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("GET = %d with ETag %q, want 200 with an ETag", first.Code, etag)
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        int
+	}{
+		{name: "same etag", ifNoneMatch: etag, want: http.StatusNotModified},
+		{name: "weak etag", ifNoneMatch: "W/" + etag, want: http.StatusNotModified},
+		{name: "one of several", ifNoneMatch: `"other", ` + etag, want: http.StatusNotModified},
+		{name: "other etag", ifNoneMatch: `"other"`, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := get(t, handler, "/characters/edward", http.Header{"If-None-Match": {tt.ifNoneMatch}})
+			if recorder.Code != tt.want {
+				t.Errorf("GET = %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified && recorder.Body.Len() != 0 {
+				t.Errorf("304 with a body %q", recorder.Body)
+			}
+		})
+	}
+
+	character.Name = "Edward"
+	if err := characters.Upsert(ctx, character); err != nil {
+		t.Fatal(err)
+	}
+	if recorder := get(t, handler, "/characters/edward", http.Header{"If-None-Match": {etag}}); recorder.Code != http.StatusOK {
+		t.Errorf("GET after a change = %d, want 200", recorder.Code)
+	}
+
+	if recorder := get(t, handler, "/characters/missing", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("GET missing = %d, want 404", recorder.Code)
+	}
+}
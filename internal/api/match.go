@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/weeb-vip/character-staff-sync/internal/names"
+)
+
+// matchCharacters answers GET /characters/match?name=, characters with a
+// similar name ordered by confidence.
+func (s *Server) matchCharacters(w http.ResponseWriter, r *http.Request) {
+	name, limit, ok := matchParams(w, r)
+	if !ok {
+		return
+	}
+
+	matches, err := s.characters.MatchByName(r.Context(), name, limit)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, Page[names.Match]{Data: matches})
+}
+
+// matchStaff answers GET /staff/match?name=, name is the full name in
+// either order.
+func (s *Server) matchStaff(w http.ResponseWriter, r *http.Request) {
+	name, limit, ok := matchParams(w, r)
+	if !ok {
+		return
+	}
+
+	matches, err := s.staff.MatchByFullName(r.Context(), name, "", limit)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, Page[names.Match]{Data: matches})
+}
+
+func matchParams(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	name := r.URL.Query().Get("name")
+	if names.Key(name) == "" {
+		writeError(w, r, http.StatusBadRequest, errors.New("name is required"))
+		return "", 0, false
+	}
+
+	limit, _, err := pageParams(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return "", 0, false
+	}
+	return name, limit, true
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+// writeJSON writes body with a strong ETag over the encoded body and answers
+// 304 when the client already has it.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if status == http.StatusOK && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// writeError answers with the error message for client errors. Server errors
+// are logged and answered with a generic message, they can carry SQL and
+// driver details.
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	message := err.Error()
+	if status >= http.StatusInternalServerError {
+		logger.FromCtx(r.Context()).Error("Error serving request",
+			zap.String("method", r.Method), zap.String("path", r.URL.Path), zap.Error(err))
+		message = "internal error"
+	}
+
+	data, _ := json.Marshal(ErrorResponse{Error: message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// pageParams reads limit and cursor, the cursor is the opaque form of the
+// last id of the previous page.
+func pageParams(r *http.Request) (limit int, afterID string, err error) {
+	limit = defaultPageSize
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, "", fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+	}
+
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid cursor")
+		}
+		afterID = string(decoded)
+	}
+
+	return limit, afterID, nil
+}
+
+// newPage trims the extra row fetched to detect a following page and sets
+// the cursor from the last id.
+func newPage[T any](items []T, limit int, id func(T) string) Page[T] {
+	page := Page[T]{Data: items}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	if len(items) > limit {
+		page.Data = items[:limit]
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(id(page.Data[limit-1])))
+	}
+	return page
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/search_document"
+)
+
+// searchNames answers GET /search?q=&type=, every word of q matches as a
+// prefix and results are ordered by relevance.
+func (s *Server) searchNames(w http.ResponseWriter, r *http.Request) {
+	text := r.URL.Query().Get("q")
+	if len(search_document.Terms(text)) == 0 {
+		writeError(w, r, http.StatusBadRequest, errors.New("q is required"))
+		return
+	}
+
+	entityType := r.URL.Query().Get("type")
+	switch entityType {
+	case "", search_document.EntityTypeCharacter, search_document.EntityTypeStaff:
+	default:
+		writeError(w, r, http.StatusBadRequest, errors.New("type must be character or staff"))
+		return
+	}
+
+	limit, _, err := pageParams(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	hits, err := s.search.Search(r.Context(), search_document.Query{
+		Text:       text,
+		EntityType: entityType,
+		Limit:      limit,
+	})
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	items := make([]SearchHit, len(hits))
+	for i, hit := range hits {
+		items[i] = SearchHit{
+			Type:  hit.EntityType,
+			ID:    hit.EntityID,
+			Name:  hit.Name,
+			Score: hit.Score,
+		}
+	}
+	writeJSON(w, r, http.StatusOK, Page[SearchHit]{Data: items})
+}
@@ -0,0 +1,70 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/weeb-vip/character-staff-sync/internal/api"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestSearchNames(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+	handler, database := newTestServer(t)
+
+	// written through the repositories, which keep the search documents
+	if err := anime_character.NewAnimeCharacterRepository(database).Upsert(ctx, &anime_character.AnimeCharacter{ID: "edward", AnimeID: "anime", Name: "Edward Elric", Role: "Main"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := anime_staff.NewAnimeStaffRepository(database).Upsert(ctx, &anime_staff.AnimeStaff{ID: "romi", GivenName: "Romi", FamilyName: "Park", Summary: "Voices Edward Elric."}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		code int
+		want []string
+	}{
+		{name: "all types", path: "/search?q=edward", code: http.StatusOK, want: []string{"character edward", "staff romi"}},
+		{name: "one type", path: "/search?q=edward&type=staff", code: http.StatusOK, want: []string{"staff romi"}},
+		{name: "no match", path: "/search?q=winry", code: http.StatusOK, want: []string{}},
+		{name: "missing q", path: "/search", code: http.StatusBadRequest},
+		{name: "q without words", path: "/search?q=%25%2A", code: http.StatusBadRequest},
+		{name: "unknown type", path: "/search?q=edward&type=anime", code: http.StatusBadRequest},
+		{name: "bad limit", path: "/search?q=edward&limit=0", code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := get(t, handler, tt.path, nil)
+			if recorder.Code != tt.code {
+				t.Fatalf("GET %s = %d, want %d: %s", tt.path, recorder.Code, tt.code, recorder.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var page api.Page[api.SearchHit]
+			if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, hit := range page.Data {
+				got = append(got, hit.Type+" "+hit.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("hits = %q, want %q", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("hits[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_cast"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/search_document"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Server answers read requests for characters, staff and their links from
+// the synced tables.
+type Server struct {
+	characters anime_character.AnimeCharacterRepository
+	staff      anime_staff.AnimeStaffRepository
+	cast       anime_cast.AnimeCastRepository
+	search     search_document.SearchDocumentRepository
+}
+
+func NewServer(characters anime_character.AnimeCharacterRepository, staff anime_staff.AnimeStaffRepository, cast anime_cast.AnimeCastRepository, search search_document.SearchDocumentRepository) *Server {
+	return &Server{characters: characters, staff: staff, cast: cast, search: search}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /characters/match", s.matchCharacters)
+	mux.HandleFunc("GET /characters/{id}", s.getCharacter)
+	mux.HandleFunc("GET /characters/{id}/staff", s.listCharacterStaff)
+	mux.HandleFunc("GET /staff/match", s.matchStaff)
+	mux.HandleFunc("GET /staff/{id}", s.getStaff)
+	mux.HandleFunc("GET /staff/{id}/characters", s.listStaffCharacters)
+	mux.HandleFunc("GET /anime/{animeId}/characters", s.listAnimeCharacters)
+	mux.HandleFunc("GET /anime/{animeId}/cast", s.listAnimeCast)
+	mux.HandleFunc("GET /search", s.searchNames)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("GET /metrics", promhttp.Handler())
+	return mux
+}
+
+// Serve runs the read API on AppConfig.Port until ctx is cancelled.
+func Serve(ctx context.Context) error {
+	cfg := config.LoadConfigOrPanic()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	database := db.NewDB(cfg.DBConfig)
+	server := NewServer(
+		anime_character.NewAnimeCharacterRepository(database),
+		anime_staff.NewAnimeStaffRepository(database),
+		anime_cast.NewAnimeCastRepository(database),
+		search_document.NewSearchDocumentRepository(database),
+	)
+
+	httpServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.AppConfig.Port),
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// requests carry the logger but are not cancelled with ctx, shutdown
+		// lets them finish
+		BaseContext: func(net.Listener) context.Context {
+			return logger.WithCtx(context.Background(), log)
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Info("Serving read api", zap.String("addr", httpServer.Addr))
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_cast"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// Page is a page of a listing, NextCursor is empty on the last page.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// SearchHit is a search match, Name is the character name or the staff
+// given and family name.
+type SearchHit struct {
+	Type  string  `json:"type"`
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+type Character struct {
+	ID            string    `json:"id"`
+	AnimeID       string    `json:"anime_id"`
+	Name          string    `json:"name"`
+	Role          string    `json:"role"`
+	Birthday      string    `json:"birthday"`
+	Zodiac        string    `json:"zodiac"`
+	Gender        string    `json:"gender"`
+	Race          string    `json:"race"`
+	Height        string    `json:"height"`
+	Weight        string    `json:"weight"`
+	Title         string    `json:"title"`
+	MartialStatus string    `json:"martial_status"`
+	Summary       string    `json:"summary"`
+	Image         string    `json:"image"`
+	HeightCm      *float64  `json:"height_cm"`
+	WeightKg      *float64  `json:"weight_kg"`
+	BirthdayMonth *int      `json:"birthday_month"`
+	BirthdayDay   *int      `json:"birthday_day"`
+	BirthdayYear  *int      `json:"birthday_year"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type Staff struct {
+	ID            string    `json:"id"`
+	Language      string    `json:"language"`
+	GivenName     string    `json:"given_name"`
+	FamilyName    string    `json:"family_name"`
+	Image         string    `json:"image"`
+	Birthday      string    `json:"birthday"`
+	BirthPlace    string    `json:"birth_place"`
+	BloodType     string    `json:"blood_type"`
+	Hobbies       string    `json:"hobbies"`
+	Summary       string    `json:"summary"`
+	BirthdayMonth *int      `json:"birthday_month"`
+	BirthdayDay   *int      `json:"birthday_day"`
+	BirthdayYear  *int      `json:"birthday_year"`
+	BloodTypeCode *string   `json:"blood_type_code"`
+	HobbyList     []string  `json:"hobby_list"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CastMember is a row of the cast of an anime.
+type CastMember struct {
+	AnimeID         string    `json:"anime_id"`
+	CharacterID     string    `json:"character_id"`
+	StaffID         string    `json:"staff_id"`
+	Language        string    `json:"language"`
+	CharacterName   string    `json:"character_name"`
+	CharacterRole   string    `json:"character_role"`
+	CharacterImage  string    `json:"character_image"`
+	StaffGivenName  string    `json:"staff_given_name"`
+	StaffFamilyName string    `json:"staff_family_name"`
+	StaffImage      string    `json:"staff_image"`
+	RoleType        string    `json:"role_type"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func newCastMember(c anime_cast.AnimeCast) CastMember {
+	return CastMember{
+		AnimeID:         c.AnimeID,
+		CharacterID:     c.CharacterID,
+		StaffID:         c.StaffID,
+		Language:        c.Language,
+		CharacterName:   c.CharacterName,
+		CharacterRole:   c.CharacterRole,
+		CharacterImage:  c.CharacterImage,
+		StaffGivenName:  c.StaffGivenName,
+		StaffFamilyName: c.StaffFamilyName,
+		StaffImage:      c.StaffImage,
+		RoleType:        c.RoleType,
+		UpdatedAt:       c.UpdatedAt,
+	}
+}
+
+func newCharacter(c anime_character.AnimeCharacter) Character {
+	return Character{
+		ID:            c.ID,
+		AnimeID:       c.AnimeID,
+		Name:          c.Name,
+		Role:          c.Role,
+		Birthday:      c.Birthday,
+		Zodiac:        c.Zodiac,
+		Gender:        c.Gender,
+		Race:          c.Race,
+		Height:        c.Height,
+		Weight:        c.Weight,
+		Title:         c.Title,
+		MartialStatus: c.MartialStatus,
+		Summary:       c.Summary,
+		Image:         c.Image,
+		HeightCm:      c.HeightCm,
+		WeightKg:      c.WeightKg,
+		BirthdayMonth: c.BirthdayMonth,
+		BirthdayDay:   c.BirthdayDay,
+		BirthdayYear:  c.BirthdayYear,
+		CreatedAt:     c.CreatedAt,
+		UpdatedAt:     c.UpdatedAt,
+	}
+}
+
+func newStaff(s anime_staff.AnimeStaff, hobbies []string) Staff {
+	if hobbies == nil {
+		hobbies = []string{}
+	}
+	return Staff{
+		ID:            s.ID,
+		Language:      s.Language,
+		GivenName:     s.GivenName,
+		FamilyName:    s.FamilyName,
+		Image:         s.Image,
+		Birthday:      s.Birthday,
+		BirthPlace:    s.BirthPlace,
+		BloodType:     s.BloodType,
+		Hobbies:       s.Hobbies,
+		Summary:       s.Summary,
+		BirthdayMonth: s.BirthdayMonth,
+		BirthdayDay:   s.BirthdayDay,
+		BirthdayYear:  s.BirthdayYear,
+		BloodTypeCode: s.BloodTypeCode,
+		HobbyList:     hobbies,
+		CreatedAt:     s.CreatedAt,
+		UpdatedAt:     s.UpdatedAt,
+	}
+}
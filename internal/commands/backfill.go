@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// backfillCmd represents the backfill command
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Re-emit outbound events from the database",
+	Long: `Pages through the synced tables and re-emits outbound events
+through the configured producer.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// error need to call subcommand
+		return fmt.Errorf("please call subcommand")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backfillCmd)
+}
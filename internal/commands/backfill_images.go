@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/eventing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/backfill"
+)
+
+// backfillImagesCmd represents the backfill images command
+var backfillImagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "Re-emit image-sync events for characters and staff",
+	Long: `Pages through anime_character and anime_staff and emits an image-sync
+event for every row with an image. Progress is stored in a checkpoint file
+per entity and set of filters, so an interrupted run with the same filters
+resumes where it stopped. Once every entity finished the next run starts
+over.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.LoadConfigOrPanic()
+
+		entities, _ := cmd.Flags().GetStringSlice("entities")
+		animeID, _ := cmd.Flags().GetString("anime-id")
+		updatedSince, _ := cmd.Flags().GetString("updated-since")
+		missingCDNImage, _ := cmd.Flags().GetBool("missing-cdn-image")
+		reset, _ := cmd.Flags().GetBool("reset")
+
+		opt := backfill.Options{
+			Entities:        entities,
+			AnimeID:         animeID,
+			MissingCDNImage: missingCDNImage,
+			CDNPrefix:       cfg.BackfillConfig.CDNPrefix,
+			BatchSize:       cfg.BackfillConfig.BatchSize,
+			RatePerSecond:   cfg.BackfillConfig.RatePerSecond,
+			CheckpointFile:  cfg.BackfillConfig.CheckpointFile,
+			Reset:           reset,
+		}
+
+		if updatedSince != "" {
+			since, err := time.Parse(time.RFC3339, updatedSince)
+			if err != nil {
+				return fmt.Errorf("updated-since must be RFC3339: %w", err)
+			}
+			opt.UpdatedSince = &since
+		}
+		if cmd.Flags().Changed("cdn-prefix") {
+			opt.CDNPrefix, _ = cmd.Flags().GetString("cdn-prefix")
+		}
+		if cmd.Flags().Changed("batch-size") {
+			opt.BatchSize, _ = cmd.Flags().GetInt("batch-size")
+		}
+		if cmd.Flags().Changed("rate") {
+			opt.RatePerSecond, _ = cmd.Flags().GetFloat64("rate")
+		}
+		if cmd.Flags().Changed("checkpoint") {
+			opt.CheckpointFile, _ = cmd.Flags().GetString("checkpoint")
+		}
+
+		log.Println("Running image backfill...")
+		return eventing.BackfillImages(opt)
+	},
+}
+
+func init() {
+	backfillCmd.AddCommand(backfillImagesCmd)
+
+	backfillImagesCmd.Flags().StringSlice("entities", []string{backfill.EntityCharacter, backfill.EntityStaff}, "Entities to backfill (character, staff)")
+	backfillImagesCmd.Flags().String("anime-id", "", "Only backfill characters and staff of this anime")
+	backfillImagesCmd.Flags().String("updated-since", "", "Only backfill rows updated since this RFC3339 time")
+	backfillImagesCmd.Flags().Bool("missing-cdn-image", false, "Only backfill rows whose image is not on the CDN yet")
+	backfillImagesCmd.Flags().String("cdn-prefix", "", "CDN url prefix, overrides BACKFILL_CDN_PREFIX")
+	backfillImagesCmd.Flags().Int("batch-size", 0, "Rows per page, overrides BACKFILL_BATCH_SIZE")
+	backfillImagesCmd.Flags().Float64("rate", 0, "Maximum events per second, overrides BACKFILL_RATE_PER_SECOND")
+	backfillImagesCmd.Flags().String("checkpoint", "", "Checkpoint file, overrides BACKFILL_CHECKPOINT_FILE")
+	backfillImagesCmd.Flags().Bool("reset", false, "Ignore the checkpoint and start from the beginning")
+}
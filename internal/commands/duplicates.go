@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/weeb-vip/character-staff-sync/internal/eventing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/duplicates"
+)
+
+// duplicatesCmd represents the duplicates command
+var duplicatesCmd = &cobra.Command{
+	Use:   "duplicates",
+	Short: "Find duplicate characters and staff",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// error need to call subcommand
+		return fmt.Errorf("please call subcommand")
+	},
+}
+
+// duplicatesFindCmd represents the duplicates find command
+var duplicatesFindCmd = &cobra.Command{
+	Use:   "find",
+	Short: "Write merge candidates for rows sharing a normalized name",
+	Long: `Groups anime_character (per anime) or anime_staff (per language) rows by
+their normalized name key and scores every row against the one kept, the
+row with the most links. Matching birthdays and images raise the score,
+contradicting birthdays rule the pair out. The candidates are written as
+JSON lines the merge command accepts.`,
+	Example: `  character-staff-sync duplicates find --type staff --min-score 0.8 --output ./candidates.jsonl`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entityType, _ := cmd.Flags().GetString("type")
+		minScore, _ := cmd.Flags().GetFloat64("min-score")
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+		output, _ := cmd.Flags().GetString("output")
+
+		log.Println("Finding duplicates...")
+		return eventing.FindDuplicates(duplicates.Options{
+			EntityType: entityType,
+			MinScore:   minScore,
+			BatchSize:  batchSize,
+		}, output)
+	},
+}
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+	Use:   "merge",
+	Short: "Merge a duplicate character or staff member into another",
+	Long: `Moves the links of the duplicate to the row kept, deletes the duplicate
+and records a redirect so later change events for its id are applied to
+the row kept. A merge event is emitted per merge.
+
+Pass a single pair with --type, --from and --into, or the candidates
+written by duplicates find with --candidates.`,
+	Example: `  character-staff-sync merge --type staff --from <duplicate id> --into <kept id>
+  character-staff-sync merge --candidates ./candidates.jsonl`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entityType, _ := cmd.Flags().GetString("type")
+		fromID, _ := cmd.Flags().GetString("from")
+		intoID, _ := cmd.Flags().GetString("into")
+		candidatesFile, _ := cmd.Flags().GetString("candidates")
+
+		var candidates []duplicates.Candidate
+		switch {
+		case candidatesFile != "":
+			var err error
+			candidates, err = eventing.ReadCandidates(candidatesFile)
+			if err != nil {
+				return err
+			}
+		case entityType != "" && fromID != "" && intoID != "":
+			candidates = []duplicates.Candidate{{EntityType: entityType, MergeID: fromID, KeepID: intoID}}
+		default:
+			return fmt.Errorf("either --candidates or --type, --from and --into are required")
+		}
+
+		log.Println("Merging duplicates...")
+		return eventing.MergeDuplicates(candidates)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(duplicatesCmd)
+	duplicatesCmd.AddCommand(duplicatesFindCmd)
+	rootCmd.AddCommand(mergeCmd)
+
+	duplicatesFindCmd.Flags().String("type", duplicates.EntityStaff, "Entity type (character, staff)")
+	duplicatesFindCmd.Flags().Float64("min-score", 0.8, "Lowest score written, a shared name alone scores 0.6")
+	duplicatesFindCmd.Flags().Int("batch-size", 500, "Name keys per page")
+	duplicatesFindCmd.Flags().String("output", "", "File the candidates are written to, defaults to stdout")
+
+	mergeCmd.Flags().String("type", "", "Entity type (character, staff)")
+	mergeCmd.Flags().String("from", "", "Id of the duplicate merged away")
+	mergeCmd.Flags().String("into", "", "Id of the row kept")
+	mergeCmd.Flags().String("candidates", "", "JSON lines file written by duplicates find")
+}
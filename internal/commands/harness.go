@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/harness"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+// harnessCmd represents the harness command
+var harnessCmd = &cobra.Command{
+	Use:   "harness",
+	Short: "Run fixture cases through the pipelines with in-memory brokers",
+	Long: `Runs every fixture case end to end through the kafka pipeline wiring,
+using an in-memory broker and a scratch sqlite database per case.
+
+A case is a directory holding messages.jsonl, the Debezium messages fed to
+the pipeline, and expect.json, the pipeline name plus the rows, absent ids
+and outbound events expected afterwards.`,
+	Example:      `  character-staff-sync harness --fixtures testdata/pipelines`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.LoadConfigOrPanic()
+
+		fixtures, _ := cmd.Flags().GetString("fixtures")
+		run, _ := cmd.Flags().GetString("run")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+
+		ctx := context.Background()
+		if verbose {
+			ctx = logger.WithCtx(ctx, logger.Get())
+		} else {
+			ctx = logger.WithCtx(ctx, zap.NewNop())
+		}
+
+		cases, err := harness.LoadCases(fixtures)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, c := range cases {
+			if run != "" && c.Name != run {
+				continue
+			}
+
+			result := harness.Run(ctx, cfg, c)
+			if result.Passed() {
+				fmt.Printf("PASS %s\n", result.Case)
+				continue
+			}
+
+			failed++
+			fmt.Printf("FAIL %s\n", result.Case)
+			if result.Err != nil {
+				fmt.Printf("    %v\n", result.Err)
+			}
+			for _, failure := range result.Failures {
+				fmt.Printf("    %s\n", failure)
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d case(s) failed", failed, len(cases))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(harnessCmd)
+
+	harnessCmd.Flags().String("fixtures", "testdata/pipelines", "Directory of fixture cases")
+	harnessCmd.Flags().String("run", "", "Only run the case with this name")
+	harnessCmd.Flags().Bool("verbose", false, "Log pipeline output")
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/weeb-vip/character-staff-sync/internal/eventing"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the recorded changes of a character, staff member or link",
+	Long: `Prints the changes applied to an entity, oldest first, by the kafka and
+pulsar pipelines, replay, merges and live rebuilds, with the pipeline,
+message id and Debezium source coordinates of every change. Updates list the changed columns with their previous and new
+values. Changes are only recorded with HISTORY_ENABLED=true.`,
+	Example: `  character-staff-sync history --type character --id <character id>
+  character-staff-sync history --type staff --id <staff id> --limit 10 --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entityType, _ := cmd.Flags().GetString("type")
+		entityID, _ := cmd.Flags().GetString("id")
+		limit, _ := cmd.Flags().GetInt("limit")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		if entityID == "" {
+			return fmt.Errorf("--id is required")
+		}
+
+		return eventing.History(eventing.HistoryOptions{
+			EntityType: entityType,
+			EntityID:   entityID,
+			Limit:      limit,
+			JSON:       asJSON,
+		}, cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().String("type", "character", "Entity type (character, staff, link)")
+	historyCmd.Flags().String("id", "", "Id of the entity")
+	historyCmd.Flags().Int("limit", 100, "Number of most recent changes shown")
+	historyCmd.Flags().Bool("json", false, "Write the changes as JSON lines")
+}
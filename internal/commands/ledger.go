@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/eventing"
+)
+
+// ledgerCmd represents the ledger command
+var ledgerCmd = &cobra.Command{
+	Use:   "ledger",
+	Short: "Manage the processed-message ledger",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// error need to call subcommand
+		return fmt.Errorf("please call subcommand")
+	},
+}
+
+// ledgerPruneCmd represents the ledger prune command
+var ledgerPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove ledger entries past the retention",
+	Long: `Removes processed-message entries older than the retention. The
+pipelines prune on their own every LEDGER_CLEANUP_INTERVAL_MINUTES, this
+runs the cleanup once.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.LoadConfigOrPanic()
+
+		olderThan := time.Duration(cfg.LedgerConfig.RetentionHours) * time.Hour
+		if cmd.Flags().Changed("older-than") {
+			olderThan, _ = cmd.Flags().GetDuration("older-than")
+		}
+
+		log.Println("Pruning processed message ledger...")
+		return eventing.PruneLedger(olderThan)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ledgerCmd)
+	ledgerCmd.AddCommand(ledgerPruneCmd)
+
+	ledgerPruneCmd.Flags().Duration("older-than", 0, "Remove entries older than this, defaults to LEDGER_RETENTION_HOURS")
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/weeb-vip/character-staff-sync/internal/eventing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/attributes"
+)
+
+// normalizeCmd represents the normalize command
+var normalizeCmd = &cobra.Command{
+	Use:   "normalize",
+	Short: "Re-derive normalized columns from stored rows",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// error need to call subcommand
+		return fmt.Errorf("please call subcommand")
+	},
+}
+
+// normalizeAttributesCmd represents the normalize attributes command
+var normalizeAttributesCmd = &cobra.Command{
+	Use:   "attributes",
+	Short: "Parse height, weight, birthday, blood type and hobbies into typed columns",
+	Long: `Pages through anime_character and anime_staff and parses the free-form
+attributes into their typed columns. The pipelines normalize on write, this
+fills rows synced before the typed columns existed or after the parsers
+changed. Values that cannot be parsed are logged and counted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entities, _ := cmd.Flags().GetStringSlice("entities")
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+
+		log.Println("Normalizing attributes...")
+		return eventing.NormalizeAttributes(entities, batchSize)
+	},
+}
+
+// normalizeNamesCmd represents the normalize names command
+var normalizeNamesCmd = &cobra.Command{
+	Use:   "names",
+	Short: "Derive the normalized name keys used for name matching",
+	Long: `Pages through anime_character and anime_staff and rewrites their name
+keys. The pipelines derive the key on write, this fills rows synced before
+the keys existed or after the normalization rules changed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entities, _ := cmd.Flags().GetStringSlice("entities")
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+
+		log.Println("Normalizing names...")
+		return eventing.NormalizeNames(entities, batchSize)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(normalizeCmd)
+	normalizeCmd.AddCommand(normalizeAttributesCmd)
+
+	normalizeAttributesCmd.Flags().StringSlice("entities", []string{attributes.EntityCharacter, attributes.EntityStaff}, "Entities to normalize (character, staff)")
+	normalizeAttributesCmd.Flags().Int("batch-size", 500, "Rows per page")
+
+	normalizeCmd.AddCommand(normalizeNamesCmd)
+	normalizeNamesCmd.Flags().StringSlice("entities", []string{attributes.EntityCharacter, attributes.EntityStaff}, "Entities to normalize (character, staff)")
+	normalizeNamesCmd.Flags().Int("batch-size", 500, "Rows per page")
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/weeb-vip/character-staff-sync/internal/eventing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/rebuild"
+)
+
+// rebuildCmd represents the rebuild command
+var rebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Regenerate derived tables",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// error need to call subcommand
+		return fmt.Errorf("please call subcommand")
+	},
+}
+
+// rebuildCastViewCmd represents the rebuild cast-view command
+var rebuildCastViewCmd = &cobra.Command{
+	Use:   "cast-view",
+	Short: "Regenerate the anime_cast table from scratch",
+	Long: `Replaces every anime_cast row with the join of anime_character,
+anime_character_staff_link and anime_staff. The pipelines keep the table
+current on their own, this repairs it after manual edits or a restore.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.Println("Rebuilding cast view...")
+		return eventing.RebuildCastView()
+	},
+}
+
+// rebuildAtCmd represents the rebuild at command
+var rebuildAtCmd = &cobra.Command{
+	Use:   "at",
+	Short: "Restore characters, staff and links as they were at a point in time",
+	Long: `Replays the recorded entity history up to --at and restores one entity
+(--type and --id) or the characters of an anime and their links
+(--anime-id). Only entities with recorded changes are restored, the
+history has to be enabled (HISTORY_ENABLED=true) before the time restored.
+
+By default the rows are written to copies of the tables named with
+--prefix, e.g. restore_anime_character, for inspection. With --live the
+live tables are restored instead, including search documents and the cast
+view; without --confirm this only prints the steps. Every line of the
+output is the action (restore, delete or unchanged), the entity type and
+the id.`,
+	Example: `  character-staff-sync rebuild at --at 2024-05-01T12:00:00Z --type character --id <id>
+  character-staff-sync rebuild at --at 2024-05-01T12:00:00Z --anime-id <anime id> --live
+  character-staff-sync rebuild at --at 2024-05-01T12:00:00Z --anime-id <anime id> --live --confirm`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atFlag, _ := cmd.Flags().GetString("at")
+		entityType, _ := cmd.Flags().GetString("type")
+		entityID, _ := cmd.Flags().GetString("id")
+		animeID, _ := cmd.Flags().GetString("anime-id")
+		live, _ := cmd.Flags().GetBool("live")
+		confirm, _ := cmd.Flags().GetBool("confirm")
+		prefix, _ := cmd.Flags().GetString("prefix")
+
+		at, err := time.Parse(time.RFC3339, atFlag)
+		if err != nil {
+			return fmt.Errorf("--at must be an RFC 3339 time: %w", err)
+		}
+		if (entityID == "") == (animeID == "") {
+			return fmt.Errorf("either --id or --anime-id is required")
+		}
+
+		log.Println("Rebuilding from history...")
+		return eventing.RebuildAt(eventing.RebuildOptions{
+			Options: rebuild.Options{
+				At:         at,
+				EntityType: entityType,
+				EntityID:   entityID,
+				AnimeID:    animeID,
+			},
+			Live:    live,
+			Confirm: confirm,
+			Prefix:  prefix,
+		}, cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rebuildCmd)
+	rebuildCmd.AddCommand(rebuildCastViewCmd)
+	rebuildCmd.AddCommand(rebuildAtCmd)
+
+	rebuildAtCmd.Flags().String("at", "", "Point in time restored, RFC 3339")
+	rebuildAtCmd.Flags().String("type", "character", "Entity type of --id (character, staff, link)")
+	rebuildAtCmd.Flags().String("id", "", "Id of the entity restored")
+	rebuildAtCmd.Flags().String("anime-id", "", "Anime whose characters and links are restored")
+	rebuildAtCmd.Flags().Bool("live", false, "Restore the live tables instead of prefixed copies")
+	rebuildAtCmd.Flags().Bool("confirm", false, "Write the live tables, without it --live only prints the steps")
+	rebuildAtCmd.Flags().String("prefix", "restore_", "Prefix of the tables written without --live")
+}
@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/weeb-vip/character-staff-sync/internal/eventing"
+)
+
+// reconcileCmd represents the reconcile command
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Compare a source export with the target table",
+	Long: `Compares a JSONL or CSV export of the upstream table with the MySQL
+target using per id range checksums, and drills down to per row diffs
+for the ranges that do not match.
+
+The corrective upserts and deletes can be written to a file, which the
+replay command accepts, or applied directly through the processor of the
+live pipeline, with its redirects, sources, history and cast changes.`,
+	Example: `  character-staff-sync reconcile --table character --source ./anime_character.jsonl --report ./report.json --apply`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		table, _ := cmd.Flags().GetString("table")
+		source, _ := cmd.Flags().GetString("source")
+		sourceFormat, _ := cmd.Flags().GetString("source-format")
+		rangeSize, _ := cmd.Flags().GetInt("range-size")
+		report, _ := cmd.Flags().GetString("report")
+		corrections, _ := cmd.Flags().GetString("corrections")
+		apply, _ := cmd.Flags().GetBool("apply")
+
+		log.Println("Running reconcile...")
+		return eventing.Reconcile(eventing.ReconcileOptions{
+			Table:        table,
+			Source:       source,
+			SourceFormat: sourceFormat,
+			RangeSize:    rangeSize,
+			Report:       report,
+			Corrections:  corrections,
+			Apply:        apply,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+
+	reconcileCmd.Flags().String("table", "", "Table to reconcile (character, staff, link)")
+	reconcileCmd.Flags().String("source", "", "Source export file")
+	reconcileCmd.Flags().String("source-format", "", "Source format (jsonl, csv), defaults to the file extension")
+	reconcileCmd.Flags().Int("range-size", 1000, "Source rows per checksum range")
+	reconcileCmd.Flags().String("report", "", "File the JSON report is written to")
+	reconcileCmd.Flags().String("corrections", "", "File the corrective payloads are written to")
+	reconcileCmd.Flags().Bool("apply", false, "Apply the corrections through the pipeline processor")
+
+	_ = reconcileCmd.MarkFlagRequired("table")
+	_ = reconcileCmd.MarkFlagRequired("source")
+}
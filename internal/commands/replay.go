@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/eventing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/replay"
+)
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay captured Debezium messages from files",
+	Long: `Reads Debezium messages from a JSONL file or a directory of JSONL files
+and runs them through the same wiring as the live kafka pipeline against the
+chosen database, cast changes, image dedupe and the ledger included. No broker
+is needed, messages sent to the retry topic are consumed again after the
+files, and outbound events are collected in memory and written to the output
+file instead of being routed.
+
+Lines are either raw kafka messages with a base64 encoded Value, or decoded
+Debezium messages.`,
+	Example: `  character-staff-sync replay --pipeline staff --input ./incident --output ./out.jsonl --db-name weeb_replay`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.LoadConfigOrPanic()
+
+		pipeline, _ := cmd.Flags().GetString("pipeline")
+		input, _ := cmd.Flags().GetString("input")
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+
+		dbConfig := cfg.DBConfig
+		if cmd.Flags().Changed("db-host") {
+			dbConfig.Host, _ = cmd.Flags().GetString("db-host")
+		}
+		if cmd.Flags().Changed("db-port") {
+			dbConfig.Port, _ = cmd.Flags().GetUint("db-port")
+		}
+		if cmd.Flags().Changed("db-name") {
+			dbConfig.DataBase, _ = cmd.Flags().GetString("db-name")
+		}
+		if cmd.Flags().Changed("db-user") {
+			dbConfig.User, _ = cmd.Flags().GetString("db-user")
+		}
+		if cmd.Flags().Changed("db-password") {
+			dbConfig.Password, _ = cmd.Flags().GetString("db-password")
+		}
+
+		log.Println("Running replay...")
+		return eventing.Replay(eventing.ReplayOptions{
+			Pipeline: pipeline,
+			Input:    input,
+			Format:   format,
+			Output:   output,
+			DBConfig: dbConfig,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().String("pipeline", "", "Pipeline to replay through (character, staff, link)")
+	replayCmd.Flags().String("input", "", "JSONL file or directory of captured messages")
+	replayCmd.Flags().String("format", replay.FormatAuto, "Line format (auto, raw, decoded)")
+	replayCmd.Flags().String("output", "", "File the outbound events are written to")
+	replayCmd.Flags().String("db-host", "", "Database host, overrides DBHOST")
+	replayCmd.Flags().Uint("db-port", 0, "Database port, overrides DBPORT")
+	replayCmd.Flags().String("db-name", "", "Database name, overrides DBNAME")
+	replayCmd.Flags().String("db-user", "", "Database user, overrides DBUSERNAME")
+	replayCmd.Flags().String("db-password", "", "Database password, overrides DBPASSWORD")
+
+	_ = replayCmd.MarkFlagRequired("pipeline")
+	_ = replayCmd.MarkFlagRequired("input")
+}
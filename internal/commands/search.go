@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/search_document"
+	"github.com/weeb-vip/character-staff-sync/internal/eventing"
+)
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search",
+	Short: "Manage the character and staff search index",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// error need to call subcommand
+		return fmt.Errorf("please call subcommand")
+	},
+}
+
+// searchReindexCmd represents the search reindex command
+var searchReindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuild the search documents from stored rows",
+	Long: `Pages through anime_character and anime_staff and rewrites their
+search documents. The pipelines index on write, this fills rows synced
+before the search index existed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entityTypes, _ := cmd.Flags().GetStringSlice("types")
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+
+		log.Println("Reindexing search documents...")
+		return eventing.ReindexSearch(entityTypes, batchSize)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.AddCommand(searchReindexCmd)
+
+	searchReindexCmd.Flags().StringSlice("types", []string{search_document.EntityTypeCharacter, search_document.EntityTypeStaff}, "Entity types to reindex (character, staff)")
+	searchReindexCmd.Flags().Int("batch-size", 500, "Rows per page")
+}
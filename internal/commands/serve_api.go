@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/weeb-vip/character-staff-sync/internal/api"
+)
+
+// serveAPICmd represents the serve-api command
+var serveAPICmd = &cobra.Command{
+	Use:   "serve-api",
+	Short: "Serve the HTTP read API for characters and staff",
+	Long: `Serves characters, staff and their links from the synced tables on
+PORT. Listings are paged with limit and cursor, responses carry an ETag.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		log.Println("Running read api...")
+		return api.Serve(ctx)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveAPICmd)
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var schemaCache = &sync.Map{}
+
+// Changes compares two rows of the same model and returns the columns whose
+// value differs together with their previous values. The primary key, the
+// autoCreateTime/autoUpdateTime columns and columns tagged derived, which are
+// computed from other columns, are not compared. A nil previous
+// row reports every column as changed without previous values.
+func Changes(previous interface{}, current interface{}) ([]string, map[string]interface{}, error) {
+	s, err := schema.Parse(current, schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	currentValue := reflect.Indirect(reflect.ValueOf(current))
+	var previousValue reflect.Value
+	if rv := reflect.ValueOf(previous); previous != nil && !(rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		previousValue = reflect.Indirect(rv)
+		if previousValue.Type() != currentValue.Type() {
+			return nil, nil, fmt.Errorf("cannot compare %s with %s", previousValue.Type(), currentValue.Type())
+		}
+	}
+
+	var changed []string
+	values := map[string]interface{}{}
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.PrimaryKey || field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 || isDerived(field) {
+			continue
+		}
+
+		next, _ := field.ValueOf(context.Background(), currentValue)
+		if !previousValue.IsValid() {
+			changed = append(changed, field.DBName)
+			continue
+		}
+
+		prev, _ := field.ValueOf(context.Background(), previousValue)
+		if !reflect.DeepEqual(prev, next) {
+			changed = append(changed, field.DBName)
+			values[field.DBName] = prev
+		}
+	}
+
+	return changed, values, nil
+}
+
+func isDerived(field *schema.Field) bool {
+	_, ok := field.TagSettings["DERIVED"]
+	return ok
+}
+
+// StoredChanges compares the incoming row with the stored row, or with the
+// before image when the row is not stored yet. A row that is not stored
+// always has changes so it gets written. find loads the stored row and fails
+// with gorm.ErrRecordNotFound when there is none, before is nil without a
+// before image and diff compares two rows, usually Changes plus the child
+// lists of the model.
+func StoredChanges[T any](next *T, before *T, find func() (*T, error), diff func(previous *T, next *T) ([]string, map[string]interface{}, error)) ([]string, map[string]interface{}, error) {
+	stored, err := find()
+	if err == nil {
+		return diff(stored, next)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	if before != nil {
+		changed, values, err := diff(before, next)
+		if err != nil || len(changed) > 0 {
+			return changed, values, err
+		}
+	}
+	return Changes(nil, next)
+}
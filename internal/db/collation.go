@@ -0,0 +1,15 @@
+package db
+
+// Bytewise returns column compared and ordered byte by byte, the way Go
+// compares strings. The default collations of MySQL and Postgres ignore case
+// or follow the locale, SQLite already compares bytes.
+func (d *DB) Bytewise(column string) string {
+	switch d.Dialect {
+	case DialectMySQL:
+		return "BINARY " + column
+	case DialectPostgres:
+		return column + ` COLLATE "C"`
+	default:
+		return column
+	}
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// CopyColumns sets the columns of dst to their value in src, both rows of
+// the same model. Names that are not a column of the model are ignored.
+func CopyColumns(dst interface{}, src interface{}, columns []string) error {
+	s, err := schema.Parse(dst, schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+
+	dstValue := reflect.Indirect(reflect.ValueOf(dst))
+	srcValue := reflect.Indirect(reflect.ValueOf(src))
+	if dstValue.Type() != srcValue.Type() {
+		return fmt.Errorf("cannot copy %s to %s", srcValue.Type(), dstValue.Type())
+	}
+
+	for _, column := range columns {
+		field, ok := s.FieldsByDBName[column]
+		if !ok {
+			continue
+		}
+		value, _ := field.ValueOf(context.Background(), srcValue)
+		if err := field.Set(context.Background(), dstValue, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EmptyColumns returns the given columns of the row that hold their zero
+// value.
+func EmptyColumns(row interface{}, columns []string) ([]string, error) {
+	s, err := schema.Parse(row, schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return nil, err
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(row))
+	var empty []string
+	for _, column := range columns {
+		field, ok := s.FieldsByDBName[column]
+		if !ok {
+			continue
+		}
+		if _, zero := field.ValueOf(context.Background(), value); zero {
+			empty = append(empty, column)
+		}
+	}
+	return empty, nil
+}
+
+// Columns returns the values of every column of the row keyed by column
+// name.
+func Columns(row interface{}) (map[string]interface{}, error) {
+	s, err := schema.Parse(row, schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return nil, err
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(row))
+	columns := make(map[string]interface{}, len(s.DBNames))
+	for _, name := range s.DBNames {
+		columns[name], _ = s.FieldsByDBName[name].ValueOf(context.Background(), value)
+	}
+	return columns, nil
+}
+
+// DecodeColumns sets the fields of row from a JSON object keyed by column
+// name, as written by Columns. Unknown columns are ignored.
+func DecodeColumns(data []byte, row interface{}) error {
+	s, err := schema.Parse(row, schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+
+	var columns map[string]json.RawMessage
+	if err := json.Unmarshal(data, &columns); err != nil {
+		return err
+	}
+	fields := make(map[string]json.RawMessage, len(columns))
+	for name, value := range columns {
+		if field, ok := s.FieldsByDBName[name]; ok {
+			fields[field.Name] = value
+		}
+	}
+
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, row)
+}
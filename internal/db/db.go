@@ -4,17 +4,32 @@ import (
 	"fmt"
 	"github.com/weeb-vip/character-staff-sync/config"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"time"
 )
 
+type Dialect = string
+
+const (
+	DialectMySQL    Dialect = "mysql"
+	DialectPostgres Dialect = "postgres"
+	DialectSQLite   Dialect = "sqlite"
+)
+
 type DB struct {
-	DB *gorm.DB
+	DB      *gorm.DB
+	Dialect Dialect
 }
 
 func NewDB(cfg config.DBConfig) *DB {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&tls=%s&interpolateParams=true", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DataBase, cfg.SSLMode)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	dialector, err := newDialector(cfg)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		panic("failed to connect database")
 	}
@@ -24,6 +39,13 @@ func NewDB(cfg config.DBConfig) *DB {
 		panic("failed to get database connection")
 	}
 
+	if dialect(cfg) == DialectSQLite {
+		// sqlite only allows a single writer, a larger pool only
+		// produces "database is locked" errors
+		sqlDB.SetMaxOpenConns(1)
+		return &DB{DB: db, Dialect: DialectSQLite}
+	}
+
 	// Set maximum number of open connections
 	// This prevents too many connections to the database
 	sqlDB.SetMaxOpenConns(25)
@@ -40,5 +62,31 @@ func NewDB(cfg config.DBConfig) *DB {
 	// This helps clean up idle connections
 	sqlDB.SetConnMaxIdleTime(90 * time.Second)
 
-	return &DB{DB: db}
+	return &DB{DB: db, Dialect: dialect(cfg)}
+}
+
+func dialect(cfg config.DBConfig) Dialect {
+	if cfg.Dialect == "" {
+		return DialectMySQL
+	}
+	return cfg.Dialect
+}
+
+func newDialector(cfg config.DBConfig) (gorm.Dialector, error) {
+	switch dialect(cfg) {
+	case DialectMySQL:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&tls=%s&interpolateParams=true&multiStatements=true", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DataBase, cfg.SSLMode)
+		return mysql.Open(dsn), nil
+	case DialectPostgres:
+		sslMode := "disable"
+		if cfg.SSLMode == "true" {
+			sslMode = "require"
+		}
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s", cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DataBase, sslMode)
+		return postgres.Open(dsn), nil
+	case DialectSQLite:
+		return sqlite.Open(cfg.SQLitePath + "?_foreign_keys=on&_busy_timeout=5000"), nil
+	default:
+		return nil, fmt.Errorf("unknown database dialect %q", cfg.Dialect)
+	}
 }
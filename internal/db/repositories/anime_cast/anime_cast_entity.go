@@ -0,0 +1,27 @@
+package anime_cast
+
+import (
+	"time"
+)
+
+// AnimeCast is a denormalized row of the cast of an anime, one per
+// character, staff member and language, built from the character, staff and
+// link tables.
+type AnimeCast struct {
+	AnimeID         string    `gorm:"type:varchar(36);primaryKey"`
+	CharacterID     string    `gorm:"type:char(36);primaryKey"`
+	StaffID         string    `gorm:"type:char(36);primaryKey"`
+	Language        string    `gorm:"type:varchar(30);primaryKey"`
+	CharacterName   string    `gorm:"type:varchar(255);not null"`
+	CharacterRole   string    `gorm:"type:varchar(255);not null"`
+	CharacterImage  string    `gorm:"type:text;not null"`
+	StaffGivenName  string    `gorm:"type:varchar(255);not null"`
+	StaffFamilyName string    `gorm:"type:varchar(255);not null"`
+	StaffImage      string    `gorm:"type:text;not null"`
+	RoleType        string    `gorm:"type:varchar(30);not null"`
+	UpdatedAt       time.Time `gorm:"not null"`
+}
+
+func (AnimeCast) TableName() string {
+	return "anime_cast"
+}
@@ -0,0 +1,113 @@
+package anime_cast
+
+import (
+	"context"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"gorm.io/gorm"
+)
+
+// selectCast builds the cast rows from the source tables. Staff members that
+// are not synced yet fall back to the names stored on the link.
+const selectCast = `
+SELECT c.anime_id, l.character_id, l.staff_id, l.language,
+       MIN(c.name), MIN(c.role), MIN(COALESCE(c.image, '')),
+       MIN(COALESCE(s.given_name, l.staff_given_name)), MIN(COALESCE(s.family_name, l.staff_family_name)),
+       MIN(COALESCE(s.image, '')), MIN(l.role_type), CURRENT_TIMESTAMP
+FROM anime_character_staff_link l
+JOIN anime_character c ON c.id = l.character_id
+LEFT JOIN anime_staff s ON s.id = l.staff_id`
+
+const insertCast = `INSERT INTO anime_cast (anime_id, character_id, staff_id, language,
+    character_name, character_role, character_image,
+    staff_given_name, staff_family_name, staff_image, role_type, updated_at)`
+
+const groupCast = ` GROUP BY c.anime_id, l.character_id, l.staff_id, l.language`
+
+// Key is the position of a row in a listing, rows of an anime are ordered by
+// character, staff and language.
+type Key struct {
+	CharacterID string
+	StaffID     string
+	Language    string
+}
+
+// ListFilter narrows down a paged listing, zero values are ignored.
+type ListFilter struct {
+	Language string
+	RoleType string
+}
+
+type AnimeCastRepository interface {
+	// RefreshCharacter rebuilds the rows of the character from the source
+	// tables, run after the character or one of its links was written.
+	RefreshCharacter(ctx context.Context, characterID string) error
+	// RefreshStaff rebuilds the rows of the staff member.
+	RefreshStaff(ctx context.Context, staffID string) error
+	// Rebuild replaces every row, it returns the number of rows written.
+	Rebuild(ctx context.Context) (int64, error)
+	ListByAnime(ctx context.Context, animeID string, after Key, limit int, filter ListFilter) ([]AnimeCast, error)
+}
+
+type AnimeCastRepositoryImpl struct {
+	db *db.DB
+}
+
+func NewAnimeCastRepository(db *db.DB) AnimeCastRepository {
+	return &AnimeCastRepositoryImpl{db: db}
+}
+
+func (r *AnimeCastRepositoryImpl) RefreshCharacter(ctx context.Context, characterID string) error {
+	return r.refresh(ctx, "character_id", characterID)
+}
+
+func (r *AnimeCastRepositoryImpl) RefreshStaff(ctx context.Context, staffID string) error {
+	return r.refresh(ctx, "staff_id", staffID)
+}
+
+func (r *AnimeCastRepositoryImpl) refresh(ctx context.Context, column string, id string) error {
+	if id == "" {
+		return nil
+	}
+	return r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(column+" = ?", id).Delete(&AnimeCast{}).Error; err != nil {
+			return err
+		}
+		return tx.Exec(insertCast+selectCast+" WHERE l."+column+" = ?"+groupCast, id).Error
+	})
+}
+
+func (r *AnimeCastRepositoryImpl) Rebuild(ctx context.Context) (int64, error) {
+	var rows int64
+	err := r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&AnimeCast{}).Error; err != nil {
+			return err
+		}
+		result := tx.Exec(insertCast + selectCast + groupCast)
+		rows = result.RowsAffected
+		return result.Error
+	})
+	return rows, err
+}
+
+// ListByAnime pages through the cast of the anime, starting after the key.
+func (r *AnimeCastRepositoryImpl) ListByAnime(ctx context.Context, animeID string, after Key, limit int, filter ListFilter) ([]AnimeCast, error) {
+	query := r.db.Conn(ctx).Where("anime_id = ?", animeID).
+		Where("(character_id > ?) OR (character_id = ? AND staff_id > ?) OR (character_id = ? AND staff_id = ? AND language > ?)",
+			after.CharacterID,
+			after.CharacterID, after.StaffID,
+			after.CharacterID, after.StaffID, after.Language)
+	if filter.Language != "" {
+		query = query.Where("language = ?", filter.Language)
+	}
+	if filter.RoleType != "" {
+		query = query.Where("role_type = ?", filter.RoleType)
+	}
+
+	var cast []AnimeCast
+	err := query.Order("character_id, staff_id, language").Limit(limit).Find(&cast).Error
+	if err != nil {
+		return nil, err
+	}
+	return cast, nil
+}
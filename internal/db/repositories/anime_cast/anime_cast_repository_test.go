@@ -0,0 +1,164 @@
+package anime_cast_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	migrations "github.com/weeb-vip/character-staff-sync/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_cast"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+// TestCastFollowsWrites writes through the character, staff and link
+// repositories and checks the cast rows after every write, then that a full
+// rebuild agrees with them.
+func TestCastFollowsWrites(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(t.TempDir(), "cast.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+	cast := anime_cast.NewAnimeCastRepository(database)
+	characters := anime_character.NewAnimeCharacterRepository(database)
+	staff := anime_staff.NewAnimeStaffRepository(database)
+	links := anime_character_staff_link.NewAnimeCharacterStaffLinkRepository(database)
+
+	rows := func(filter anime_cast.ListFilter) []string {
+		t.Helper()
+		members, err := cast.ListByAnime(ctx, "anime", anime_cast.Key{}, 100, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, row := range members {
+			got = append(got, row.CharacterID+" "+row.StaffID+" "+row.Language+": "+row.CharacterName+" by "+row.StaffGivenName+" "+row.StaffFamilyName)
+		}
+		return got
+	}
+	check := func(step string, want ...string) {
+		t.Helper()
+		if got := rows(anime_cast.ListFilter{}); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: cast = %q, want %q", step, got, want)
+		}
+	}
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	must(characters.Upsert(ctx, &anime_character.AnimeCharacter{ID: "alphonse", AnimeID: "anime", Name: "Alphonse Elric", Role: "Main"}))
+	must(characters.Upsert(ctx, &anime_character.AnimeCharacter{ID: "edward", AnimeID: "anime", Name: "Edward Elric", Role: "Main"}))
+	must(links.Upsert(ctx, &anime_character_staff_link.AnimeCharacterStaffLink{ID: "park", CharacterID: "edward", StaffID: "romi", StaffGivenName: "Romi", StaffFamilyName: "Park", Language: "Japanese", RoleType: anime_character_staff_link.RoleTypeVoice}))
+	must(links.Upsert(ctx, &anime_character_staff_link.AnimeCharacterStaffLink{ID: "mignogna", CharacterID: "edward", StaffID: "vic", StaffGivenName: "Vic", StaffFamilyName: "Mignogna", Language: "English", RoleType: anime_character_staff_link.RoleTypeVoice}))
+	must(links.Upsert(ctx, &anime_character_staff_link.AnimeCharacterStaffLink{ID: "kugimiya", CharacterID: "alphonse", StaffID: "rie", StaffGivenName: "Rie", StaffFamilyName: "Kugimiya", Language: "Japanese", RoleType: anime_character_staff_link.RoleTypeVoice}))
+	check("links without staff rows",
+		"alphonse rie Japanese: Alphonse Elric by Rie Kugimiya",
+		"edward romi Japanese: Edward Elric by Romi Park",
+		"edward vic English: Edward Elric by Vic Mignogna",
+	)
+
+	must(staff.Upsert(ctx, &anime_staff.AnimeStaff{ID: "romi", Language: "Japanese", GivenName: "Romi", FamilyName: "Paku"}))
+	check("staff row replaces the link names",
+		"alphonse rie Japanese: Alphonse Elric by Rie Kugimiya",
+		"edward romi Japanese: Edward Elric by Romi Paku",
+		"edward vic English: Edward Elric by Vic Mignogna",
+	)
+
+	must(characters.Upsert(ctx, &anime_character.AnimeCharacter{ID: "edward", AnimeID: "anime", Name: "Edward", Role: "Main"}))
+	check("renamed character",
+		"alphonse rie Japanese: Alphonse Elric by Rie Kugimiya",
+		"edward romi Japanese: Edward by Romi Paku",
+		"edward vic English: Edward by Vic Mignogna",
+	)
+
+	must(links.Upsert(ctx, &anime_character_staff_link.AnimeCharacterStaffLink{ID: "kugimiya", CharacterID: "edward", StaffID: "rie", StaffGivenName: "Rie", StaffFamilyName: "Kugimiya", Language: "Japanese", RoleType: anime_character_staff_link.RoleTypeVoice}))
+	check("link moved to another character",
+		"edward rie Japanese: Edward by Rie Kugimiya",
+		"edward romi Japanese: Edward by Romi Paku",
+		"edward vic English: Edward by Vic Mignogna",
+	)
+
+	must(links.Delete(ctx, &anime_character_staff_link.AnimeCharacterStaffLink{ID: "mignogna"}))
+	must(staff.Delete(ctx, &anime_staff.AnimeStaff{ID: "romi"}))
+	check("deleted link and staff row",
+		"edward rie Japanese: Edward by Rie Kugimiya",
+		"edward romi Japanese: Edward by Romi Park",
+	)
+
+	if got := rows(anime_cast.ListFilter{Language: "Japanese", RoleType: "staff"}); len(got) != 0 {
+		t.Errorf("cast with another role type = %q, want none", got)
+	}
+
+	incremental := rows(anime_cast.ListFilter{})
+	written, err := cast.Rebuild(ctx)
+	must(err)
+	if written != int64(len(incremental)) {
+		t.Errorf("rebuild wrote %d rows, want %d", written, len(incremental))
+	}
+	check("rebuild", incremental...)
+
+	must(characters.Delete(ctx, &anime_character.AnimeCharacter{ID: "edward"}))
+	check("deleted character")
+}
+
+func TestListByAnimePages(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(t.TempDir(), "cast.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+	characters := anime_character.NewAnimeCharacterRepository(database)
+	links := anime_character_staff_link.NewAnimeCharacterStaffLinkRepository(database)
+
+	if err := characters.Upsert(ctx, &anime_character.AnimeCharacter{ID: "edward", AnimeID: "anime", Name: "Edward Elric", Role: "Main"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, link := range []*anime_character_staff_link.AnimeCharacterStaffLink{
+		{ID: "1", CharacterID: "edward", StaffID: "romi", Language: "Japanese", RoleType: anime_character_staff_link.RoleTypeVoice},
+		{ID: "2", CharacterID: "edward", StaffID: "romi", Language: "English", RoleType: anime_character_staff_link.RoleTypeVoice},
+		{ID: "3", CharacterID: "edward", StaffID: "vic", Language: "English", RoleType: anime_character_staff_link.RoleTypeVoice},
+	} {
+		if err := links.Upsert(ctx, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cast := anime_cast.NewAnimeCastRepository(database)
+	var got []anime_cast.Key
+	after := anime_cast.Key{}
+	for {
+		page, err := cast.ListByAnime(ctx, "anime", after, 1, anime_cast.ListFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		after = anime_cast.Key{CharacterID: page[0].CharacterID, StaffID: page[0].StaffID, Language: page[0].Language}
+		got = append(got, after)
+		if len(got) > 3 {
+			t.Fatal("paging did not stop")
+		}
+	}
+
+	want := []anime_cast.Key{
+		{CharacterID: "edward", StaffID: "romi", Language: "English"},
+		{CharacterID: "edward", StaffID: "romi", Language: "Japanese"},
+		{CharacterID: "edward", StaffID: "vic", Language: "English"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pages = %+v, want %+v", got, want)
+	}
+}
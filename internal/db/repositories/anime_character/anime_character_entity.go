@@ -1,28 +1,56 @@
 package anime_character
 
 import (
+	"strings"
 	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/search_document"
 )
 
 type AnimeCharacter struct {
-	ID            string    `gorm:"type:char(36);primaryKey"`
-	AnimeID       string    `gorm:"type:varchar(36);not null"`
-	Name          string    `gorm:"type:varchar(255);not null"`
-	Role          string    `gorm:"type:varchar(255);not null"`
-	Birthday      string    `gorm:"type:varchar(255)"`
-	Zodiac        string    `gorm:"type:varchar(255)"`
-	Gender        string    `gorm:"type:varchar(255)"`
-	Race          string    `gorm:"type:varchar(255)"`
-	Height        string    `gorm:"type:varchar(255)"`
-	Weight        string    `gorm:"type:varchar(255)"`
-	Title         string    `gorm:"type:varchar(255)"`
-	MartialStatus string    `gorm:"type:varchar(255)"`
-	Summary       string    `gorm:"type:text"`
-	Image         string    `gorm:"type:text"`
+	ID            string `gorm:"type:char(36);primaryKey"`
+	AnimeID       string `gorm:"type:varchar(36);not null"`
+	Name          string `gorm:"type:varchar(255);not null"`
+	Role          string `gorm:"type:varchar(255);not null"`
+	Birthday      string `gorm:"type:varchar(255)"`
+	Zodiac        string `gorm:"type:varchar(255)"`
+	Gender        string `gorm:"type:varchar(255)"`
+	Race          string `gorm:"type:varchar(255)"`
+	Height        string `gorm:"type:varchar(255)"`
+	Weight        string `gorm:"type:varchar(255)"`
+	Title         string `gorm:"type:varchar(255)"`
+	MartialStatus string `gorm:"type:varchar(255)"`
+	Summary       string `gorm:"type:text"`
+	Image         string `gorm:"type:text"`
+	// derived columns, typed values parsed from Height, Weight and Birthday,
+	// nil when the raw value is empty or could not be parsed, and NameKey,
+	// the normalized Name used for matching
+	HeightCm      *float64  `gorm:"type:decimal(7,1);derived"`
+	WeightKg      *float64  `gorm:"type:decimal(7,1);derived"`
+	BirthdayMonth *int      `gorm:"type:smallint;derived"`
+	BirthdayDay   *int      `gorm:"type:smallint;derived"`
+	BirthdayYear  *int      `gorm:"type:smallint;derived"`
+	NameKey       string    `gorm:"type:varchar(255);not null;derived"`
 	CreatedAt     time.Time `gorm:"autoCreateTime"`
 	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+
+	// Aliases are the other names, stored in entity_alias, nil keeps the
+	// stored aliases on upsert
+	Aliases []entity_alias.EntityAlias `gorm:"-"`
 }
 
 func (AnimeCharacter) TableName() string {
 	return "anime_character"
 }
+
+// SearchDocument returns the searchable text of the character.
+func (c *AnimeCharacter) SearchDocument() *search_document.SearchDocument {
+	return &search_document.SearchDocument{
+		EntityType: search_document.EntityTypeCharacter,
+		EntityID:   c.ID,
+		Name:       c.Name,
+		Aliases:    strings.Join(entity_alias.Names(c.Aliases), ", "),
+		Summary:    c.Summary,
+	}
+}
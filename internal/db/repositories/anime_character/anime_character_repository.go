@@ -1,49 +1,253 @@
 package anime_character
 
 import (
+	"context"
+	"time"
+
 	"github.com/weeb-vip/character-staff-sync/internal/db"
-	"gorm.io/gorm/clause"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_cast"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/search_document"
+	"github.com/weeb-vip/character-staff-sync/internal/names"
+	"gorm.io/gorm"
 )
 
+// ListFilter narrows down a paged listing, zero values are ignored.
+type ListFilter struct {
+	// StaffID only returns characters linked to the staff member.
+	StaffID string
+	// Language only returns characters with a link in the language, combined
+	// with StaffID the same link has to match.
+	Language string
+	AnimeID  string
+	// UpdatedSince only returns rows updated at or after the given time.
+	UpdatedSince *time.Time
+	// ImageNotPrefix only returns rows with an image that does not start with
+	// the prefix, used to find images that are not on the CDN yet.
+	ImageNotPrefix string
+}
+
 type AnimeCharacterRepository interface {
-	Upsert(character *AnimeCharacter) error
-	Delete(character *AnimeCharacter) error
-	FindByID(id string) (*AnimeCharacter, error)
-	FindByName(name string) (string, error)
+	Upsert(ctx context.Context, character *AnimeCharacter) error
+	Delete(ctx context.Context, character *AnimeCharacter) error
+	FindByID(ctx context.Context, id string) (*AnimeCharacter, error)
+	FindByName(ctx context.Context, name string) (string, error)
+	MatchByName(ctx context.Context, name string, limit int) ([]names.Match, error)
+	ListAfterID(ctx context.Context, afterID string, limit int, filter ListFilter) ([]AnimeCharacter, error)
+	ListIDRange(ctx context.Context, afterID string, throughID string) ([]AnimeCharacter, error)
+	ListDuplicateNameKeys(ctx context.Context, afterKey string, limit int) ([]string, error)
+	ListByNameKey(ctx context.Context, key string) ([]AnimeCharacter, error)
 }
 
 type AnimeCharacterRepositoryImpl struct {
-	db *db.DB
+	db      *db.DB
+	search  search_document.SearchDocumentRepository
+	cast    anime_cast.AnimeCastRepository
+	aliases entity_alias.EntityAliasRepository
 }
 
 func NewAnimeCharacterRepository(db *db.DB) AnimeCharacterRepository {
-	return &AnimeCharacterRepositoryImpl{db: db}
+	return &AnimeCharacterRepositoryImpl{
+		db:      db,
+		search:  search_document.NewSearchDocumentRepository(db),
+		cast:    anime_cast.NewAnimeCastRepository(db),
+		aliases: entity_alias.NewEntityAliasRepository(db),
+	}
 }
 
-func (r *AnimeCharacterRepositoryImpl) Upsert(character *AnimeCharacter) error {
-	return r.db.DB.Clauses(clause.OnConflict{
-		UpdateAll: true,
-	}).Create(character).Error
+// Upsert writes the character, its aliases, search document and cast rows in
+// one transaction.
+func (r *AnimeCharacterRepositoryImpl) Upsert(ctx context.Context, character *AnimeCharacter) error {
+	character.NameKey = names.Key(character.Name)
+	return r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := db.WithTx(ctx, tx)
+		if err := r.db.Upsert(ctx, character); err != nil {
+			return err
+		}
+		if err := r.upsertAliases(ctx, character); err != nil {
+			return err
+		}
+		if err := r.search.Upsert(ctx, character.SearchDocument()); err != nil {
+			return err
+		}
+		return r.cast.RefreshCharacter(ctx, character.ID)
+	})
 }
 
-func (r *AnimeCharacterRepositoryImpl) Delete(character *AnimeCharacter) error {
-	return r.db.DB.Delete(character).Error
+func (r *AnimeCharacterRepositoryImpl) Delete(ctx context.Context, character *AnimeCharacter) error {
+	return r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := db.WithTx(ctx, tx)
+		if err := r.search.Delete(ctx, search_document.EntityTypeCharacter, character.ID); err != nil {
+			return err
+		}
+		if err := r.aliases.Delete(ctx, entity_alias.EntityTypeCharacter, character.ID); err != nil {
+			return err
+		}
+		if err := tx.Delete(character).Error; err != nil {
+			return err
+		}
+		return r.cast.RefreshCharacter(ctx, character.ID)
+	})
 }
 
-func (r *AnimeCharacterRepositoryImpl) FindByID(id string) (*AnimeCharacter, error) {
+func (r *AnimeCharacterRepositoryImpl) FindByID(ctx context.Context, id string) (*AnimeCharacter, error) {
 	var result AnimeCharacter
-	err := r.db.DB.First(&result, "id = ?", id).Error
+	err := r.db.Conn(ctx).First(&result, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	result.Aliases, err = r.aliases.List(ctx, entity_alias.EntityTypeCharacter, id)
 	if err != nil {
 		return nil, err
 	}
 	return &result, nil
 }
 
-func (r *AnimeCharacterRepositoryImpl) FindByName(name string) (string, error) {
+// upsertAliases replaces the stored aliases with the ones sent, or loads the
+// stored aliases for the search document when none were sent.
+func (r *AnimeCharacterRepositoryImpl) upsertAliases(ctx context.Context, character *AnimeCharacter) error {
+	if character.Aliases == nil {
+		aliases, err := r.aliases.List(ctx, entity_alias.EntityTypeCharacter, character.ID)
+		if err != nil {
+			return err
+		}
+		character.Aliases = aliases
+		return nil
+	}
+	return r.aliases.Replace(ctx, entity_alias.EntityTypeCharacter, character.ID, character.Aliases)
+}
+
+// FindByName returns the character whose normalized name or alias equals the
+// normalized name, ignoring case, accents, long vowels and word order. Rows
+// without a name key yet are matched on the exact name.
+func (r *AnimeCharacterRepositoryImpl) FindByName(ctx context.Context, name string) (string, error) {
+	key := names.Key(name)
+	aliased := r.db.Conn(ctx).Model(&entity_alias.EntityAlias{}).
+		Select("entity_id").
+		Where("entity_type = ? AND name_key = ?", entity_alias.EntityTypeCharacter, key)
+
 	var character AnimeCharacter
-	err := r.db.DB.Select("id").Where("name = ?", name).First(&character).Error
+	err := r.db.Conn(ctx).Select("id").
+		Where("(name_key = ? AND name_key <> '') OR (name_key = '' AND name = ?)", key, name).
+		Or("id IN (?)", aliased).
+		Order("id").
+		First(&character).Error
 	if err != nil {
 		return "", err
 	}
 	return character.ID, nil
 }
+
+// MatchByName returns up to limit characters with a name or alias similar to
+// name, best match first, see names.Score for the confidence.
+func (r *AnimeCharacterRepositoryImpl) MatchByName(ctx context.Context, name string, limit int) ([]names.Match, error) {
+	key := names.Key(name)
+	fragments := names.Fragments(key)
+	if len(fragments) == 0 {
+		return []names.Match{}, nil
+	}
+
+	query := r.db.Conn(ctx).Select("id", "name", "name_key")
+	conditions := r.db.Conn(ctx)
+	for _, fragment := range fragments {
+		conditions = conditions.Or("name_key LIKE ?", "%"+fragment+"%")
+	}
+
+	var characters []AnimeCharacter
+	err := query.Where(conditions).Limit(names.MaxCandidates).Find(&characters).Error
+	if err != nil {
+		return nil, err
+	}
+
+	aliases, err := r.aliases.ListByFragments(ctx, entity_alias.EntityTypeCharacter, fragments, names.MaxCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]names.Match, 0, len(characters)+len(aliases))
+	keys := make([]string, 0, len(characters)+len(aliases))
+	for _, character := range characters {
+		candidates = append(candidates, names.Match{ID: character.ID, Name: character.Name})
+		keys = append(keys, character.NameKey)
+	}
+	for _, alias := range aliases {
+		candidates = append(candidates, names.Match{ID: alias.EntityID, Name: alias.Name})
+		keys = append(keys, alias.NameKey)
+	}
+	return names.Rank(key, candidates, keys, names.MinScore, limit), nil
+}
+
+// ListAfterID pages through characters ordered by id, starting after afterID.
+func (r *AnimeCharacterRepositoryImpl) ListAfterID(ctx context.Context, afterID string, limit int, filter ListFilter) ([]AnimeCharacter, error) {
+	query := r.db.Conn(ctx).Where("id > ?", afterID)
+	if filter.AnimeID != "" {
+		query = query.Where("anime_id = ?", filter.AnimeID)
+	}
+	if filter.StaffID != "" || filter.Language != "" {
+		links := r.db.Conn(ctx).Table("anime_character_staff_link").Select("character_id")
+		if filter.StaffID != "" {
+			links = links.Where("staff_id = ?", filter.StaffID)
+		}
+		if filter.Language != "" {
+			links = links.Where("language = ?", filter.Language)
+		}
+		query = query.Where("id IN (?)", links)
+	}
+	if filter.UpdatedSince != nil {
+		query = query.Where("updated_at >= ?", *filter.UpdatedSince)
+	}
+	if filter.ImageNotPrefix != "" {
+		query = query.Where("image <> '' AND image NOT LIKE ?", filter.ImageNotPrefix+"%")
+	}
+
+	var characters []AnimeCharacter
+	err := query.Order("id").Limit(limit).Find(&characters).Error
+	if err != nil {
+		return nil, err
+	}
+	return characters, nil
+}
+
+// ListIDRange returns the rows with afterID < id <= throughID ordered by id,
+// an empty throughID leaves the range open ended. Ids compare byte by byte so
+// ranges line up with a source sorted in Go.
+func (r *AnimeCharacterRepositoryImpl) ListIDRange(ctx context.Context, afterID string, throughID string) ([]AnimeCharacter, error) {
+	query := r.db.Conn(ctx).Where(r.db.Bytewise("id")+" > ?", afterID)
+	if throughID != "" {
+		query = query.Where(r.db.Bytewise("id")+" <= ?", throughID)
+	}
+
+	var characters []AnimeCharacter
+	err := query.Order(r.db.Bytewise("id")).Find(&characters).Error
+	if err != nil {
+		return nil, err
+	}
+	return characters, nil
+}
+
+// ListDuplicateNameKeys pages through the name keys shared by more than one
+// row, ordered by key and starting after afterKey.
+func (r *AnimeCharacterRepositoryImpl) ListDuplicateNameKeys(ctx context.Context, afterKey string, limit int) ([]string, error) {
+	var keys []string
+	err := r.db.Conn(ctx).Model(&AnimeCharacter{}).
+		Where("name_key > ?", afterKey).
+		Group("name_key").
+		Having("COUNT(*) > 1").
+		Order("name_key").
+		Limit(limit).
+		Pluck("name_key", &keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ListByNameKey returns the characters with the name key ordered by id.
+func (r *AnimeCharacterRepositoryImpl) ListByNameKey(ctx context.Context, key string) ([]AnimeCharacter, error) {
+	var characters []AnimeCharacter
+	err := r.db.Conn(ctx).Where("name_key = ?", key).Order("id").Find(&characters).Error
+	if err != nil {
+		return nil, err
+	}
+	return characters, nil
+}
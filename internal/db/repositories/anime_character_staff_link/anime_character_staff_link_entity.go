@@ -4,15 +4,24 @@ import (
 	"time"
 )
 
+// RoleTypeVoice is the role type of voice acting credits, links synced
+// before role types existed are voice credits.
+const RoleTypeVoice = "voice"
+
 type AnimeCharacterStaffLink struct {
-	ID              string    `gorm:"type:char(36);primaryKey"`
-	CharacterID     string    `gorm:"type:varchar(36);not null"`
-	StaffID         string    `gorm:"type:varchar(36);not null"`
-	CharacterName   string    `gorm:"type:varchar(255);not null"`
-	StaffGivenName  string    `gorm:"type:varchar(255);not null"`
-	StaffFamilyName string    `gorm:"type:varchar(255);not null"`
-	CreatedAt       time.Time `gorm:"autoCreateTime"`
-	UpdatedAt       time.Time `gorm:"autoUpdateTime"`
+	ID              string `gorm:"type:char(36);primaryKey"`
+	CharacterID     string `gorm:"type:varchar(36);not null"`
+	StaffID         string `gorm:"type:varchar(36);not null"`
+	CharacterName   string `gorm:"type:varchar(255);not null"`
+	StaffGivenName  string `gorm:"type:varchar(255);not null"`
+	StaffFamilyName string `gorm:"type:varchar(255);not null"`
+	// Language is the language of the performance, e.g. Japanese or English.
+	Language string `gorm:"type:varchar(30);not null"`
+	// RoleType is the kind of credit, e.g. voice.
+	RoleType  string    `gorm:"type:varchar(30);not null"`
+	Notes     *string   `gorm:"type:text"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 }
 
 func (AnimeCharacterStaffLink) TableName() string {
@@ -1,32 +1,227 @@
 package anime_character_staff_link
 
 import (
+	"context"
+	"errors"
+
 	"github.com/weeb-vip/character-staff-sync/internal/db"
-	"gorm.io/gorm/clause"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_cast"
+	"gorm.io/gorm"
 )
 
+// ListFilter narrows down a paged listing, zero values are ignored.
+type ListFilter struct {
+	// AnimeID only returns links of characters of the anime.
+	AnimeID     string
+	CharacterID string
+	StaffID     string
+	Language    string
+	RoleType    string
+}
+
 type AnimeCharacterStaffLinkRepository interface {
-	Upsert(link *AnimeCharacterStaffLink) error
-	Delete(link *AnimeCharacterStaffLink) error
+	Upsert(ctx context.Context, link *AnimeCharacterStaffLink) error
+	Delete(ctx context.Context, link *AnimeCharacterStaffLink) error
+	FindByID(ctx context.Context, id string) (*AnimeCharacterStaffLink, error)
+	ListAfterID(ctx context.Context, afterID string, limit int, filter ListFilter) ([]AnimeCharacterStaffLink, error)
+	ListIDRange(ctx context.Context, afterID string, throughID string) ([]AnimeCharacterStaffLink, error)
+	ListAnimeIDsByStaff(ctx context.Context, staffID string) ([]string, error)
+	Count(ctx context.Context, filter ListFilter) (int64, error)
+	RepointCharacter(ctx context.Context, fromID string, toID string, characterName string) ([]AnimeCharacterStaffLink, error)
+	RepointStaff(ctx context.Context, fromID string, toID string, givenName string, familyName string) ([]AnimeCharacterStaffLink, error)
 }
 
 type AnimeCharacterStaffLinkRepositoryImpl struct {
-	db *db.DB
+	db   *db.DB
+	cast anime_cast.AnimeCastRepository
 }
 
 func NewAnimeCharacterStaffLinkRepository(db *db.DB) AnimeCharacterStaffLinkRepository {
-	return &AnimeCharacterStaffLinkRepositoryImpl{db: db}
+	return &AnimeCharacterStaffLinkRepositoryImpl{
+		db:   db,
+		cast: anime_cast.NewAnimeCastRepository(db),
+	}
+}
+
+// Upsert writes the link and refreshes the cast rows of its character, and of
+// the previous character when the link moved, in one transaction.
+func (r *AnimeCharacterStaffLinkRepositoryImpl) Upsert(ctx context.Context, link *AnimeCharacterStaffLink) error {
+	return r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := db.WithTx(ctx, tx)
+		stored, err := r.findStored(ctx, link.ID)
+		if err != nil {
+			return err
+		}
+		if err := r.db.Upsert(ctx, link); err != nil {
+			return err
+		}
+		if stored != nil && stored.CharacterID != link.CharacterID {
+			if err := r.cast.RefreshCharacter(ctx, stored.CharacterID); err != nil {
+				return err
+			}
+		}
+		return r.cast.RefreshCharacter(ctx, link.CharacterID)
+	})
+}
+
+// Delete removes the link and refreshes the cast rows of the stored link's
+// character, delete images may only carry the id.
+func (r *AnimeCharacterStaffLinkRepositoryImpl) Delete(ctx context.Context, link *AnimeCharacterStaffLink) error {
+	return r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := db.WithTx(ctx, tx)
+		stored, err := r.findStored(ctx, link.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(link).Error; err != nil {
+			return err
+		}
+		if stored == nil {
+			return nil
+		}
+		return r.cast.RefreshCharacter(ctx, stored.CharacterID)
+	})
+}
+
+func (r *AnimeCharacterStaffLinkRepositoryImpl) findStored(ctx context.Context, id string) (*AnimeCharacterStaffLink, error) {
+	stored, err := r.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return stored, err
+}
+
+func (r *AnimeCharacterStaffLinkRepositoryImpl) FindByID(ctx context.Context, id string) (*AnimeCharacterStaffLink, error) {
+	var result AnimeCharacterStaffLink
+	err := r.db.Conn(ctx).First(&result, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListAfterID pages through links ordered by id, starting after afterID.
+func (r *AnimeCharacterStaffLinkRepositoryImpl) ListAfterID(ctx context.Context, afterID string, limit int, filter ListFilter) ([]AnimeCharacterStaffLink, error) {
+	query := r.filtered(ctx, r.db.Conn(ctx).Where("id > ?", afterID), filter)
+
+	var links []AnimeCharacterStaffLink
+	err := query.Order("id").Limit(limit).Find(&links).Error
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// Count returns the number of links matching the filter.
+func (r *AnimeCharacterStaffLinkRepositoryImpl) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	var count int64
+	err := r.filtered(ctx, r.db.Conn(ctx).Model(&AnimeCharacterStaffLink{}), filter).Count(&count).Error
+	return count, err
+}
+
+func (r *AnimeCharacterStaffLinkRepositoryImpl) filtered(ctx context.Context, query *gorm.DB, filter ListFilter) *gorm.DB {
+	if filter.AnimeID != "" {
+		query = query.Where("character_id IN (?)", r.db.Conn(ctx).
+			Table("anime_character").
+			Select("id").
+			Where("anime_id = ?", filter.AnimeID))
+	}
+	if filter.CharacterID != "" {
+		query = query.Where("character_id = ?", filter.CharacterID)
+	}
+	if filter.StaffID != "" {
+		query = query.Where("staff_id = ?", filter.StaffID)
+	}
+	if filter.Language != "" {
+		query = query.Where("language = ?", filter.Language)
+	}
+	if filter.RoleType != "" {
+		query = query.Where("role_type = ?", filter.RoleType)
+	}
+	return query
+}
+
+// ListIDRange returns the rows with afterID < id <= throughID ordered by id,
+// an empty throughID leaves the range open ended. Ids compare byte by byte so
+// ranges line up with a source sorted in Go.
+func (r *AnimeCharacterStaffLinkRepositoryImpl) ListIDRange(ctx context.Context, afterID string, throughID string) ([]AnimeCharacterStaffLink, error) {
+	query := r.db.Conn(ctx).Where(r.db.Bytewise("id")+" > ?", afterID)
+	if throughID != "" {
+		query = query.Where(r.db.Bytewise("id")+" <= ?", throughID)
+	}
+
+	var links []AnimeCharacterStaffLink
+	err := query.Order(r.db.Bytewise("id")).Find(&links).Error
+	if err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// ListAnimeIDsByStaff returns the anime of the characters linked to the staff
+// member.
+func (r *AnimeCharacterStaffLinkRepositoryImpl) ListAnimeIDsByStaff(ctx context.Context, staffID string) ([]string, error) {
+	var animeIDs []string
+	err := r.db.Conn(ctx).
+		Table("anime_character_staff_link AS l").
+		Joins("JOIN anime_character AS c ON c.id = l.character_id").
+		Where("l.staff_id = ?", staffID).
+		Distinct().
+		Order("c.anime_id").
+		Pluck("c.anime_id", &animeIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return animeIDs, nil
 }
 
-func (r *AnimeCharacterStaffLinkRepositoryImpl) Upsert(link *AnimeCharacterStaffLink) error {
-	return r.db.DB.
-		Clauses(clause.OnConflict{
-			UpdateAll: true,
-		}).
-		Create(link).
-		Error
+// RepointCharacter moves the links of a character to another character,
+// takes over its name and refreshes the cast rows of both, used when merging
+// duplicates. It returns the moved links as they were before.
+func (r *AnimeCharacterStaffLinkRepositoryImpl) RepointCharacter(ctx context.Context, fromID string, toID string, characterName string) ([]AnimeCharacterStaffLink, error) {
+	var moved []AnimeCharacterStaffLink
+	err := r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := db.WithTx(ctx, tx)
+		if err := tx.Where("character_id = ?", fromID).Order("id").Find(&moved).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&AnimeCharacterStaffLink{}).Where("character_id = ?", fromID).Updates(map[string]interface{}{
+			"character_id":   toID,
+			"character_name": characterName,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := r.cast.RefreshCharacter(ctx, fromID); err != nil {
+			return err
+		}
+		return r.cast.RefreshCharacter(ctx, toID)
+	})
+	return moved, err
 }
 
-func (r *AnimeCharacterStaffLinkRepositoryImpl) Delete(link *AnimeCharacterStaffLink) error {
-	return r.db.DB.Delete(link).Error
+// RepointStaff moves the links of a staff member to another staff member,
+// takes over its names and refreshes the cast rows of both, used when merging
+// duplicates. It returns the moved links as they were before.
+func (r *AnimeCharacterStaffLinkRepositoryImpl) RepointStaff(ctx context.Context, fromID string, toID string, givenName string, familyName string) ([]AnimeCharacterStaffLink, error) {
+	var moved []AnimeCharacterStaffLink
+	err := r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := db.WithTx(ctx, tx)
+		if err := tx.Where("staff_id = ?", fromID).Order("id").Find(&moved).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&AnimeCharacterStaffLink{}).Where("staff_id = ?", fromID).Updates(map[string]interface{}{
+			"staff_id":          toID,
+			"staff_given_name":  givenName,
+			"staff_family_name": familyName,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := r.cast.RefreshStaff(ctx, fromID); err != nil {
+			return err
+		}
+		return r.cast.RefreshStaff(ctx, toID)
+	})
+	return moved, err
 }
@@ -1,24 +1,65 @@
 package anime_staff
 
 import (
+	"strings"
 	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/search_document"
 )
 
 type AnimeStaff struct {
-	ID         string    `gorm:"type:char(36);primaryKey"`
-	Language   string    `gorm:"type:varchar(30);not null"`
-	GivenName  string    `gorm:"type:varchar(255);not null"`
-	FamilyName string    `gorm:"type:varchar(255);not null"`
-	Image      string    `gorm:"type:text"`
-	Birthday   string    `gorm:"type:varchar(255)"`
-	BirthPlace string    `gorm:"type:varchar(255)"`
-	BloodType  string    `gorm:"type:varchar(255)"`
-	Hobbies    string    `gorm:"type:varchar(255)"`
-	Summary    string    `gorm:"type:text"`
-	CreatedAt  time.Time `gorm:"autoCreateTime"`
-	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+	ID         string `gorm:"type:char(36);primaryKey"`
+	Language   string `gorm:"type:varchar(30);not null"`
+	GivenName  string `gorm:"type:varchar(255);not null"`
+	FamilyName string `gorm:"type:varchar(255);not null"`
+	Image      string `gorm:"type:text"`
+	Birthday   string `gorm:"type:varchar(255)"`
+	BirthPlace string `gorm:"type:varchar(255)"`
+	BloodType  string `gorm:"type:varchar(255)"`
+	Hobbies    string `gorm:"type:varchar(255)"`
+	Summary    string `gorm:"type:text"`
+	// derived columns, typed values parsed from Birthday and BloodType, nil
+	// when the raw value is empty or could not be parsed, and NameKey, the
+	// normalized full name used for matching
+	BirthdayMonth *int    `gorm:"type:smallint;derived"`
+	BirthdayDay   *int    `gorm:"type:smallint;derived"`
+	BirthdayYear  *int    `gorm:"type:smallint;derived"`
+	BloodTypeCode *string `gorm:"type:varchar(2);derived"`
+	NameKey       string  `gorm:"type:varchar(512);not null;derived"`
+	// HobbyList is Hobbies split into a list, stored in anime_staff_hobby,
+	// nil keeps the stored hobbies on upsert
+	HobbyList []string  `gorm:"-"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	// Aliases are the other names, stored in entity_alias, nil keeps the
+	// stored aliases on upsert
+	Aliases []entity_alias.EntityAlias `gorm:"-"`
 }
 
 func (AnimeStaff) TableName() string {
 	return "anime_staff"
 }
+
+// SearchDocument returns the searchable text of the staff member, the name
+// is given name first, the terms match in any order.
+func (s *AnimeStaff) SearchDocument() *search_document.SearchDocument {
+	return &search_document.SearchDocument{
+		EntityType: search_document.EntityTypeStaff,
+		EntityID:   s.ID,
+		Name:       strings.TrimSpace(s.GivenName + " " + s.FamilyName),
+		Aliases:    strings.Join(entity_alias.Names(s.Aliases), ", "),
+		Summary:    s.Summary,
+	}
+}
+
+type AnimeStaffHobby struct {
+	StaffID  string `gorm:"type:char(36);primaryKey"`
+	Position int    `gorm:"primaryKey;autoIncrement:false"`
+	Hobby    string `gorm:"type:varchar(255);not null"`
+}
+
+func (AnimeStaffHobby) TableName() string {
+	return "anime_staff_hobby"
+}
@@ -1,51 +1,308 @@
 package anime_staff
 
 import (
+	"context"
+	"time"
+
 	"github.com/weeb-vip/character-staff-sync/internal/db"
-	"gorm.io/gorm/clause"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_cast"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/search_document"
+	"github.com/weeb-vip/character-staff-sync/internal/names"
+	"gorm.io/gorm"
 )
 
+// ListFilter narrows down a paged listing, zero values are ignored.
+type ListFilter struct {
+	// CharacterID only returns staff linked to the character.
+	CharacterID string
+	// AnimeID only returns staff linked to a character of the anime.
+	AnimeID string
+	// Language only returns staff with a link in the language, combined
+	// with CharacterID or AnimeID the same link has to match.
+	Language string
+	// UpdatedSince only returns rows updated at or after the given time.
+	UpdatedSince *time.Time
+	// ImageNotPrefix only returns rows with an image that does not start with
+	// the prefix, used to find images that are not on the CDN yet.
+	ImageNotPrefix string
+}
+
 type AnimeStaffRepository interface {
-	Upsert(staff *AnimeStaff) error
-	Delete(staff *AnimeStaff) error
-	FindByID(id string) (*AnimeStaff, error) // Optional but helpful
-	FindByFullName(givenName string, familyName string) (string, error)
+	Upsert(ctx context.Context, staff *AnimeStaff) error
+	Delete(ctx context.Context, staff *AnimeStaff) error
+	FindByID(ctx context.Context, id string) (*AnimeStaff, error) // Optional but helpful
+	FindByFullName(ctx context.Context, givenName string, familyName string) (string, error)
+	MatchByFullName(ctx context.Context, givenName string, familyName string, limit int) ([]names.Match, error)
+	ListAfterID(ctx context.Context, afterID string, limit int, filter ListFilter) ([]AnimeStaff, error)
+	ListIDRange(ctx context.Context, afterID string, throughID string) ([]AnimeStaff, error)
+	ListDuplicateNameKeys(ctx context.Context, afterKey string, limit int) ([]string, error)
+	ListByNameKey(ctx context.Context, key string) ([]AnimeStaff, error)
+	ListHobbies(ctx context.Context, staffIDs []string) (map[string][]string, error)
 }
 
 type AnimeStaffRepositoryImpl struct {
-	db *db.DB
+	db      *db.DB
+	search  search_document.SearchDocumentRepository
+	cast    anime_cast.AnimeCastRepository
+	aliases entity_alias.EntityAliasRepository
 }
 
 func NewAnimeStaffRepository(db *db.DB) AnimeStaffRepository {
-	return &AnimeStaffRepositoryImpl{db: db}
+	return &AnimeStaffRepositoryImpl{
+		db:      db,
+		search:  search_document.NewSearchDocumentRepository(db),
+		cast:    anime_cast.NewAnimeCastRepository(db),
+		aliases: entity_alias.NewEntityAliasRepository(db),
+	}
 }
 
-func (r *AnimeStaffRepositoryImpl) Upsert(staff *AnimeStaff) error {
-	return r.db.DB.Clauses(clause.OnConflict{
-		UpdateAll: true,
-	}).Create(staff).Error
+// Upsert writes the staff row, its aliases, search document and cast rows and
+// replaces its hobbies in one transaction. A nil hobby list keeps the stored
+// hobbies, so rows loaded from the database can be written back as they are.
+func (r *AnimeStaffRepositoryImpl) Upsert(ctx context.Context, staff *AnimeStaff) error {
+	staff.NameKey = names.Key(names.FullName(staff.GivenName, staff.FamilyName))
+	return r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := db.WithTx(ctx, tx)
+		if err := r.db.Upsert(ctx, staff); err != nil {
+			return err
+		}
+		if err := r.upsertAliases(ctx, staff); err != nil {
+			return err
+		}
+		if err := r.search.Upsert(ctx, staff.SearchDocument()); err != nil {
+			return err
+		}
+		if err := r.cast.RefreshStaff(ctx, staff.ID); err != nil {
+			return err
+		}
+		if staff.HobbyList == nil {
+			return nil
+		}
+		if err := tx.Where("staff_id = ?", staff.ID).Delete(&AnimeStaffHobby{}).Error; err != nil {
+			return err
+		}
+		if len(staff.HobbyList) == 0 {
+			return nil
+		}
+
+		hobbies := make([]AnimeStaffHobby, 0, len(staff.HobbyList))
+		for i, hobby := range staff.HobbyList {
+			hobbies = append(hobbies, AnimeStaffHobby{StaffID: staff.ID, Position: i, Hobby: hobby})
+		}
+		return tx.Create(&hobbies).Error
+	})
 }
 
-func (r *AnimeStaffRepositoryImpl) Delete(staff *AnimeStaff) error {
-	return r.db.DB.Delete(staff).Error
+func (r *AnimeStaffRepositoryImpl) Delete(ctx context.Context, staff *AnimeStaff) error {
+	return r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := db.WithTx(ctx, tx)
+		if err := r.search.Delete(ctx, search_document.EntityTypeStaff, staff.ID); err != nil {
+			return err
+		}
+		if err := r.aliases.Delete(ctx, entity_alias.EntityTypeStaff, staff.ID); err != nil {
+			return err
+		}
+		if err := tx.Where("staff_id = ?", staff.ID).Delete(&AnimeStaffHobby{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(staff).Error; err != nil {
+			return err
+		}
+		// links outlive the staff row, the cast falls back to the link names
+		return r.cast.RefreshStaff(ctx, staff.ID)
+	})
 }
 
-func (r *AnimeStaffRepositoryImpl) FindByID(id string) (*AnimeStaff, error) {
+// FindByID returns the staff member with its aliases and hobbies.
+func (r *AnimeStaffRepositoryImpl) FindByID(ctx context.Context, id string) (*AnimeStaff, error) {
 	var result AnimeStaff
-	err := r.db.DB.First(&result, "id = ?", id).Error
+	err := r.db.Conn(ctx).First(&result, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
+	result.Aliases, err = r.aliases.List(ctx, entity_alias.EntityTypeStaff, id)
+	if err != nil {
+		return nil, err
+	}
+	hobbies, err := r.ListHobbies(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	result.HobbyList = hobbies[id]
+	if result.HobbyList == nil {
+		result.HobbyList = []string{}
+	}
 	return &result, nil
 }
 
-func (r *AnimeStaffRepositoryImpl) FindByFullName(givenName string, familyName string) (string, error) {
+// upsertAliases replaces the stored aliases with the ones sent, or loads the
+// stored aliases for the search document when none were sent.
+func (r *AnimeStaffRepositoryImpl) upsertAliases(ctx context.Context, staff *AnimeStaff) error {
+	if staff.Aliases == nil {
+		aliases, err := r.aliases.List(ctx, entity_alias.EntityTypeStaff, staff.ID)
+		if err != nil {
+			return err
+		}
+		staff.Aliases = aliases
+		return nil
+	}
+	return r.aliases.Replace(ctx, entity_alias.EntityTypeStaff, staff.ID, staff.Aliases)
+}
+
+// FindByFullName returns the staff member whose normalized full name or alias
+// equals the normalized full name, so swapped name order, case, accents and
+// long vowels still match. Rows without a name key yet are matched exactly.
+func (r *AnimeStaffRepositoryImpl) FindByFullName(ctx context.Context, givenName string, familyName string) (string, error) {
+	key := names.Key(names.FullName(givenName, familyName))
+	aliased := r.db.Conn(ctx).Model(&entity_alias.EntityAlias{}).
+		Select("entity_id").
+		Where("entity_type = ? AND name_key = ?", entity_alias.EntityTypeStaff, key)
+
 	var staff AnimeStaff
-	err := r.db.DB.Select("id").
-		Where("given_name = ? AND family_name = ?", givenName, familyName).
+	err := r.db.Conn(ctx).Select("id").
+		Where("(name_key = ? AND name_key <> '') OR (name_key = '' AND given_name = ? AND family_name = ?)",
+			key, givenName, familyName).
+		Or("id IN (?)", aliased).
+		Order("id").
 		First(&staff).Error
 	if err != nil {
 		return "", err
 	}
 	return staff.ID, nil
 }
+
+// MatchByFullName returns up to limit staff members with a full name or alias
+// similar to the given one, best match first, see names.Score for the
+// confidence.
+func (r *AnimeStaffRepositoryImpl) MatchByFullName(ctx context.Context, givenName string, familyName string, limit int) ([]names.Match, error) {
+	key := names.Key(names.FullName(givenName, familyName))
+	fragments := names.Fragments(key)
+	if len(fragments) == 0 {
+		return []names.Match{}, nil
+	}
+
+	query := r.db.Conn(ctx).Select("id", "given_name", "family_name", "name_key")
+	conditions := r.db.Conn(ctx)
+	for _, fragment := range fragments {
+		conditions = conditions.Or("name_key LIKE ?", "%"+fragment+"%")
+	}
+
+	var staff []AnimeStaff
+	err := query.Where(conditions).Limit(names.MaxCandidates).Find(&staff).Error
+	if err != nil {
+		return nil, err
+	}
+
+	aliases, err := r.aliases.ListByFragments(ctx, entity_alias.EntityTypeStaff, fragments, names.MaxCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]names.Match, 0, len(staff)+len(aliases))
+	keys := make([]string, 0, len(staff)+len(aliases))
+	for _, member := range staff {
+		candidates = append(candidates, names.Match{ID: member.ID, Name: names.FullName(member.GivenName, member.FamilyName)})
+		keys = append(keys, member.NameKey)
+	}
+	for _, alias := range aliases {
+		candidates = append(candidates, names.Match{ID: alias.EntityID, Name: alias.Name})
+		keys = append(keys, alias.NameKey)
+	}
+	return names.Rank(key, candidates, keys, names.MinScore, limit), nil
+}
+
+// ListAfterID pages through staff ordered by id, starting after afterID.
+func (r *AnimeStaffRepositoryImpl) ListAfterID(ctx context.Context, afterID string, limit int, filter ListFilter) ([]AnimeStaff, error) {
+	query := r.db.Conn(ctx).Where("id > ?", afterID)
+	if filter.AnimeID != "" || filter.CharacterID != "" || filter.Language != "" {
+		links := r.db.Conn(ctx).Table("anime_character_staff_link AS l").Select("l.staff_id")
+		if filter.AnimeID != "" {
+			links = links.
+				Joins("JOIN anime_character AS c ON c.id = l.character_id").
+				Where("c.anime_id = ?", filter.AnimeID)
+		}
+		if filter.CharacterID != "" {
+			links = links.Where("l.character_id = ?", filter.CharacterID)
+		}
+		if filter.Language != "" {
+			links = links.Where("l.language = ?", filter.Language)
+		}
+		query = query.Where("id IN (?)", links)
+	}
+	if filter.UpdatedSince != nil {
+		query = query.Where("updated_at >= ?", *filter.UpdatedSince)
+	}
+	if filter.ImageNotPrefix != "" {
+		query = query.Where("image <> '' AND image NOT LIKE ?", filter.ImageNotPrefix+"%")
+	}
+
+	var staff []AnimeStaff
+	err := query.Order("id").Limit(limit).Find(&staff).Error
+	if err != nil {
+		return nil, err
+	}
+	return staff, nil
+}
+
+// ListIDRange returns the rows with afterID < id <= throughID ordered by id,
+// an empty throughID leaves the range open ended. Ids compare byte by byte so
+// ranges line up with a source sorted in Go.
+func (r *AnimeStaffRepositoryImpl) ListIDRange(ctx context.Context, afterID string, throughID string) ([]AnimeStaff, error) {
+	query := r.db.Conn(ctx).Where(r.db.Bytewise("id")+" > ?", afterID)
+	if throughID != "" {
+		query = query.Where(r.db.Bytewise("id")+" <= ?", throughID)
+	}
+
+	var staff []AnimeStaff
+	err := query.Order(r.db.Bytewise("id")).Find(&staff).Error
+	if err != nil {
+		return nil, err
+	}
+	return staff, nil
+}
+
+// ListHobbies returns the hobby lists of the staff members keyed by staff id.
+func (r *AnimeStaffRepositoryImpl) ListHobbies(ctx context.Context, staffIDs []string) (map[string][]string, error) {
+	hobbies := map[string][]string{}
+	if len(staffIDs) == 0 {
+		return hobbies, nil
+	}
+
+	var rows []AnimeStaffHobby
+	err := r.db.Conn(ctx).Where("staff_id IN ?", staffIDs).Order("staff_id, position").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		hobbies[row.StaffID] = append(hobbies[row.StaffID], row.Hobby)
+	}
+	return hobbies, nil
+}
+
+// ListDuplicateNameKeys pages through the name keys shared by more than one
+// row, ordered by key and starting after afterKey.
+func (r *AnimeStaffRepositoryImpl) ListDuplicateNameKeys(ctx context.Context, afterKey string, limit int) ([]string, error) {
+	var keys []string
+	err := r.db.Conn(ctx).Model(&AnimeStaff{}).
+		Where("name_key > ?", afterKey).
+		Group("name_key").
+		Having("COUNT(*) > 1").
+		Order("name_key").
+		Limit(limit).
+		Pluck("name_key", &keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ListByNameKey returns the staff members with the name key ordered by id.
+func (r *AnimeStaffRepositoryImpl) ListByNameKey(ctx context.Context, key string) ([]AnimeStaff, error) {
+	var staff []AnimeStaff
+	err := r.db.Conn(ctx).Where("name_key = ?", key).Order("id").Find(&staff).Error
+	if err != nil {
+		return nil, err
+	}
+	return staff, nil
+}
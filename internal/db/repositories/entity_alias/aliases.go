@@ -0,0 +1,106 @@
+package entity_alias
+
+import (
+	"strings"
+)
+
+// languages maps the staff language column to the language of native names.
+var languages = map[string]string{
+	"japanese":   LanguageJapanese,
+	"english":    LanguageEnglish,
+	"korean":     "ko",
+	"chinese":    "zh",
+	"mandarin":   "zh",
+	"french":     "fr",
+	"german":     "de",
+	"spanish":    "es",
+	"italian":    "it",
+	"portuguese": "pt",
+	"brazilian":  "pt",
+	"hungarian":  "hu",
+	"hebrew":     "he",
+}
+
+// NativeLanguage returns the language tag of a staff language such as
+// "Japanese", und when it is not known.
+func NativeLanguage(language string) string {
+	if tag, ok := languages[strings.ToLower(strings.TrimSpace(language))]; ok {
+		return tag
+	}
+	return LanguageUndetermined
+}
+
+// Build returns the aliases sent by the source. It returns nil when the
+// source sent none of the alias fields, the stored aliases are kept then,
+// and an empty list when it sent them all empty. Nicknames are a comma or
+// semicolon separated list.
+func Build(nativeLanguage string, native *string, romaji *string, english *string, nicknames *string) []EntityAlias {
+	if native == nil && romaji == nil && english == nil && nicknames == nil {
+		return nil
+	}
+
+	aliases := []EntityAlias{}
+	add := func(language string, kind Kind, name string) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return
+		}
+		position := 0
+		for _, alias := range aliases {
+			if alias.Kind == kind && alias.Language == language {
+				if alias.Name == name {
+					return
+				}
+				position++
+			}
+		}
+		aliases = append(aliases, EntityAlias{Language: language, Kind: kind, Position: position, Name: name})
+	}
+
+	if native != nil {
+		add(nativeLanguage, KindNative, *native)
+	}
+	if romaji != nil {
+		add(LanguageJapaneseRomaji, KindRomaji, *romaji)
+	}
+	if english != nil {
+		add(LanguageEnglish, KindEnglish, *english)
+	}
+	if nicknames != nil {
+		for _, nickname := range strings.FieldsFunc(*nicknames, func(r rune) bool { return r == ',' || r == ';' }) {
+			add(LanguageUndetermined, KindNickname, nickname)
+		}
+	}
+	return aliases
+}
+
+// Names returns the distinct alias names in order.
+func Names(aliases []EntityAlias) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, alias := range aliases {
+		if !seen[alias.Name] {
+			seen[alias.Name] = true
+			names = append(names, alias.Name)
+		}
+	}
+	return names
+}
+
+// Changed reports whether next differs from the stored aliases, a nil next
+// keeps the stored aliases and never changes them.
+func Changed(stored []EntityAlias, next []EntityAlias) bool {
+	if next == nil {
+		return false
+	}
+	if len(stored) != len(next) {
+		return true
+	}
+	for i := range next {
+		if stored[i].Language != next[i].Language || stored[i].Kind != next[i].Kind ||
+			stored[i].Position != next[i].Position || stored[i].Name != next[i].Name {
+			return true
+		}
+	}
+	return false
+}
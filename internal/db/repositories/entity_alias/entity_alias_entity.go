@@ -0,0 +1,40 @@
+package entity_alias
+
+const (
+	EntityTypeCharacter = "character"
+	EntityTypeStaff     = "staff"
+)
+
+type Kind = string
+
+const (
+	KindNative   Kind = "native"
+	KindRomaji   Kind = "romaji"
+	KindEnglish  Kind = "english"
+	KindNickname Kind = "nickname"
+)
+
+// Language tags of the aliases, BCP 47.
+const (
+	LanguageJapanese       = "ja"
+	LanguageJapaneseRomaji = "ja-Latn"
+	LanguageEnglish        = "en"
+	LanguageUndetermined   = "und"
+)
+
+// EntityAlias is an alternative name of a character or staff member, kinds
+// other than nicknames have a single alias per language.
+type EntityAlias struct {
+	EntityType string `gorm:"type:varchar(16);primaryKey"`
+	EntityID   string `gorm:"type:char(36);primaryKey"`
+	Language   string `gorm:"type:varchar(16);primaryKey"`
+	Kind       string `gorm:"type:varchar(16);primaryKey"`
+	Position   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name       string `gorm:"type:varchar(255);not null"`
+	// NameKey is the normalized Name used for matching
+	NameKey string `gorm:"type:varchar(255);not null"`
+}
+
+func (EntityAlias) TableName() string {
+	return "entity_alias"
+}
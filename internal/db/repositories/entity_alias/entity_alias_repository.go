@@ -0,0 +1,116 @@
+package entity_alias
+
+import (
+	"context"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/names"
+	"gorm.io/gorm"
+)
+
+type EntityAliasRepository interface {
+	Replace(ctx context.Context, entityType string, entityID string, aliases []EntityAlias) error
+	Delete(ctx context.Context, entityType string, entityID string) error
+	List(ctx context.Context, entityType string, entityID string) ([]EntityAlias, error)
+	ListByEntities(ctx context.Context, entityType string, entityIDs []string) (map[string][]EntityAlias, error)
+	ListByFragments(ctx context.Context, entityType string, fragments []string, limit int) ([]EntityAlias, error)
+}
+
+type EntityAliasRepositoryImpl struct {
+	db *db.DB
+}
+
+func NewEntityAliasRepository(db *db.DB) EntityAliasRepository {
+	return &EntityAliasRepositoryImpl{db: db}
+}
+
+// Replace stores aliases as the aliases of the entity, in the order built
+// by Build.
+func (r *EntityAliasRepositoryImpl) Replace(ctx context.Context, entityType string, entityID string, aliases []EntityAlias) error {
+	return r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("entity_type = ? AND entity_id = ?", entityType, entityID).Delete(&EntityAlias{}).Error
+		if err != nil {
+			return err
+		}
+		if len(aliases) == 0 {
+			return nil
+		}
+
+		rows := make([]EntityAlias, len(aliases))
+		for i, alias := range aliases {
+			alias.EntityType = entityType
+			alias.EntityID = entityID
+			alias.NameKey = names.Key(alias.Name)
+			rows[i] = alias
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+func (r *EntityAliasRepositoryImpl) Delete(ctx context.Context, entityType string, entityID string) error {
+	return r.db.Conn(ctx).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Delete(&EntityAlias{}).Error
+}
+
+// List returns the aliases of the entity, an empty list when it has none.
+func (r *EntityAliasRepositoryImpl) List(ctx context.Context, entityType string, entityID string) ([]EntityAlias, error) {
+	aliases := []EntityAlias{}
+	err := r.ordered(r.db.Conn(ctx)).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Find(&aliases).Error
+	if err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// ListByEntities returns the aliases of the entities keyed by entity id.
+func (r *EntityAliasRepositoryImpl) ListByEntities(ctx context.Context, entityType string, entityIDs []string) (map[string][]EntityAlias, error) {
+	aliases := map[string][]EntityAlias{}
+	if len(entityIDs) == 0 {
+		return aliases, nil
+	}
+
+	var rows []EntityAlias
+	err := r.ordered(r.db.Conn(ctx)).
+		Where("entity_type = ? AND entity_id IN ?", entityType, entityIDs).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		aliases[row.EntityID] = append(aliases[row.EntityID], row)
+	}
+	return aliases, nil
+}
+
+// ListByFragments returns up to limit aliases whose name key contains one of
+// the fragments, the candidates of a fuzzy name match.
+func (r *EntityAliasRepositoryImpl) ListByFragments(ctx context.Context, entityType string, fragments []string, limit int) ([]EntityAlias, error) {
+	var aliases []EntityAlias
+	if len(fragments) == 0 {
+		return aliases, nil
+	}
+
+	conditions := r.db.Conn(ctx)
+	for _, fragment := range fragments {
+		conditions = conditions.Or("name_key LIKE ?", "%"+fragment+"%")
+	}
+	err := r.db.Conn(ctx).
+		Where("entity_type = ?", entityType).
+		Where(conditions).
+		Limit(limit).
+		Find(&aliases).Error
+	if err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// ordered sorts aliases the way Build creates them.
+func (r *EntityAliasRepositoryImpl) ordered(query *gorm.DB) *gorm.DB {
+	return query.Order("entity_id").Order(
+		"CASE kind WHEN 'native' THEN 0 WHEN 'romaji' THEN 1 WHEN 'english' THEN 2 ELSE 3 END",
+	).Order("language").Order("position")
+}
@@ -0,0 +1,46 @@
+package entity_history
+
+import (
+	"time"
+)
+
+const (
+	EntityTypeCharacter = "character"
+	EntityTypeStaff     = "staff"
+	EntityTypeLink      = "link"
+)
+
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// EntityHistory is one applied change of a row. Before and After are JSON
+// objects of the row's columns, Before is empty for a created row and After
+// for a deleted one.
+type EntityHistory struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement"`
+	EntityType string `gorm:"type:varchar(16);not null"`
+	EntityID   string `gorm:"type:char(36);not null"`
+	// AnimeID is set for characters and CharacterID for links, they select
+	// the history of an anime's cast
+	AnimeID       string `gorm:"type:varchar(36);not null"`
+	CharacterID   string `gorm:"type:char(36);not null"`
+	Operation     string `gorm:"type:varchar(16);not null"`
+	Before        string `gorm:"type:text;not null"`
+	After         string `gorm:"type:text;not null"`
+	ChangedFields string `gorm:"type:text;not null"`
+	Pipeline      string `gorm:"type:varchar(32);not null"`
+	// MessageID is the broker position of the message that applied the
+	// change, the source columns are the Debezium source coordinates
+	MessageID  string    `gorm:"type:varchar(255);not null"`
+	SourceTxID int64     `gorm:"column:source_tx_id;not null"`
+	SourceLsn  int64     `gorm:"not null"`
+	SourceTsMs int64     `gorm:"not null"`
+	RecordedAt time.Time `gorm:"not null"`
+}
+
+func (EntityHistory) TableName() string {
+	return "entity_history"
+}
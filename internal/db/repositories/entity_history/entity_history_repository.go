@@ -0,0 +1,102 @@
+package entity_history
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+)
+
+// ListFilter selects entities by the anime of a character or the character
+// of a link, zero values are ignored.
+type ListFilter struct {
+	AnimeID      string
+	CharacterIDs []string
+}
+
+type EntityHistoryRepository interface {
+	Create(ctx context.Context, entry *EntityHistory) error
+	List(ctx context.Context, entityType string, entityID string, limit int) ([]EntityHistory, error)
+	ListEntityIDs(ctx context.Context, entityType string, filter ListFilter) ([]string, error)
+	FindAround(ctx context.Context, entityType string, entityID string, at time.Time) (*EntityHistory, *EntityHistory, error)
+}
+
+type EntityHistoryRepositoryImpl struct {
+	db *db.DB
+}
+
+func NewEntityHistoryRepository(db *db.DB) EntityHistoryRepository {
+	return &EntityHistoryRepositoryImpl{db: db}
+}
+
+func (r *EntityHistoryRepositoryImpl) Create(ctx context.Context, entry *EntityHistory) error {
+	return r.db.Conn(ctx).Create(entry).Error
+}
+
+// List returns the most recent limit changes of the entity, oldest first.
+func (r *EntityHistoryRepositoryImpl) List(ctx context.Context, entityType string, entityID string, limit int) ([]EntityHistory, error) {
+	var entries []EntityHistory
+	err := r.db.Conn(ctx).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
+
+// ListEntityIDs returns the distinct ids of the entities with recorded
+// changes matching the filter, ordered by id.
+func (r *EntityHistoryRepositoryImpl) ListEntityIDs(ctx context.Context, entityType string, filter ListFilter) ([]string, error) {
+	query := r.db.Conn(ctx).Model(&EntityHistory{}).Where("entity_type = ?", entityType)
+	if filter.AnimeID != "" {
+		query = query.Where("anime_id = ?", filter.AnimeID)
+	}
+	if filter.CharacterIDs != nil {
+		if len(filter.CharacterIDs) == 0 {
+			return []string{}, nil
+		}
+		query = query.Where("character_id IN ?", filter.CharacterIDs)
+	}
+
+	var ids []string
+	err := query.Distinct("entity_id").Order("entity_id").Pluck("entity_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// FindAround returns the last change of the entity recorded at or before at
+// and the first one recorded after it, nil when there is none.
+func (r *EntityHistoryRepositoryImpl) FindAround(ctx context.Context, entityType string, entityID string, at time.Time) (*EntityHistory, *EntityHistory, error) {
+	var last, next []EntityHistory
+	err := r.db.Conn(ctx).
+		Where("entity_type = ? AND entity_id = ? AND recorded_at <= ?", entityType, entityID, at).
+		Order("id DESC").
+		Limit(1).
+		Find(&last).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	err = r.db.Conn(ctx).
+		Where("entity_type = ? AND entity_id = ? AND recorded_at > ?", entityType, entityID, at).
+		Order("id").
+		Limit(1).
+		Find(&next).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	return first(last), first(next), nil
+}
+
+func first(entries []EntityHistory) *EntityHistory {
+	if len(entries) == 0 {
+		return nil
+	}
+	return &entries[0]
+}
@@ -0,0 +1,23 @@
+package entity_redirect
+
+import (
+	"time"
+)
+
+const (
+	EntityTypeCharacter = "character"
+	EntityTypeStaff     = "staff"
+)
+
+// EntityRedirect points the id of a character or staff member that was merged
+// away to the id it was merged into.
+type EntityRedirect struct {
+	EntityType string    `gorm:"type:varchar(16);primaryKey"`
+	OldID      string    `gorm:"type:char(36);primaryKey"`
+	NewID      string    `gorm:"type:char(36);not null"`
+	MergedAt   time.Time `gorm:"not null"`
+}
+
+func (EntityRedirect) TableName() string {
+	return "entity_redirect"
+}
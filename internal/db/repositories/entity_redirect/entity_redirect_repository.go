@@ -0,0 +1,72 @@
+package entity_redirect
+
+import (
+	"context"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"gorm.io/gorm"
+)
+
+// Resolver follows redirects, the pipelines use it to rewrite ids of merged
+// entities.
+type Resolver interface {
+	// Resolve returns the id the entity was merged into, or id itself when
+	// it was not merged.
+	Resolve(ctx context.Context, entityType string, id string) (string, error)
+}
+
+type EntityRedirectRepository interface {
+	Resolver
+	Redirect(ctx context.Context, entityType string, oldID string, newID string) error
+}
+
+type EntityRedirectRepositoryImpl struct {
+	db *db.DB
+}
+
+func NewEntityRedirectRepository(db *db.DB) EntityRedirectRepository {
+	return &EntityRedirectRepositoryImpl{db: db}
+}
+
+// Redirect records that oldID was merged into newID. Redirects to oldID are
+// moved to newID so every redirect resolves in one lookup, and a redirect
+// away from newID is dropped since newID is live again.
+func (r *EntityRedirectRepositoryImpl) Redirect(ctx context.Context, entityType string, oldID string, newID string) error {
+	return r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := db.WithTx(ctx, tx)
+		err := tx.Where("entity_type = ? AND old_id = ?", entityType, newID).Delete(&EntityRedirect{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&EntityRedirect{}).
+			Where("entity_type = ? AND new_id = ?", entityType, oldID).
+			Update("new_id", newID).Error
+		if err != nil {
+			return err
+		}
+		return r.db.Upsert(ctx, &EntityRedirect{
+			EntityType: entityType,
+			OldID:      oldID,
+			NewID:      newID,
+			MergedAt:   time.Now(),
+		})
+	})
+}
+
+func (r *EntityRedirectRepositoryImpl) Resolve(ctx context.Context, entityType string, id string) (string, error) {
+	if id == "" {
+		return id, nil
+	}
+
+	// most ids have no redirect, Find does not log that as an error like First
+	var redirect EntityRedirect
+	result := r.db.Conn(ctx).Where("entity_type = ? AND old_id = ?", entityType, id).Limit(1).Find(&redirect)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return id, nil
+	}
+	return redirect.NewID, nil
+}
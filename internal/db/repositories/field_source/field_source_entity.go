@@ -0,0 +1,26 @@
+package field_source
+
+import (
+	"time"
+)
+
+const (
+	EntityTypeCharacter = "character"
+	EntityTypeStaff     = "staff"
+	EntityTypeLink      = "link"
+)
+
+// FieldSource is the source that last set a field of a row, together with
+// its priority at that time.
+type FieldSource struct {
+	EntityType string    `gorm:"type:varchar(16);primaryKey"`
+	EntityID   string    `gorm:"type:char(36);primaryKey"`
+	Field      string    `gorm:"type:varchar(64);primaryKey"`
+	Source     string    `gorm:"type:varchar(64);not null"`
+	Priority   int       `gorm:"not null"`
+	UpdatedAt  time.Time `gorm:"not null"`
+}
+
+func (FieldSource) TableName() string {
+	return "field_source"
+}
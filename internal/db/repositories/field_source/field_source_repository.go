@@ -0,0 +1,67 @@
+package field_source
+
+import (
+	"context"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"gorm.io/gorm"
+)
+
+type FieldSourceRepository interface {
+	List(ctx context.Context, entityType string, entityID string) ([]FieldSource, error)
+	Claim(ctx context.Context, entityType string, entityID string, fields []string, source string, priority int) error
+	Delete(ctx context.Context, entityType string, entityID string) error
+}
+
+type FieldSourceRepositoryImpl struct {
+	db *db.DB
+}
+
+func NewFieldSourceRepository(db *db.DB) FieldSourceRepository {
+	return &FieldSourceRepositoryImpl{db: db}
+}
+
+// List returns the owners of the fields of the row ordered by field.
+func (r *FieldSourceRepositoryImpl) List(ctx context.Context, entityType string, entityID string) ([]FieldSource, error) {
+	var owners []FieldSource
+	err := r.db.Conn(ctx).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("field").
+		Find(&owners).Error
+	if err != nil {
+		return nil, err
+	}
+	return owners, nil
+}
+
+// Claim records the source as the owner of the fields.
+func (r *FieldSourceRepositoryImpl) Claim(ctx context.Context, entityType string, entityID string, fields []string, source string, priority int) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	now := time.Now()
+	return r.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := db.WithTx(ctx, tx)
+		for _, field := range fields {
+			err := r.db.Upsert(ctx, &FieldSource{
+				EntityType: entityType,
+				EntityID:   entityID,
+				Field:      field,
+				Source:     source,
+				Priority:   priority,
+				UpdatedAt:  now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *FieldSourceRepositoryImpl) Delete(ctx context.Context, entityType string, entityID string) error {
+	return r.db.Conn(ctx).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Delete(&FieldSource{}).Error
+}
@@ -0,0 +1,18 @@
+package image_request
+
+import (
+	"time"
+)
+
+// ImageRequest is an image url sent to image-sync for an entity, it is not
+// sent again before it expires.
+type ImageRequest struct {
+	EntityType string    `gorm:"type:varchar(16);primaryKey"`
+	EntityID   string    `gorm:"type:char(36);primaryKey"`
+	URLHash    string    `gorm:"column:url_hash;type:char(64);primaryKey"`
+	ExpiresAt  time.Time `gorm:"not null"`
+}
+
+func (ImageRequest) TableName() string {
+	return "image_request"
+}
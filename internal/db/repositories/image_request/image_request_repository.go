@@ -0,0 +1,47 @@
+package image_request
+
+import (
+	"context"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"gorm.io/gorm/clause"
+)
+
+type ImageRequestRepository interface {
+	// Exists reports whether the request is stored and expires after now.
+	Exists(ctx context.Context, entityType string, entityID string, urlHash string, now time.Time) (bool, error)
+	Upsert(ctx context.Context, request *ImageRequest) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type ImageRequestRepositoryImpl struct {
+	db *db.DB
+}
+
+func NewImageRequestRepository(db *db.DB) ImageRequestRepository {
+	return &ImageRequestRepositoryImpl{db: db}
+}
+
+func (r *ImageRequestRepositoryImpl) Exists(ctx context.Context, entityType string, entityID string, urlHash string, now time.Time) (bool, error) {
+	var count int64
+	err := r.db.Conn(ctx).Model(&ImageRequest{}).
+		Where("entity_type = ? AND entity_id = ? AND url_hash = ? AND expires_at > ?", entityType, entityID, urlHash, now).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *ImageRequestRepositoryImpl) Upsert(ctx context.Context, request *ImageRequest) error {
+	return r.db.Conn(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "entity_type"}, {Name: "entity_id"}, {Name: "url_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+	}).Create(request).Error
+}
+
+func (r *ImageRequestRepositoryImpl) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.Conn(ctx).Where("expires_at <= ?", now).Delete(&ImageRequest{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,17 @@
+package processed_message
+
+import (
+	"time"
+)
+
+type ProcessedMessage struct {
+	ID             string    `gorm:"type:char(64);primaryKey"`
+	Pipeline       string    `gorm:"type:varchar(32);not null"`
+	MessageKey     *string   `gorm:"type:varchar(255)"`
+	SourcePosition *string   `gorm:"type:varchar(255)"`
+	ProcessedAt    time.Time `gorm:"not null"`
+}
+
+func (ProcessedMessage) TableName() string {
+	return "processed_message"
+}
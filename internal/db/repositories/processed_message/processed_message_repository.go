@@ -0,0 +1,38 @@
+package processed_message
+
+import (
+	"context"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"gorm.io/gorm/clause"
+)
+
+type ProcessedMessageRepository interface {
+	// Record stores the message and reports false when the message key or
+	// the source position was already recorded for the pipeline.
+	Record(ctx context.Context, message *ProcessedMessage) (bool, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type ProcessedMessageRepositoryImpl struct {
+	db *db.DB
+}
+
+func NewProcessedMessageRepository(db *db.DB) ProcessedMessageRepository {
+	return &ProcessedMessageRepositoryImpl{db: db}
+}
+
+func (r *ProcessedMessageRepositoryImpl) Record(ctx context.Context, message *ProcessedMessage) (bool, error) {
+	// without conflict columns every unique index counts as a conflict
+	result := r.db.Conn(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(message)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *ProcessedMessageRepositoryImpl) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.Conn(ctx).Where("processed_at < ?", before).Delete(&ProcessedMessage{})
+	return result.RowsAffected, result.Error
+}
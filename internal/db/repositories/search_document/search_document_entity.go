@@ -0,0 +1,30 @@
+package search_document
+
+import (
+	"time"
+)
+
+const (
+	EntityTypeCharacter = "character"
+	EntityTypeStaff     = "staff"
+)
+
+// SearchDocument is the searchable text of a character or staff member.
+type SearchDocument struct {
+	EntityType string    `gorm:"type:varchar(16);primaryKey"`
+	EntityID   string    `gorm:"type:char(36);primaryKey"`
+	Name       string    `gorm:"type:varchar(512);not null"`
+	Aliases    string    `gorm:"type:text;not null"`
+	Summary    string    `gorm:"type:text;not null"`
+	UpdatedAt  time.Time `gorm:"not null"`
+}
+
+func (SearchDocument) TableName() string {
+	return "search_document"
+}
+
+// Hit is a matching document with its relevance, higher scores rank first.
+type Hit struct {
+	SearchDocument
+	Score float64
+}
@@ -0,0 +1,117 @@
+package search_document
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+)
+
+// maxTerms caps the number of query terms, longer queries are cut.
+const maxTerms = 8
+
+// postgresVector must match the expression of the search_document_fulltext
+// index or the index is not used.
+const postgresVector = "(setweight(to_tsvector('simple', name || ' ' || aliases), 'A') || setweight(to_tsvector('simple', summary), 'B'))"
+
+// Query searches names, aliases and summaries, every term of Text must
+// match as a word prefix so partial input works as autocomplete.
+type Query struct {
+	Text string
+	// EntityType limits the search to one entity type, empty searches all.
+	EntityType string
+	Limit      int
+}
+
+type SearchDocumentRepository interface {
+	Upsert(ctx context.Context, document *SearchDocument) error
+	Delete(ctx context.Context, entityType string, entityID string) error
+	Search(ctx context.Context, query Query) ([]Hit, error)
+}
+
+type SearchDocumentRepositoryImpl struct {
+	db *db.DB
+}
+
+func NewSearchDocumentRepository(db *db.DB) SearchDocumentRepository {
+	return &SearchDocumentRepositoryImpl{db: db}
+}
+
+func (r *SearchDocumentRepositoryImpl) Upsert(ctx context.Context, document *SearchDocument) error {
+	return r.db.Upsert(ctx, document)
+}
+
+func (r *SearchDocumentRepositoryImpl) Delete(ctx context.Context, entityType string, entityID string) error {
+	return r.db.Conn(ctx).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Delete(&SearchDocument{}).Error
+}
+
+// Search ranks matches by relevance, name and alias matches weigh more than
+// summary matches and names starting with the query rank first.
+func (r *SearchDocumentRepositoryImpl) Search(ctx context.Context, query Query) ([]Hit, error) {
+	terms := Terms(query.Text)
+	if len(terms) == 0 {
+		return []Hit{}, nil
+	}
+
+	var score string
+	var args []interface{}
+	stmt := r.db.Conn(ctx).Model(&SearchDocument{})
+
+	switch r.db.Dialect {
+	case db.DialectMySQL:
+		against := "+" + strings.Join(terms, "* +") + "*"
+		score = "MATCH(name, aliases) AGAINST (? IN BOOLEAN MODE) * 4 + MATCH(name, aliases, summary) AGAINST (? IN BOOLEAN MODE)"
+		args = []interface{}{against, against}
+		stmt = stmt.Where("MATCH(name, aliases, summary) AGAINST (? IN BOOLEAN MODE)", against)
+	case db.DialectPostgres:
+		tsquery := strings.Join(terms, ":* & ") + ":*"
+		score = "ts_rank(" + postgresVector + ", to_tsquery('simple', ?))"
+		args = []interface{}{tsquery}
+		stmt = stmt.Where(postgresVector+" @@ to_tsquery('simple', ?)", tsquery)
+	default:
+		// no full text index, match word prefixes with LIKE
+		var scores []string
+		for _, term := range terms {
+			nameMatch := "(lower(name) LIKE ? OR lower(name) LIKE ? OR lower(aliases) LIKE ? OR lower(aliases) LIKE ?)"
+			nameArgs := []interface{}{term + "%", "% " + term + "%", term + "%", "% " + term + "%"}
+			stmt = stmt.Where(nameMatch+" OR lower(summary) LIKE ?", append(nameArgs, "%"+term+"%")...)
+			scores = append(scores, "CASE WHEN "+nameMatch+" THEN 4 ELSE 1 END")
+			args = append(args, nameArgs...)
+		}
+		score = strings.Join(scores, " + ")
+	}
+
+	score += " + CASE WHEN lower(name) LIKE ? THEN 10 ELSE 0 END"
+	args = append(args, strings.Join(terms, " ")+"%")
+
+	if query.EntityType != "" {
+		stmt = stmt.Where("entity_type = ?", query.EntityType)
+	}
+
+	var hits []Hit
+	err := stmt.
+		Select(fmt.Sprintf("search_document.*, %s AS score", score), args...).
+		Order("score DESC, name, entity_id").
+		Limit(query.Limit).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// Terms lowercases the text and splits it into words, anything that is not
+// a letter or digit separates words so no query syntax reaches the database.
+func Terms(text string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxTerms {
+		terms = terms[:maxTerms]
+	}
+	return terms
+}
@@ -0,0 +1,87 @@
+package search_document_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	migrations "github.com/weeb-vip/character-staff-sync/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/search_document"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Edward Elric", want: []string{"edward", "elric"}},
+		{text: "  full-metal*  ", want: []string{"full", "metal"}},
+		{text: `"+-%_*`, want: []string{}},
+		{text: "エドワード・エルリック", want: []string{"エドワード", "エルリック"}},
+		{text: "a b c d e f g h i j", want: []string{"a", "b", "c", "d", "e", "f", "g", "h"}},
+	}
+	for _, tt := range tests {
+		if got := search_document.Terms(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// TestSearchWithoutFullText covers the LIKE fallback used by dialects
+// without a full text index.
+func TestSearchWithoutFullText(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(t.TempDir(), "search.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+	repo := search_document.NewSearchDocumentRepository(database)
+	for _, document := range []*search_document.SearchDocument{
+		{EntityType: search_document.EntityTypeCharacter, EntityID: "edward", Name: "Edward Elric", Aliases: "エドワード, Fullmetal Alchemist"},
+		{EntityType: search_document.EntityTypeCharacter, EntityID: "alphonse", Name: "Alphonse Elric", Summary: "The younger brother of Edward."},
+		{EntityType: search_document.EntityTypeStaff, EntityID: "romi", Name: "Romi Park", Summary: "Voices Edward in both series."},
+	} {
+		if err := repo.Upsert(ctx, document); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query search_document.Query
+		want  []string
+	}{
+		{name: "name prefix ranks first", query: search_document.Query{Text: "edw"}, want: []string{"edward", "alphonse", "romi"}},
+		{name: "word prefix", query: search_document.Query{Text: "ELR"}, want: []string{"alphonse", "edward"}},
+		{name: "every term matches", query: search_document.Query{Text: "full alch"}, want: []string{"edward"}},
+		{name: "native alias", query: search_document.Query{Text: "エドワード"}, want: []string{"edward"}},
+		{name: "entity type", query: search_document.Query{Text: "edw", EntityType: search_document.EntityTypeStaff}, want: []string{"romi"}},
+		{name: "limit", query: search_document.Query{Text: "edw", Limit: 1}, want: []string{"edward"}},
+		{name: "like wildcards are not terms", query: search_document.Query{Text: "%"}, want: nil},
+		{name: "no match", query: search_document.Query{Text: "winry"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.query.Limit == 0 {
+				tt.query.Limit = 10
+			}
+			hits, err := repo.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, hit := range hits {
+				got = append(got, hit.EntityID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %q, want %q", tt.query.Text, got, tt.want)
+			}
+		})
+	}
+}
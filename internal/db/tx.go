@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// WithTx returns a copy of ctx carrying tx, repositories writing with Conn
+// join the transaction.
+func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// Conn returns the transaction attached to ctx, or the connection pool when
+// there is none.
+func (d *DB) Conn(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok && tx != nil {
+		return tx
+	}
+	return d.DB.WithContext(ctx)
+}
+
+type afterCommitKey struct{}
+
+// afterCommit holds the functions deferred until a transaction committed.
+type afterCommit struct {
+	mu  sync.Mutex
+	fns []func(ctx context.Context) error
+}
+
+// WithAfterCommit returns a copy of ctx deferring the functions passed to
+// AfterCommit, and commit running them in order with ctx, outside the
+// transaction. commit is called once the transaction committed, a rolled
+// back transaction drops them.
+func WithAfterCommit(ctx context.Context) (context.Context, func() error) {
+	deferred := &afterCommit{}
+	commit := func() error {
+		deferred.mu.Lock()
+		fns := deferred.fns
+		deferred.fns = nil
+		deferred.mu.Unlock()
+
+		for _, fn := range fns {
+			if err := fn(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return context.WithValue(ctx, afterCommitKey{}, deferred), commit
+}
+
+// AfterCommit runs fn once the transaction of ctx committed, see
+// WithAfterCommit, or right away when nothing defers it. Events published
+// from a write go through it so a rolled back write publishes nothing.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	deferred, ok := ctx.Value(afterCommitKey{}).(*afterCommit)
+	if !ok {
+		return fn(ctx)
+	}
+	deferred.mu.Lock()
+	defer deferred.mu.Unlock()
+	deferred.fns = append(deferred.fns, fn)
+	return nil
+}
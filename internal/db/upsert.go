@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Upsert inserts value or updates the existing row with the same primary
+// key. Every column is updated except the primary key and autoCreateTime
+// columns, so created_at keeps the time the row was first created while
+// updated_at takes the value given instead of the current time.
+func (d *DB) Upsert(ctx context.Context, value interface{}) error {
+	return d.upsert(d.Conn(ctx), value)
+}
+
+// UpsertTable is Upsert into table instead of the table of value.
+func (d *DB) UpsertTable(ctx context.Context, table string, value interface{}) error {
+	return d.upsert(d.Conn(ctx).Table(table), value)
+}
+
+func (d *DB) upsert(conn *gorm.DB, value interface{}) error {
+	stmt := &gorm.Statement{DB: d.DB}
+	if err := stmt.Parse(value); err != nil {
+		return err
+	}
+
+	var conflict []clause.Column
+	for _, field := range stmt.Schema.PrimaryFields {
+		conflict = append(conflict, clause.Column{Name: field.DBName})
+	}
+
+	var columns []string
+	for _, name := range stmt.Schema.DBNames {
+		field := stmt.Schema.FieldsByDBName[name]
+		if field.PrimaryKey || field.AutoCreateTime > 0 {
+			continue
+		}
+		columns = append(columns, name)
+	}
+
+	return conn.Clauses(clause.OnConflict{
+		Columns:   conflict,
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(value).Error
+}
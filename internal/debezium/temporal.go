@@ -0,0 +1,137 @@
+package debezium
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Numeric temporal values carry no unit once the schema is stripped, the
+// unit is picked by magnitude. The bounds keep every unit unambiguous for
+// dates between 1970 and roughly 2200.
+const (
+	// io.debezium.time.Date, days since the epoch
+	maxDays = 100_000
+	// io.debezium.time.Timestamp and plain epoch millis
+	maxMillis = 10_000_000_000_000
+	// io.debezium.time.MicroTimestamp
+	maxMicros = 10_000_000_000_000_000
+)
+
+var zonedLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// Timestamp decodes any of the Debezium temporal representations: Date,
+// Timestamp (epoch millis), MicroTimestamp, NanoTimestamp and
+// ZonedTimestamp. It encodes back to the value it was decoded from so
+// payloads are passed on unchanged.
+type Timestamp struct {
+	Time time.Time
+	raw  json.RawMessage
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*t = Timestamp{}
+		return nil
+	}
+
+	var parsed time.Time
+	var err error
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		parsed, err = ParseTime(s)
+	} else {
+		parsed, err = ParseNumber(string(data))
+	}
+	if err != nil {
+		return err
+	}
+
+	t.Time = parsed
+	t.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if len(t.raw) > 0 {
+		return t.raw, nil
+	}
+	if t.Time.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(strconv.FormatInt(t.Time.UnixMilli(), 10)), nil
+}
+
+// ParseNumber decodes an epoch based number, see the bounds for the units.
+func ParseNumber(s string) (time.Time, error) {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil {
+			return time.Time{}, fmt.Errorf("invalid temporal value %q", s)
+		}
+		v = int64(f)
+	}
+
+	abs := v
+	if abs < 0 {
+		abs = -abs
+	}
+
+	switch {
+	case abs < maxDays:
+		return time.Unix(0, 0).UTC().AddDate(0, 0, int(v)), nil
+	case abs < maxMillis:
+		return time.UnixMilli(v).UTC(), nil
+	case abs < maxMicros:
+		return time.UnixMicro(v).UTC(), nil
+	default:
+		return time.Unix(0, v).UTC(), nil
+	}
+}
+
+// ParseTime decodes a ZonedTimestamp or a date string, numbers in a string
+// are decoded like ParseNumber.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return ParseNumber(s)
+	}
+
+	for _, layout := range zonedLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid temporal value %q", s)
+}
+
+// CreatedAt returns the creation time of a row, zero when the source did not
+// send one so the database default applies.
+func CreatedAt(t *Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.Time
+}
+
+// UpdatedAt returns the modification time of a row, now when the source did
+// not send one.
+func UpdatedAt(t *Timestamp) time.Time {
+	if t == nil || t.Time.IsZero() {
+		return time.Now()
+	}
+	return t.Time
+}
@@ -0,0 +1,66 @@
+package debezium
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    time.Time
+		wantErr bool
+	}{
+		{raw: "0", want: time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{raw: "19000", want: time.Date(2022, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{raw: "-1", want: time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{raw: "1700000000000", want: time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)},
+		{raw: "1700000000000123", want: time.Date(2023, 11, 14, 22, 13, 20, 123000, time.UTC)},
+		{raw: "1700000000000123456", want: time.Date(2023, 11, 14, 22, 13, 20, 123456, time.UTC)},
+		{raw: "1700000000000.0", want: time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)},
+		{raw: "soon", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseNumber(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNumber(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseNumber(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    time.Time
+		wantErr bool
+	}{
+		{raw: "2023-11-14T22:13:20Z", want: time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)},
+		{raw: "2023-11-15T07:13:20.5+09:00", want: time.Date(2023, 11, 14, 22, 13, 20, 500000000, time.UTC)},
+		{raw: "2023-11-14T22:13:20.123456", want: time.Date(2023, 11, 14, 22, 13, 20, 123456000, time.UTC)},
+		{raw: "2023-11-14 22:13:20", want: time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)},
+		{raw: "2023-11-14", want: time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC)},
+		{raw: " 1700000000000 ", want: time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)},
+		{raw: "19000", want: time.Date(2022, 1, 8, 0, 0, 0, 0, time.UTC)},
+		{raw: "14/11/2023", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseTime(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTime(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
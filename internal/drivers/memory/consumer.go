@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/consumer"
+	"go.uber.org/zap"
+)
+
+// Consumer is an in-memory consumer.Consumer. Messages that fail to process
+// are not acked, like the pulsar consumer they are left for redelivery.
+type Consumer[T any] struct {
+	topic    string
+	messages []*Message
+	acked    []*Message
+	failed   []*Message
+	// StopWhenDrained makes Receive return once every published message was
+	// processed instead of waiting for new ones.
+	StopWhenDrained bool
+	notify          chan struct{}
+	next            int
+	mu              sync.Mutex
+}
+
+func NewConsumer[T any](topic string) *Consumer[T] {
+	return &Consumer[T]{
+		topic:  topic,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+var _ consumer.Consumer[struct{}] = &Consumer[struct{}]{}
+
+// Publish queues a message for Receive.
+func (c *Consumer[T]) Publish(key string, payload []byte) {
+	c.mu.Lock()
+	c.messages = append(c.messages, &Message{
+		topic:       c.topic,
+		key:         key,
+		payload:     payload,
+		properties:  map[string]string{},
+		id:          pulsar.NewMessageID(0, int64(len(c.messages)), 0, 0),
+		publishTime: time.Now(),
+	})
+	c.mu.Unlock()
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Consumer[T]) Receive(ctx context.Context, process func(ctx context.Context, msg pulsar.Message) error) error {
+	log := logger.FromCtx(ctx)
+
+	for {
+		msg, ok := c.take()
+		if !ok {
+			if c.StopWhenDrained {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-c.notify:
+				continue
+			}
+		}
+
+		log.Info("Received message", zap.String("msgId", msg.ID().String()))
+
+		if err := process(ctx, msg); err != nil {
+			log.Warn("error processing message: ", zap.String("error", err.Error()))
+			c.mu.Lock()
+			c.failed = append(c.failed, msg)
+			c.mu.Unlock()
+			continue
+		}
+
+		c.mu.Lock()
+		c.acked = append(c.acked, msg)
+		c.mu.Unlock()
+	}
+}
+
+func (c *Consumer[T]) take() (*Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next >= len(c.messages) {
+		return nil, false
+	}
+	msg := c.messages[c.next]
+	c.next++
+	return msg, true
+}
+
+func (c *Consumer[T]) Acked() []*Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*Message(nil), c.acked...)
+}
+
+func (c *Consumer[T]) Failed() []*Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*Message(nil), c.failed...)
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/ThatCatDev/ep/v2/drivers"
+	"github.com/ThatCatDev/ep/v2/event"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Driver is an in-memory drivers.Driver, every topic is a slice of messages
+// with a committed offset per topic.
+type Driver struct {
+	topics map[string][]*kafka.Message
+	offset map[string]int
+	// StopWhenDrained makes Consume return once the topic has no pending
+	// messages instead of waiting for new ones.
+	StopWhenDrained bool
+	notify          chan struct{}
+	mu              sync.Mutex
+}
+
+var _ drivers.Driver[*kafka.Message] = &Driver{}
+
+func NewDriver() *Driver {
+	return &Driver{
+		topics: map[string][]*kafka.Message{},
+		offset: map[string]int{},
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (d *Driver) Consume(ctx context.Context, topic string, handler func(context.Context, *kafka.Message, []byte) error) error {
+	for {
+		message, ok := d.next(topic)
+		if !ok {
+			if d.StopWhenDrained {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-d.notify:
+				continue
+			}
+		}
+
+		if err := handler(ctx, message, message.Value); err != nil {
+			return err
+		}
+		d.commit(topic)
+	}
+}
+
+func (d *Driver) next(topic string) (*kafka.Message, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	offset := d.offset[topic]
+	if offset >= len(d.topics[topic]) {
+		return nil, false
+	}
+	return d.topics[topic][offset], true
+}
+
+func (d *Driver) commit(topic string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.offset[topic]++
+}
+
+func (d *Driver) Produce(ctx context.Context, topic string, message *kafka.Message) error {
+	d.mu.Lock()
+	stored := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{
+			Topic:     &topic,
+			Partition: 0,
+			Offset:    kafka.Offset(len(d.topics[topic])),
+		},
+		Value:   message.Value,
+		Key:     message.Key,
+		Headers: message.Headers,
+	}
+	d.topics[topic] = append(d.topics[topic], stored)
+	d.mu.Unlock()
+
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (d *Driver) CreateTopic(ctx context.Context, topic string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.topics[topic]; !ok {
+		d.topics[topic] = nil
+	}
+	return nil
+}
+
+func (d *Driver) Close() error {
+	return nil
+}
+
+// ExtractEvent mirrors the kafka driver so the middleware chain sees the same
+// RawData as it does with a broker.
+func (d *Driver) ExtractEvent(data *kafka.Message) (*event.SubData[*kafka.Message], error) {
+	eventData := &event.SubData[*kafka.Message]{
+		DriverMessage: data,
+	}
+	headers := map[string]string{}
+	for _, v := range data.Headers {
+		headers[v.Key] = string(v.Value)
+	}
+	eventData.Headers = headers
+
+	msgByte, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(msgByte, &eventData.RawData)
+
+	return eventData, err
+}
+
+// Messages returns every message produced to the topic.
+func (d *Driver) Messages(topic string) []*kafka.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	messages := make([]*kafka.Message, len(d.topics[topic]))
+	copy(messages, d.topics[topic])
+	return messages
+}
+
+// Pending returns the number of produced messages not consumed yet.
+func (d *Driver) Pending(topic string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.topics[topic]) - d.offset[topic]
+}
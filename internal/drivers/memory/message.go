@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+// Message is an in-memory pulsar.Message.
+type Message struct {
+	topic       string
+	key         string
+	payload     []byte
+	properties  map[string]string
+	id          pulsar.MessageID
+	publishTime time.Time
+	redelivery  uint32
+}
+
+var _ pulsar.Message = &Message{}
+
+func (m *Message) Topic() string {
+	return m.topic
+}
+
+func (m *Message) ProducerName() string {
+	return "memory"
+}
+
+func (m *Message) Properties() map[string]string {
+	return m.properties
+}
+
+func (m *Message) Payload() []byte {
+	return m.payload
+}
+
+func (m *Message) ID() pulsar.MessageID {
+	return m.id
+}
+
+func (m *Message) PublishTime() time.Time {
+	return m.publishTime
+}
+
+func (m *Message) EventTime() time.Time {
+	return m.publishTime
+}
+
+func (m *Message) Key() string {
+	return m.key
+}
+
+func (m *Message) OrderingKey() string {
+	return m.key
+}
+
+func (m *Message) RedeliveryCount() uint32 {
+	return m.redelivery
+}
+
+func (m *Message) IsReplicated() bool {
+	return false
+}
+
+func (m *Message) GetReplicatedFrom() string {
+	return ""
+}
+
+func (m *Message) GetSchemaValue(v interface{}) error {
+	return json.Unmarshal(m.payload, v)
+}
+
+func (m *Message) SchemaVersion() []byte {
+	return nil
+}
+
+func (m *Message) GetEncryptionContext() *pulsar.EncryptionContext {
+	return nil
+}
+
+func (m *Message) Index() *uint64 {
+	return nil
+}
+
+func (m *Message) BrokerPublishTime() *time.Time {
+	return nil
+}
@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/weeb-vip/character-staff-sync/internal/producer"
+)
+
+// Producer is an in-memory producer.Producer that keeps every sent payload.
+type Producer[T any] struct {
+	messages [][]byte
+	mu       sync.Mutex
+}
+
+func NewProducer[T any]() *Producer[T] {
+	return &Producer[T]{}
+}
+
+var _ producer.Producer[struct{}] = &Producer[struct{}]{}
+
+func (p *Producer[T]) Send(ctx context.Context, data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.messages = append(p.messages, append([]byte(nil), data...))
+	return nil
+}
+
+func (p *Producer[T]) Messages() [][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([][]byte(nil), p.messages...)
+}
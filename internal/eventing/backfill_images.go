@@ -0,0 +1,79 @@
+package eventing
+
+import (
+	"context"
+
+	"github.com/ThatCatDev/ep/v2/drivers"
+	epKafka "github.com/ThatCatDev/ep/v2/drivers/kafka"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/routing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/backfill"
+	"go.uber.org/zap"
+)
+
+// BackfillImages re-emits image-sync events for rows already in the database
+// through the configured producer.
+func BackfillImages(opt backfill.Options) error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	driver := epKafka.NewKafkaDriver(&epKafka.KafkaConfig{
+		ConsumerGroupName: cfg.KafkaConfig.ConsumerGroupName,
+		BootstrapServers:  cfg.KafkaConfig.BootstrapServers,
+	})
+	defer func(driver drivers.Driver[*kafka.Message]) {
+		err := driver.Close()
+		if err != nil {
+			log.Error("Error closing Kafka driver", zap.String("error", err.Error()))
+		}
+	}(driver)
+
+	router, err := newRouter(ctx, cfg, driver)
+	if err != nil {
+		log.Error("Error creating router", zap.Error(err))
+		return err
+	}
+	defer closeRouter(ctx, router)
+
+	characterProducer := KafkaProducer(ctx, driver, cfg.KafkaConfig.ProducerTopic)
+	staffProducer := characterProducer
+	if router != nil {
+		characterProducer = router.Producer(routing.EventTypeCharacter)
+		staffProducer = router.Producer(routing.EventTypeStaff)
+	}
+
+	database := db.NewDB(cfg.DBConfig)
+	opt.Images = newImageValidator(cfg)
+
+	backfillInstance := backfill.NewBackfill(
+		opt,
+		anime_character.NewAnimeCharacterRepository(database),
+		anime_staff.NewAnimeStaffRepository(database),
+		characterProducer,
+		staffProducer,
+	)
+
+	log.Info("Starting image backfill", zap.Strings("entities", opt.Entities))
+	stats, err := backfillInstance.Run(ctx)
+	if err != nil {
+		log.Error("Error backfilling images", zap.Error(err))
+		return err
+	}
+
+	for _, entity := range opt.Entities {
+		log.Info("Image backfill summary", zap.String("entity", entity),
+			zap.Int("scanned", stats.Scanned[entity]),
+			zap.Int("emitted", stats.Emitted[entity]),
+			zap.Int("skipped", stats.Skipped[entity]),
+			zap.Int("rejected", stats.Rejected[entity]))
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package eventing
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThatCatDev/ep/v2/drivers"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/routing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/cast_events"
+)
+
+// newCastRecorder returns the cast change aggregator when cast events are
+// enabled, nil otherwise. stop emits the changes still pending.
+func newCastRecorder(ctx context.Context, cfg config.Config, driver drivers.Driver[*kafka.Message], router routing.Router, database *db.DB) (cast_events.Recorder, func()) {
+	if !cfg.CastEventsConfig.Enabled {
+		return nil, func() {}
+	}
+
+	castProducer := KafkaProducer(ctx, driver, cfg.KafkaConfig.ProducerTopic)
+	if router != nil {
+		castProducer = router.Producer(routing.EventTypeCast)
+	}
+
+	aggregator := cast_events.NewAggregator(ctx, cast_events.Options{
+		Window:  time.Duration(cfg.CastEventsConfig.WindowMs) * time.Millisecond,
+		MaxWait: time.Duration(cfg.CastEventsConfig.MaxWaitMs) * time.Millisecond,
+
+		RetryInterval:    time.Duration(cfg.CastEventsConfig.RetryMs) * time.Millisecond,
+		MaxRetryInterval: time.Duration(cfg.CastEventsConfig.MaxRetryMs) * time.Millisecond,
+	}, cast_events.NewResolver(
+		anime_character.NewAnimeCharacterRepository(database),
+		anime_character_staff_link.NewAnimeCharacterStaffLinkRepository(database),
+	), castProducer)
+
+	return aggregator, aggregator.Close
+}
@@ -0,0 +1,144 @@
+package eventing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ThatCatDev/ep/v2/drivers"
+	epKafka "github.com/ThatCatDev/ep/v2/drivers/kafka"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/routing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/duplicates"
+	"go.uber.org/zap"
+)
+
+// FindDuplicates writes the merge candidates as JSON lines to output, or to
+// stdout when output is empty.
+func FindDuplicates(opt duplicates.Options, output string) error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	database := db.NewDB(cfg.DBConfig)
+	detector := duplicates.NewDetector(
+		anime_character.NewAnimeCharacterRepository(database),
+		anime_staff.NewAnimeStaffRepository(database),
+		anime_character_staff_link.NewAnimeCharacterStaffLinkRepository(database),
+	)
+
+	candidates, err := detector.Find(ctx, opt)
+	if err != nil {
+		log.Error("Error finding duplicates", zap.Error(err))
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, candidate := range candidates {
+		if err := encoder.Encode(candidate); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Found merge candidates", zap.String("entityType", opt.EntityType), zap.Int("candidates", len(candidates)))
+	return nil
+}
+
+// pipelineMerge is recorded as the pipeline of the changes a merge applies.
+const pipelineMerge = "merge"
+
+// MergeDuplicates merges the MergeID of every candidate into its KeepID and
+// emits a merge event per merge through the configured producer.
+func MergeDuplicates(candidates []duplicates.Candidate) error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	driver := epKafka.NewKafkaDriver(&epKafka.KafkaConfig{
+		ConsumerGroupName: cfg.KafkaConfig.ConsumerGroupName,
+		BootstrapServers:  cfg.KafkaConfig.BootstrapServers,
+	})
+	defer func(driver drivers.Driver[*kafka.Message]) {
+		err := driver.Close()
+		if err != nil {
+			log.Error("Error closing Kafka driver", zap.String("error", err.Error()))
+		}
+	}(driver)
+
+	router, err := newRouter(ctx, cfg, driver)
+	if err != nil {
+		log.Error("Error creating router", zap.Error(err))
+		return err
+	}
+	defer closeRouter(ctx, router)
+
+	characterProducer := KafkaProducer(ctx, driver, cfg.KafkaConfig.ProducerTopic)
+	staffProducer := characterProducer
+	if router != nil {
+		characterProducer = router.Producer(routing.EventTypeCharacter)
+		staffProducer = router.Producer(routing.EventTypeStaff)
+	}
+
+	database := db.NewDB(cfg.DBConfig)
+
+	castChanges, stopCastChanges := newCastRecorder(ctx, cfg, driver, router, database)
+	defer stopCastChanges()
+
+	merger := duplicates.NewMerger(duplicates.MergeOptions{
+		CastChanges: castChanges,
+		History:     newHistoryRecorder(ctx, cfg, database, pipelineMerge),
+	}, database, characterProducer, staffProducer)
+
+	for _, candidate := range candidates {
+		if _, err := merger.Merge(ctx, candidate.EntityType, candidate.MergeID, candidate.KeepID); err != nil {
+			log.Error("Error merging duplicate", zap.String("entityType", candidate.EntityType),
+				zap.String("from", candidate.MergeID), zap.String("into", candidate.KeepID), zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadCandidates reads the JSON lines written by FindDuplicates.
+func ReadCandidates(path string) ([]duplicates.Candidate, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var candidates []duplicates.Candidate
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var candidate duplicates.Candidate
+		if err := json.Unmarshal(scanner.Bytes(), &candidate); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, scanner.Err()
+}
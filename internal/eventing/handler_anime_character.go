@@ -11,8 +11,11 @@ import (
 	"github.com/weeb-vip/character-staff-sync/config"
 	"github.com/weeb-vip/character-staff-sync/internal"
 	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_redirect"
 	"github.com/weeb-vip/character-staff-sync/internal/logger"
 	"github.com/weeb-vip/character-staff-sync/internal/producer"
+	"github.com/weeb-vip/character-staff-sync/internal/ratelimit"
+	"github.com/weeb-vip/character-staff-sync/internal/routing"
 	"github.com/weeb-vip/character-staff-sync/internal/services/consumer"
 	"github.com/weeb-vip/character-staff-sync/internal/services/processor"
 	"github.com/weeb-vip/character-staff-sync/internal/services/pulsar_anime_character_postgres_processor"
@@ -52,26 +55,75 @@ func EventingAnimeCharacter() error {
 
 	database := db.NewDB(cfg.DBConfig)
 
+	sourceGuard, err := newSourceGuard(ctx, cfg, database, PipelineCharacter)
+	if err != nil {
+		log.Error("Error creating source guard", zap.Error(err))
+		return err
+	}
+
+	imageRequests, stopImageRequests, err := newImageDedupe(ctx, cfg, database)
+	if err != nil {
+		log.Error("Error creating image dedupe cache", zap.Error(err))
+		return err
+	}
+	defer stopImageRequests()
+
 	processorOptions := pulsar_anime_character_postgres_processor.Options{
 		NoErrorOnDelete: true,
+		Redirects:       entity_redirect.NewEntityRedirectRepository(database),
+		Sources:         sourceGuard,
+		History:         newHistoryRecorder(ctx, cfg, database, PipelineCharacter),
+		Images:          newImageValidator(cfg),
+		ImageRequests:   imageRequests,
 	}
 
-	characterProducer := producer.NewProducer[pulsar_anime_character_postgres_processor.ProducerPayload](ctx, cfg.PulsarConfig)
+	router, err := newRouter(ctx, cfg, driver)
+	if err != nil {
+		log.Error("Error creating router", zap.Error(err))
+		return err
+	}
+	defer closeRouter(ctx, router)
+
+	castChanges, stopCastChanges := newCastRecorder(ctx, cfg, driver, router, database)
+	defer stopCastChanges()
+	processorOptions.CastChanges = castChanges
+
+	var characterProducer producer.Producer[pulsar_anime_character_postgres_processor.ProducerPayload]
+	characterKafkaProducer := KafkaProducer(ctx, driver, cfg.KafkaConfig.Topic)
+	if router != nil {
+		// routed image requests take the shape of the kafka pipeline
+		processorOptions.Routed = true
+		characterKafkaProducer = router.Producer(routing.EventTypeCharacter)
+	} else {
+		characterProducer = producer.NewProducer[pulsar_anime_character_postgres_processor.ProducerPayload](ctx, cfg.PulsarConfig)
+	}
 
 	characterProcessor := pulsar_anime_character_postgres_processor.NewPulsarAnimeCharacterPostgresProcessor(
 		processorOptions,
 		database,
 		characterProducer,
-		KafkaProducer(ctx, driver, cfg.KafkaConfig.Topic),
+		characterKafkaProducer,
 	)
 
 	messageProcessor := processor.NewProcessor[pulsar_anime_character_postgres_processor.Payload]()
 
-	characterConsumer := consumer.NewConsumer[pulsar_anime_character_postgres_processor.Payload](ctx, cfg.PulsarConfig)
+	characterConsumer := consumer.NewConsumer[pulsar_anime_character_postgres_processor.Payload](ctx, cfg.PulsarConfig, PipelineCharacter, cfg.PulsarConfig.CharacterConcurrency)
 
 	log.Info("Starting anime character eventing")
-	err := characterConsumer.Receive(ctx, func(ctx context.Context, msg pulsar.Message) error {
-		return messageProcessor.Process(ctx, string(msg.Payload()), characterProcessor.Process)
+	stopRateLimits, err := startRateLimits(ctx, cfg)
+	if err != nil {
+		log.Error("Error configuring rate limits", zap.Error(err))
+		return err
+	}
+	defer stopRateLimits()
+
+	messageLedger, stopLedger := newLedger(ctx, cfg, database)
+	defer stopLedger()
+
+	err = characterConsumer.Receive(ctx, func(ctx context.Context, msg pulsar.Message) error {
+		return applyPulsarOnce(ctx, messageLedger, PipelineCharacter, msg, func(ctx context.Context) error {
+			return messageProcessor.ProcessOnce(ctx, string(msg.Payload()), characterProcessor.Process)
+		})
 	})
 	if err != nil {
 		log.Error(fmt.Sprintf("Error receiving character message: %v", err))
@@ -91,8 +143,12 @@ func addFFToCtx(ctx context.Context, cfg config.Config) context.Context {
 }
 
 func KafkaProducer(ctx context.Context, driver drivers.Driver[*kafka.Message], topic string) func(ctx context.Context, message *kafka.Message) error {
+	sink := routing.SinkSpec{Kind: routing.SinkKindKafka, Target: topic}.String()
 	return func(ctx context.Context, message *kafka.Message) error {
 		log := logger.FromCtx(ctx)
+		if err := ratelimit.Default.Wait(ctx, ratelimit.DirectionProduce, sink); err != nil {
+			return err
+		}
 		log.Info("Producing message to Kafka", zap.String("topic", topic), zap.String("key", string(message.Key)), zap.String("value", string(message.Value)))
 		if err := driver.Produce(ctx, topic, message); err != nil {
 			log.Error("Failed to produce message", zap.String("topic", topic), zap.Error(err))
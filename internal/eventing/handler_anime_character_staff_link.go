@@ -4,28 +4,75 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/ThatCatDev/ep/v2/drivers"
+	epKafka "github.com/ThatCatDev/ep/v2/drivers/kafka"
 	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/weeb-vip/character-staff-sync/config"
 	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_redirect"
 	"github.com/weeb-vip/character-staff-sync/internal/logger"
 	"github.com/weeb-vip/character-staff-sync/internal/producer"
+	"github.com/weeb-vip/character-staff-sync/internal/routing"
 	"github.com/weeb-vip/character-staff-sync/internal/services/consumer"
 	"github.com/weeb-vip/character-staff-sync/internal/services/processor"
 	"github.com/weeb-vip/character-staff-sync/internal/services/pulsar_anime_character_staff_link_postgres_processor"
+	"go.uber.org/zap"
 )
 
 func EventingAnimeCharacterStaffLink() error {
 	cfg := config.LoadConfigOrPanic()
 	ctx := context.Background()
 	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
 
 	database := db.NewDB(cfg.DBConfig)
 
+	sourceGuard, err := newSourceGuard(ctx, cfg, database, PipelineLink)
+	if err != nil {
+		log.Error("Error creating source guard", zap.Error(err))
+		return err
+	}
+
 	processorOptions := pulsar_anime_character_staff_link_postgres_processor.Options{
 		NoErrorOnDelete: true,
+		Redirects:       entity_redirect.NewEntityRedirectRepository(database),
+		Sources:         sourceGuard,
+		History:         newHistoryRecorder(ctx, cfg, database, PipelineLink),
 	}
 
-	linkProducer := producer.NewProducer[pulsar_anime_character_staff_link_postgres_processor.ProducerPayload](ctx, cfg.PulsarConfig)
+	var driver drivers.Driver[*kafka.Message]
+	if cfg.RoutingConfig.Enabled || cfg.CastEventsConfig.Enabled {
+		// the link pipeline only needs a kafka driver for kafka sinks and
+		// cast events
+		driver = epKafka.NewKafkaDriver(&epKafka.KafkaConfig{
+			ConsumerGroupName: cfg.KafkaConfig.ConsumerGroupName,
+			BootstrapServers:  cfg.KafkaConfig.BootstrapServers,
+		})
+		defer func(driver drivers.Driver[*kafka.Message]) {
+			if err := driver.Close(); err != nil {
+				log.Error("Error closing Kafka driver", zap.String("error", err.Error()))
+			}
+		}(driver)
+	}
+
+	router, err := newRouter(ctx, cfg, driver)
+	if err != nil {
+		log.Error("Error creating router", zap.Error(err))
+		return err
+	}
+	defer closeRouter(ctx, router)
+
+	var linkProducer producer.Producer[pulsar_anime_character_staff_link_postgres_processor.ProducerPayload]
+	if router != nil {
+		linkProducer = routing.NewProducer[pulsar_anime_character_staff_link_postgres_processor.ProducerPayload](router, routing.EventTypeLink)
+	} else {
+		linkProducer = producer.NewProducer[pulsar_anime_character_staff_link_postgres_processor.ProducerPayload](ctx, cfg.PulsarConfig)
+	}
+
+	castChanges, stopCastChanges := newCastRecorder(ctx, cfg, driver, router, database)
+	defer stopCastChanges()
+	processorOptions.CastChanges = castChanges
 
 	linkProcessor := pulsar_anime_character_staff_link_postgres_processor.NewPulsarAnimeCharacterStaffLinkPostgresProcessor(
 		processorOptions,
@@ -35,11 +82,23 @@ func EventingAnimeCharacterStaffLink() error {
 
 	messageProcessor := processor.NewProcessor[pulsar_anime_character_staff_link_postgres_processor.Payload]()
 
-	linkConsumer := consumer.NewConsumer[pulsar_anime_character_staff_link_postgres_processor.Payload](ctx, cfg.PulsarConfig)
+	linkConsumer := consumer.NewConsumer[pulsar_anime_character_staff_link_postgres_processor.Payload](ctx, cfg.PulsarConfig, PipelineLink, cfg.PulsarConfig.LinkConcurrency)
 
 	log.Info("Starting anime character-staff link eventing")
-	err := linkConsumer.Receive(ctx, func(ctx context.Context, msg pulsar.Message) error {
-		return messageProcessor.Process(ctx, string(msg.Payload()), linkProcessor.Process)
+	stopRateLimits, err := startRateLimits(ctx, cfg)
+	if err != nil {
+		log.Error("Error configuring rate limits", zap.Error(err))
+		return err
+	}
+	defer stopRateLimits()
+
+	messageLedger, stopLedger := newLedger(ctx, cfg, database)
+	defer stopLedger()
+
+	err = linkConsumer.Receive(ctx, func(ctx context.Context, msg pulsar.Message) error {
+		return applyPulsarOnce(ctx, messageLedger, PipelineLink, msg, func(ctx context.Context) error {
+			return messageProcessor.ProcessOnce(ctx, string(msg.Payload()), linkProcessor.Process)
+		})
 	})
 	if err != nil {
 		log.Error(fmt.Sprintf("Error receiving character-staff link message: %v", err))
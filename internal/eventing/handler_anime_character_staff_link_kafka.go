@@ -4,13 +4,13 @@ import (
 	"context"
 	"github.com/ThatCatDev/ep/v2/drivers"
 	epKafka "github.com/ThatCatDev/ep/v2/drivers/kafka"
-	"github.com/ThatCatDev/ep/v2/middlewares/kafka/backoffretry"
-	"github.com/ThatCatDev/ep/v2/processor"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/weeb-vip/character-staff-sync/config"
 	"github.com/weeb-vip/character-staff-sync/internal/db"
 	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_redirect"
 	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/routing"
 	"github.com/weeb-vip/character-staff-sync/internal/services/character_staff_link_processor"
 	"go.uber.org/zap"
 )
@@ -46,29 +46,74 @@ func EventingAnimeCharacterStaffLinkKafka() error {
 
 	database := db.NewDB(cfg.DBConfig)
 
+	return RunAnimeCharacterStaffLinkKafka(ctx, cfg, driver, database)
+}
+
+// newLinkKafkaProcessor builds the link processor with every collaborator of the
+// live pipeline, shared by the pipeline and reconcile. stop releases the cast
+// recorder once the processor is done.
+func newLinkKafkaProcessor(ctx context.Context, cfg config.Config, driver drivers.Driver[*kafka.Message], router routing.Router, database *db.DB) (character_staff_link_processor.CharacterStaffLinkProcessor, func(), error) {
+	log := logger.FromCtx(ctx)
+
+	linkProducer := KafkaProducer(ctx, driver, cfg.KafkaConfig.ProducerTopic)
+	if router != nil {
+		linkProducer = router.Producer(routing.EventTypeLink)
+	}
+
 	characterStaffLinkRepo := anime_character_staff_link.NewAnimeCharacterStaffLinkRepository(database)
 
+	castChanges, stopCastChanges := newCastRecorder(ctx, cfg, driver, router, database)
+
+	sourceGuard, err := newSourceGuard(ctx, cfg, database, PipelineLink)
+	if err != nil {
+		log.Error("Error creating source guard", zap.Error(err))
+		stopCastChanges()
+		return nil, nil, err
+	}
+
 	processorOptions := character_staff_link_processor.Options{
 		NoErrorOnDelete: true,
+		CastChanges:     castChanges,
+		Redirects:       entity_redirect.NewEntityRedirectRepository(database),
+		Sources:         sourceGuard,
+		History:         newHistoryRecorder(ctx, cfg, database, PipelineLink),
 	}
 
-	linkProcessor := character_staff_link_processor.NewCharacterStaffLinkProcessor(processorOptions, characterStaffLinkRepo, KafkaProducer(ctx, driver, cfg.KafkaConfig.ProducerTopic))
+	linkProcessor := character_staff_link_processor.NewCharacterStaffLinkProcessor(processorOptions, characterStaffLinkRepo, linkProducer)
+
+	stop := stopCastChanges
+	return linkProcessor, stop, nil
+}
 
-	processorInstance := processor.NewProcessor[*kafka.Message, character_staff_link_processor.Payload](driver, cfg.KafkaConfig.Topic, linkProcessor.Process)
+// RunAnimeCharacterStaffLinkKafka wires the anime character staff link processor to the given driver and database,
+// shared by the live pipeline, the replay and the in-memory harness.
+func RunAnimeCharacterStaffLinkKafka(ctx context.Context, cfg config.Config, driver drivers.Driver[*kafka.Message], database *db.DB) error {
+	log := logger.FromCtx(ctx)
+
+	router, err := newRouter(ctx, cfg, driver)
+	if err != nil {
+		log.Error("Error creating router", zap.Error(err))
+		return err
+	}
+	defer closeRouter(ctx, router)
+
+	linkProcessor, stopProcessor, err := newLinkKafkaProcessor(ctx, cfg, driver, router, database)
+	if err != nil {
+		return err
+	}
+	defer stopProcessor()
+
+	stopRateLimits, err := startRateLimits(ctx, cfg)
+	if err != nil {
+		log.Error("Error configuring rate limits", zap.Error(err))
+		return err
+	}
+	defer stopRateLimits()
 
-	log.Info("initializing backoff retry middleware", zap.String("topic", cfg.KafkaConfig.Topic))
-	backoffRetryInstance := backoffretry.NewBackoffRetry[character_staff_link_processor.Payload](driver, backoffretry.Config{
-		MaxRetries: 3,
-		HeaderKey:  "retry",
-		RetryQueue: cfg.KafkaConfig.Topic + "-retry",
-	})
+	messageLedger, stopLedger := newLedger(ctx, cfg, database)
+	defer stopLedger()
 
-	log.Info("Starting Kafka processor", zap.String("topic", cfg.KafkaConfig.Topic))
-	err := processorInstance.
-		AddMiddleware(NewLoggerMiddleware[*kafka.Message, character_staff_link_processor.Payload]().Process).
-		AddMiddleware(NewTransformMiddleware[*kafka.Message, character_staff_link_processor.Payload]().Process).
-		AddMiddleware(backoffRetryInstance.Process).
-		Run(ctx)
+	err = runKafkaPipeline[character_staff_link_processor.Payload](ctx, driver, PipelineLink, cfg.KafkaConfig.Topic, linkProcessor.Process, ledgerMiddlewares[character_staff_link_processor.Payload](messageLedger, PipelineLink)...)
 
 	if err != nil && ctx.Err() == nil { // Ignore error if caused by context cancellation
 		log.Error("Error consuming messages", zap.String("error", err.Error()))
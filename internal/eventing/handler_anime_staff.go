@@ -9,8 +9,10 @@ import (
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/weeb-vip/character-staff-sync/config"
 	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_redirect"
 	"github.com/weeb-vip/character-staff-sync/internal/logger"
 	"github.com/weeb-vip/character-staff-sync/internal/producer"
+	"github.com/weeb-vip/character-staff-sync/internal/routing"
 	"github.com/weeb-vip/character-staff-sync/internal/services/consumer"
 	"github.com/weeb-vip/character-staff-sync/internal/services/processor"
 	"github.com/weeb-vip/character-staff-sync/internal/services/pulsar_anime_staff_postgres_processor"
@@ -50,21 +52,70 @@ func EventingAnimeStaff() error {
 
 	database := db.NewDB(cfg.DBConfig)
 
+	sourceGuard, err := newSourceGuard(ctx, cfg, database, PipelineStaff)
+	if err != nil {
+		log.Error("Error creating source guard", zap.Error(err))
+		return err
+	}
+
+	imageRequests, stopImageRequests, err := newImageDedupe(ctx, cfg, database)
+	if err != nil {
+		log.Error("Error creating image dedupe cache", zap.Error(err))
+		return err
+	}
+	defer stopImageRequests()
+
 	posgresProcessorOptions := pulsar_anime_staff_postgres_processor.Options{
 		NoErrorOnDelete: true,
+		Redirects:       entity_redirect.NewEntityRedirectRepository(database),
+		Sources:         sourceGuard,
+		History:         newHistoryRecorder(ctx, cfg, database, PipelineStaff),
+		Images:          newImageValidator(cfg),
+		ImageRequests:   imageRequests,
 	}
 
-	animeProducer := producer.NewProducer[pulsar_anime_staff_postgres_processor.ProducerPayload](ctx, cfg.PulsarConfig)
+	router, err := newRouter(ctx, cfg, driver)
+	if err != nil {
+		log.Error("Error creating router", zap.Error(err))
+		return err
+	}
+	defer closeRouter(ctx, router)
+
+	castChanges, stopCastChanges := newCastRecorder(ctx, cfg, driver, router, database)
+	defer stopCastChanges()
+	posgresProcessorOptions.CastChanges = castChanges
 
-	postgresProcessor := pulsar_anime_staff_postgres_processor.NewPulsarAnimeStaffPostgresProcessor(posgresProcessorOptions, database, animeProducer, KafkaProducer(ctx, driver, cfg.KafkaConfig.ProducerTopic))
+	var animeProducer producer.Producer[pulsar_anime_staff_postgres_processor.ProducerPayload]
+	staffKafkaProducer := KafkaProducer(ctx, driver, cfg.KafkaConfig.ProducerTopic)
+	if router != nil {
+		// routed image requests take the shape of the kafka pipeline
+		posgresProcessorOptions.Routed = true
+		staffKafkaProducer = router.Producer(routing.EventTypeStaff)
+	} else {
+		animeProducer = producer.NewProducer[pulsar_anime_staff_postgres_processor.ProducerPayload](ctx, cfg.PulsarConfig)
+	}
+
+	postgresProcessor := pulsar_anime_staff_postgres_processor.NewPulsarAnimeStaffPostgresProcessor(posgresProcessorOptions, database, animeProducer, staffKafkaProducer)
 
 	messageProcessor := processor.NewProcessor[pulsar_anime_staff_postgres_processor.Payload]()
 
-	animeConsumer := consumer.NewConsumer[pulsar_anime_staff_postgres_processor.Payload](ctx, cfg.PulsarConfig)
+	animeConsumer := consumer.NewConsumer[pulsar_anime_staff_postgres_processor.Payload](ctx, cfg.PulsarConfig, PipelineStaff, cfg.PulsarConfig.StaffConcurrency)
 
 	log.Info("Starting anime eventing")
-	err := animeConsumer.Receive(ctx, func(ctx context.Context, msg pulsar.Message) error {
-		return messageProcessor.Process(ctx, string(msg.Payload()), postgresProcessor.Process)
+	stopRateLimits, err := startRateLimits(ctx, cfg)
+	if err != nil {
+		log.Error("Error configuring rate limits", zap.Error(err))
+		return err
+	}
+	defer stopRateLimits()
+
+	messageLedger, stopLedger := newLedger(ctx, cfg, database)
+	defer stopLedger()
+
+	err = animeConsumer.Receive(ctx, func(ctx context.Context, msg pulsar.Message) error {
+		return applyPulsarOnce(ctx, messageLedger, PipelineStaff, msg, func(ctx context.Context) error {
+			return messageProcessor.ProcessOnce(ctx, string(msg.Payload()), postgresProcessor.Process)
+		})
 	})
 	if err != nil {
 		log.Error(fmt.Sprintf("Error receiving message: %v", err))
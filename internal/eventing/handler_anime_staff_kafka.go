@@ -8,13 +8,13 @@ import (
 	epKafka "github.com/ThatCatDev/ep/v2/drivers/kafka"
 	"github.com/ThatCatDev/ep/v2/event"
 	"github.com/ThatCatDev/ep/v2/middleware"
-	"github.com/ThatCatDev/ep/v2/middlewares/kafka/backoffretry"
-	"github.com/ThatCatDev/ep/v2/processor"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/weeb-vip/character-staff-sync/config"
 	"github.com/weeb-vip/character-staff-sync/internal/db"
 	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_redirect"
 	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/routing"
 	"github.com/weeb-vip/character-staff-sync/internal/services/staff_processor"
 	"go.uber.org/zap"
 )
@@ -50,29 +50,86 @@ func EventingAnimeStaffKafka() error {
 
 	database := db.NewDB(cfg.DBConfig)
 
+	return RunAnimeStaffKafka(ctx, cfg, driver, database)
+}
+
+// newStaffKafkaProcessor builds the staff processor with every collaborator of the
+// live pipeline, shared by the pipeline and reconcile. stop releases the cast
+// recorder and the image dedupe cache once the processor is done.
+func newStaffKafkaProcessor(ctx context.Context, cfg config.Config, driver drivers.Driver[*kafka.Message], router routing.Router, database *db.DB) (staff_processor.StaffProcessor, func(), error) {
+	log := logger.FromCtx(ctx)
+
+	staffProducer := KafkaProducer(ctx, driver, cfg.KafkaConfig.ProducerTopic)
+	if router != nil {
+		staffProducer = router.Producer(routing.EventTypeStaff)
+	}
+
 	animeStaffRepo := anime_staff.NewAnimeStaffRepository(database)
 
+	castChanges, stopCastChanges := newCastRecorder(ctx, cfg, driver, router, database)
+
+	sourceGuard, err := newSourceGuard(ctx, cfg, database, PipelineStaff)
+	if err != nil {
+		log.Error("Error creating source guard", zap.Error(err))
+		stopCastChanges()
+		return nil, nil, err
+	}
+
+	imageRequests, stopImageRequests, err := newImageDedupe(ctx, cfg, database)
+	if err != nil {
+		log.Error("Error creating image dedupe cache", zap.Error(err))
+		stopCastChanges()
+		return nil, nil, err
+	}
+
 	posgresProcessorOptions := staff_processor.Options{
 		NoErrorOnDelete: true,
+		CastChanges:     castChanges,
+		Redirects:       entity_redirect.NewEntityRedirectRepository(database),
+		Sources:         sourceGuard,
+		History:         newHistoryRecorder(ctx, cfg, database, PipelineStaff),
+		Images:          newImageValidator(cfg),
+		ImageRequests:   imageRequests,
 	}
 
-	staffProcessor := staff_processor.NewStaffProcessor(posgresProcessorOptions, animeStaffRepo, KafkaProducer(ctx, driver, cfg.KafkaConfig.ProducerTopic))
+	staffProcessor := staff_processor.NewStaffProcessor(posgresProcessorOptions, animeStaffRepo, staffProducer)
 
-	processorInstance := processor.NewProcessor[*kafka.Message, staff_processor.Payload](driver, cfg.KafkaConfig.Topic, staffProcessor.Process)
+	stop := func() {
+		stopImageRequests()
+		stopCastChanges()
+	}
+	return staffProcessor, stop, nil
+}
+
+// RunAnimeStaffKafka wires the anime staff processor to the given driver and database,
+// shared by the live pipeline, the replay and the in-memory harness.
+func RunAnimeStaffKafka(ctx context.Context, cfg config.Config, driver drivers.Driver[*kafka.Message], database *db.DB) error {
+	log := logger.FromCtx(ctx)
+
+	router, err := newRouter(ctx, cfg, driver)
+	if err != nil {
+		log.Error("Error creating router", zap.Error(err))
+		return err
+	}
+	defer closeRouter(ctx, router)
+
+	staffProcessor, stopProcessor, err := newStaffKafkaProcessor(ctx, cfg, driver, router, database)
+	if err != nil {
+		return err
+	}
+	defer stopProcessor()
+
+	stopRateLimits, err := startRateLimits(ctx, cfg)
+	if err != nil {
+		log.Error("Error configuring rate limits", zap.Error(err))
+		return err
+	}
+	defer stopRateLimits()
 
-	log.Info("initializing backoff retry middleware", zap.String("topic", cfg.KafkaConfig.Topic))
-	backoffRetryInstance := backoffretry.NewBackoffRetry[staff_processor.Payload](driver, backoffretry.Config{
-		MaxRetries: 3,
-		HeaderKey:  "retry",
-		RetryQueue: cfg.KafkaConfig.Topic + "-retry",
-	})
+	messageLedger, stopLedger := newLedger(ctx, cfg, database)
+	defer stopLedger()
 
-	log.Info("Starting Kafka processor", zap.String("topic", cfg.KafkaConfig.Topic))
-	err := processorInstance.
-		AddMiddleware(NewLoggerMiddleware[*kafka.Message, staff_processor.Payload]().Process).
-		AddMiddleware(NewTransformMiddleware[*kafka.Message, staff_processor.Payload]().Process).
-		AddMiddleware(backoffRetryInstance.Process).
-		Run(ctx)
+	err = runKafkaPipeline[staff_processor.Payload](ctx, driver, PipelineStaff, cfg.KafkaConfig.Topic, staffProcessor.Process, ledgerMiddlewares[staff_processor.Payload](messageLedger, PipelineStaff)...)
 
 	if err != nil && ctx.Err() == nil { // Ignore error if caused by context cancellation
 		log.Error("Error consuming messages", zap.String("error", err.Error()))
@@ -0,0 +1,79 @@
+package eventing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"go.uber.org/zap"
+)
+
+// newHistoryRecorder returns the history recorder of the pipeline, none when
+// the history is disabled.
+func newHistoryRecorder(ctx context.Context, cfg config.Config, database *db.DB, pipeline Pipeline) history.Recorder {
+	if !cfg.HistoryConfig.Enabled {
+		return nil
+	}
+	logger.FromCtx(ctx).Info("Entity history enabled", zap.String("pipeline", pipeline))
+	return history.NewRecorder(entity_history.NewEntityHistoryRepository(database), pipeline)
+}
+
+// HistoryOptions selects the entity whose recorded changes are shown.
+type HistoryOptions struct {
+	EntityType string
+	EntityID   string
+	// Limit is the number of most recent changes shown.
+	Limit int
+	// JSON writes the changes as JSON lines instead of a timeline.
+	JSON bool
+}
+
+// History writes the recorded changes of an entity to w, oldest first.
+func History(opt HistoryOptions, w io.Writer) error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	switch opt.EntityType {
+	case entity_history.EntityTypeCharacter, entity_history.EntityTypeStaff, entity_history.EntityTypeLink:
+	default:
+		return fmt.Errorf("unknown entity type %q", opt.EntityType)
+	}
+
+	database := db.NewDB(cfg.DBConfig)
+	entries, err := entity_history.NewEntityHistoryRepository(database).List(ctx, opt.EntityType, opt.EntityID, opt.Limit)
+	if err != nil {
+		log.Error("Error listing history", zap.Error(err))
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no history recorded for %s %s", opt.EntityType, opt.EntityID)
+	}
+
+	versions := make([]history.Version, 0, len(entries))
+	for _, entry := range entries {
+		version, err := history.NewVersion(entry)
+		if err != nil {
+			return err
+		}
+		versions = append(versions, version)
+	}
+
+	if !opt.JSON {
+		return history.WriteTimeline(w, versions)
+	}
+	encoder := json.NewEncoder(w)
+	for _, version := range versions {
+		if err := encoder.Encode(version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package eventing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/image_dedupe"
+	"go.uber.org/zap"
+)
+
+// newImageDedupe returns the cache of sent image requests when it is
+// enabled, nil otherwise. Stop closes the backend.
+func newImageDedupe(ctx context.Context, cfg config.Config, database *db.DB) (image_dedupe.Cache, func(), error) {
+	if !cfg.DedupeConfig.Enabled {
+		return nil, func() {}, nil
+	}
+
+	ttl := time.Duration(cfg.DedupeConfig.TTLHours) * time.Hour
+	if ttl <= 0 {
+		return nil, nil, fmt.Errorf("image dedupe ttl must be positive")
+	}
+	logger.FromCtx(ctx).Info("Image request dedupe enabled",
+		zap.String("backend", cfg.DedupeConfig.Backend), zap.Duration("ttl", ttl))
+
+	switch cfg.DedupeConfig.Backend {
+	case image_dedupe.BackendMemory:
+		return image_dedupe.NewMemoryCache(cfg.DedupeConfig.MemorySize, ttl), func() {}, nil
+	case image_dedupe.BackendDatabase:
+		cache := image_dedupe.NewDatabaseCache(database, ttl)
+		ctx, cancel := context.WithCancel(ctx)
+		if interval := time.Duration(cfg.DedupeConfig.CleanupIntervalMinutes) * time.Minute; interval > 0 {
+			go cache.RunRetention(ctx, interval)
+		}
+		return cache, cancel, nil
+	case image_dedupe.BackendRedis:
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.DedupeConfig.RedisAddr,
+			Password: cfg.DedupeConfig.RedisPassword,
+			DB:       cfg.DedupeConfig.RedisDB,
+		})
+		return image_dedupe.NewRedisCache(client, ttl), func() {
+			if err := client.Close(); err != nil {
+				logger.FromCtx(ctx).Error("Error closing redis client", zap.Error(err))
+			}
+		}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown image dedupe backend %q", cfg.DedupeConfig.Backend)
+	}
+}
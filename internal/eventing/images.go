@@ -0,0 +1,18 @@
+package eventing
+
+import (
+	"strings"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/images"
+)
+
+// newImageValidator returns the validator of the image urls sent to
+// image-sync.
+func newImageValidator(cfg config.Config) *images.Validator {
+	return images.NewValidator(
+		strings.Split(cfg.ImagesConfig.Schemes, ","),
+		strings.Split(cfg.ImagesConfig.Hosts, ","),
+		strings.Split(cfg.ImagesConfig.TrackingParams, ","),
+	)
+}
@@ -0,0 +1,111 @@
+package eventing
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThatCatDev/ep/v2/event"
+	"github.com/ThatCatDev/ep/v2/middleware"
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/routing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"github.com/weeb-vip/character-staff-sync/internal/services/ledger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/processor"
+	"go.uber.org/zap"
+)
+
+// newLedger returns the processed-message ledger when it is enabled, nil
+// otherwise. The retention cleanup runs until stop is called.
+func newLedger(ctx context.Context, cfg config.Config, database *db.DB) (ledger.Ledger, func()) {
+	if !cfg.LedgerConfig.Enabled {
+		return nil, func() {}
+	}
+
+	retention := time.Duration(cfg.LedgerConfig.RetentionHours) * time.Hour
+	interval := time.Duration(cfg.LedgerConfig.CleanupIntervalMinutes) * time.Minute
+	logger.FromCtx(ctx).Info("Processed message ledger enabled", zap.Duration("retention", retention))
+
+	messageLedger := ledger.NewLedger(database)
+	ctx, cancel := context.WithCancel(ctx)
+	if retention > 0 && interval > 0 {
+		go messageLedger.RunRetention(ctx, retention, interval)
+	}
+
+	return messageLedger, cancel
+}
+
+// ledgerMiddlewares returns the ledger middleware of the pipeline, none when
+// the ledger is disabled.
+func ledgerMiddlewares[M any](messageLedger ledger.Ledger, pipeline Pipeline) []middleware.Middleware[*kafka.Message, M] {
+	if messageLedger == nil {
+		return nil
+	}
+	return []middleware.Middleware[*kafka.Message, M]{NewLedgerMiddleware[M](messageLedger, pipeline).Process}
+}
+
+// applyPulsarOnce processes a pulsar message through the ledger when it is
+// enabled. Failed attempts are retried outside the ledger, every attempt
+// runs in a transaction of its own: after a failed statement Postgres
+// rejects the rest of the transaction, and its row locks would be held
+// through the backoff. The history records the id of the message with its
+// changes, the router tracks the deliveries of its retries under that id.
+func applyPulsarOnce(ctx context.Context, messageLedger ledger.Ledger, pipeline Pipeline, msg pulsar.Message, process func(ctx context.Context) error) error {
+	entry := ledger.PulsarEntry(pipeline, msg)
+	ctx = history.WithMessageID(ctx, entry.MessageKey)
+	ctx = routing.WithMessageID(ctx, entry.MessageKey)
+
+	return processor.Retry(func() error {
+		if messageLedger == nil {
+			return process(ctx)
+		}
+
+		applied, err := messageLedger.Apply(ctx, entry, process)
+		if err == nil && !applied {
+			logger.FromCtx(ctx).Info("Skipping already processed message", zap.String("msgId", msg.ID().String()))
+		}
+		return err
+	})
+}
+
+// LedgerMiddleware skips kafka messages that were already processed and
+// records the others in the transaction of their writes.
+type LedgerMiddleware[M any] struct {
+	Ledger   ledger.Ledger
+	Pipeline Pipeline
+}
+
+func NewLedgerMiddleware[M any](messageLedger ledger.Ledger, pipeline Pipeline) *LedgerMiddleware[M] {
+	return &LedgerMiddleware[M]{
+		Ledger:   messageLedger,
+		Pipeline: pipeline,
+	}
+}
+
+func (f *LedgerMiddleware[M]) Process(ctx context.Context, data event.Event[*kafka.Message, M], next middleware.Handler[*kafka.Message, M]) (*event.Event[*kafka.Message, M], error) {
+	if data.DriverMessage == nil {
+		return next(ctx, data)
+	}
+
+	entry := ledger.KafkaEntry(f.Pipeline, data.DriverMessage)
+
+	result := &data
+	applied, err := f.Ledger.Apply(ctx, entry, func(ctx context.Context) error {
+		var err error
+		result, err = next(ctx, data)
+		return err
+	})
+	if err != nil {
+		return result, err
+	}
+	if !applied {
+		logger.FromCtx(ctx).Info("Skipping already processed message",
+			zap.String("messageKey", entry.MessageKey),
+			zap.String("sourcePosition", entry.SourcePosition))
+	}
+
+	return result, nil
+}
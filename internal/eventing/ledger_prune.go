@@ -0,0 +1,30 @@
+package eventing
+
+import (
+	"context"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/ledger"
+	"go.uber.org/zap"
+)
+
+// PruneLedger removes processed-message entries older than olderThan.
+func PruneLedger(olderThan time.Duration) error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	database := db.NewDB(cfg.DBConfig)
+
+	pruned, err := ledger.NewLedger(database).Prune(ctx, time.Now().Add(-olderThan))
+	if err != nil {
+		return err
+	}
+
+	log.Info("Pruned processed message ledger", zap.Int64("rows", pruned))
+	return nil
+}
@@ -0,0 +1,90 @@
+package eventing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/attributes"
+	"go.uber.org/zap"
+)
+
+// NormalizeAttributes re-parses the typed attribute columns of rows already
+// in the database, used after the parsers change.
+func NormalizeAttributes(entities []string, batchSize int) error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	database := db.NewDB(cfg.DBConfig)
+
+	for _, entity := range entities {
+		var count int
+		var err error
+		switch entity {
+		case attributes.EntityCharacter:
+			count, err = normalizeCharacters(ctx, anime_character.NewAnimeCharacterRepository(database), batchSize)
+		case attributes.EntityStaff:
+			count, err = normalizeStaff(ctx, anime_staff.NewAnimeStaffRepository(database), batchSize)
+		default:
+			err = fmt.Errorf("unknown entity %q", entity)
+		}
+		if err != nil {
+			return err
+		}
+		log.Info("Normalized attributes", zap.String("entity", entity), zap.Int("rows", count))
+	}
+
+	return nil
+}
+
+func normalizeCharacters(ctx context.Context, repo anime_character.AnimeCharacterRepository, batchSize int) (int, error) {
+	count := 0
+	afterID := ""
+	for {
+		characters, err := repo.ListAfterID(ctx, afterID, batchSize, anime_character.ListFilter{})
+		if err != nil {
+			return count, err
+		}
+		if len(characters) == 0 {
+			return count, nil
+		}
+
+		for i := range characters {
+			attributes.NormalizeCharacter(ctx, &characters[i])
+			if err := repo.Upsert(ctx, &characters[i]); err != nil {
+				return count, err
+			}
+			count++
+		}
+		afterID = characters[len(characters)-1].ID
+	}
+}
+
+func normalizeStaff(ctx context.Context, repo anime_staff.AnimeStaffRepository, batchSize int) (int, error) {
+	count := 0
+	afterID := ""
+	for {
+		staff, err := repo.ListAfterID(ctx, afterID, batchSize, anime_staff.ListFilter{})
+		if err != nil {
+			return count, err
+		}
+		if len(staff) == 0 {
+			return count, nil
+		}
+
+		for i := range staff {
+			attributes.NormalizeStaff(ctx, &staff[i])
+			if err := repo.Upsert(ctx, &staff[i]); err != nil {
+				return count, err
+			}
+			count++
+		}
+		afterID = staff[len(staff)-1].ID
+	}
+}
@@ -0,0 +1,89 @@
+package eventing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/attributes"
+	"go.uber.org/zap"
+)
+
+// NormalizeNames rewrites the name keys of rows already in the database,
+// used for rows synced before the keys existed or after names.Key changes.
+// The repositories derive the key on every upsert.
+func NormalizeNames(entities []string, batchSize int) error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	database := db.NewDB(cfg.DBConfig)
+
+	for _, entity := range entities {
+		var count int
+		var err error
+		switch entity {
+		case attributes.EntityCharacter:
+			count, err = normalizeCharacterNames(ctx, anime_character.NewAnimeCharacterRepository(database), batchSize)
+		case attributes.EntityStaff:
+			count, err = normalizeStaffNames(ctx, anime_staff.NewAnimeStaffRepository(database), batchSize)
+		default:
+			err = fmt.Errorf("unknown entity %q", entity)
+		}
+		if err != nil {
+			return err
+		}
+		log.Info("Normalized names", zap.String("entity", entity), zap.Int("rows", count))
+	}
+
+	return nil
+}
+
+func normalizeCharacterNames(ctx context.Context, repo anime_character.AnimeCharacterRepository, batchSize int) (int, error) {
+	count := 0
+	afterID := ""
+	for {
+		characters, err := repo.ListAfterID(ctx, afterID, batchSize, anime_character.ListFilter{})
+		if err != nil {
+			return count, err
+		}
+		if len(characters) == 0 {
+			return count, nil
+		}
+
+		for i := range characters {
+			if err := repo.Upsert(ctx, &characters[i]); err != nil {
+				return count, err
+			}
+			count++
+		}
+		afterID = characters[len(characters)-1].ID
+	}
+}
+
+func normalizeStaffNames(ctx context.Context, repo anime_staff.AnimeStaffRepository, batchSize int) (int, error) {
+	count := 0
+	afterID := ""
+	for {
+		staff, err := repo.ListAfterID(ctx, afterID, batchSize, anime_staff.ListFilter{})
+		if err != nil {
+			return count, err
+		}
+		if len(staff) == 0 {
+			return count, nil
+		}
+
+		for i := range staff {
+			if err := repo.Upsert(ctx, &staff[i]); err != nil {
+				return count, err
+			}
+			count++
+		}
+		afterID = staff[len(staff)-1].ID
+	}
+}
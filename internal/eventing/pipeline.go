@@ -0,0 +1,52 @@
+package eventing
+
+import (
+	"context"
+
+	"github.com/ThatCatDev/ep/v2/drivers"
+	"github.com/ThatCatDev/ep/v2/middleware"
+	"github.com/ThatCatDev/ep/v2/middlewares/kafka/backoffretry"
+	"github.com/ThatCatDev/ep/v2/processor"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+// runKafkaPipeline consumes the topic through the standard middleware chain,
+// shared by the live kafka pipelines and the offline replay. Messages are
+// throttled by the consumption limit of the pipeline before anything else
+// runs, the router then learns the id of the message so its retries are
+// tracked as the same delivery. Extra middlewares run inside the retry
+// middleware, right before the processor.
+func runKafkaPipeline[M any](ctx context.Context, driver drivers.Driver[*kafka.Message], pipeline Pipeline, topic string, process processor.Process[*kafka.Message, M], extra ...middleware.Middleware[*kafka.Message, M]) error {
+	log := logger.FromCtx(ctx)
+
+	processorInstance := processor.NewProcessor[*kafka.Message, M](driver, topic, process)
+
+	log.Info("initializing backoff retry middleware", zap.String("topic", topic))
+	backoffRetryInstance := backoffretry.NewBackoffRetry[M](driver, backoffretry.Config{
+		MaxRetries: 3,
+		HeaderKey:  "retry",
+		RetryQueue: retryTopic(topic),
+	})
+
+	processorInstance.
+		AddMiddleware(NewRateLimitMiddleware[M](ratelimit.Default, pipeline).Process).
+		AddMiddleware(NewMessageIDMiddleware[M](pipeline).Process).
+		AddMiddleware(NewLoggerMiddleware[*kafka.Message, M]().Process).
+		AddMiddleware(NewTransformMiddleware[*kafka.Message, M]().Process).
+		AddMiddleware(backoffRetryInstance.Process)
+	for _, m := range extra {
+		processorInstance.AddMiddleware(m)
+	}
+
+	log.Info("Starting Kafka processor", zap.String("topic", topic))
+	return processorInstance.Run(ctx)
+}
+
+// retryTopic is the topic the backoff retry middleware of a pipeline sends
+// failed messages to.
+func retryTopic(topic string) string {
+	return topic + "-retry"
+}
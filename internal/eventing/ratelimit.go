@@ -0,0 +1,139 @@
+package eventing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Flagsmith/flagsmith-go-client/v2"
+	"github.com/ThatCatDev/ep/v2/event"
+	"github.com/ThatCatDev/ep/v2/middleware"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/admin"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+// startRateLimits configures the rate limits of the process and starts the
+// admin api and the feature flag overrides when they are enabled, until
+// stop is called.
+func startRateLimits(ctx context.Context, cfg config.Config) (func(), error) {
+	log := logger.FromCtx(ctx)
+
+	if cfg.AdminConfig.Port > 0 && cfg.AdminConfig.Token == "" {
+		return nil, fmt.Errorf("ADMIN_TOKEN must be set to serve the admin api")
+	}
+
+	consume, err := ratelimit.ParseLimits(cfg.RateLimitConfig.Consume)
+	if err != nil {
+		return nil, fmt.Errorf("invalid consume rate limits: %w", err)
+	}
+	produce, err := ratelimit.ParseLimits(cfg.RateLimitConfig.Produce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid produce rate limits: %w", err)
+	}
+	ratelimit.Default.Configure(ratelimit.Limits{
+		ratelimit.DirectionConsume: consume,
+		ratelimit.DirectionProduce: produce,
+	})
+	for direction, limits := range ratelimit.Default.Limits() {
+		for name, limit := range limits {
+			log.Info("Rate limit configured", zap.String("direction", direction), zap.String("name", name),
+				zap.Float64("rate", limit.Rate), zap.Int("burst", limit.Burst))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	if cfg.AdminConfig.Port > 0 {
+		go func() {
+			if err := admin.Serve(ctx, cfg.AdminConfig.Port, cfg.AdminConfig.Token, ratelimit.Default); err != nil {
+				log.Error("Error serving admin api", zap.Error(err))
+			}
+		}()
+	}
+	if cfg.RateLimitConfig.Flag != "" && cfg.RateLimitConfig.FlagPollSeconds > 0 {
+		client := flagsmith.NewClient(cfg.FFConfig.APIKey,
+			flagsmith.WithBaseURL(cfg.FFConfig.BaseURL),
+			flagsmith.WithContext(ctx))
+		go pollRateLimitFlag(ctx, client, cfg.RateLimitConfig.Flag, time.Duration(cfg.RateLimitConfig.FlagPollSeconds)*time.Second)
+	}
+
+	return cancel, nil
+}
+
+// pollRateLimitFlag applies the JSON value of the flag as rate limit
+// overrides every interval until ctx is done, in the form of
+// {"consume": {"character": {"rate": 20, "burst": 20}}}. An empty value
+// restores the configured limits.
+func pollRateLimitFlag(ctx context.Context, client *flagsmith.Client, flag string, interval time.Duration) {
+	log := logger.FromCtx(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	applied := ""
+	for {
+		value, err := rateLimitFlagValue(client, flag)
+		if err != nil {
+			log.Warn("Error reading rate limit flag", zap.String("flag", flag), zap.Error(err))
+		} else if value != applied {
+			var overrides ratelimit.Limits
+			if value != "" {
+				err = json.Unmarshal([]byte(value), &overrides)
+			}
+			if err != nil {
+				log.Error("Invalid rate limit flag", zap.String("flag", flag), zap.String("value", value), zap.Error(err))
+			} else {
+				ratelimit.Default.Override(overrides)
+				log.Info("Rate limit overrides applied", zap.String("flag", flag), zap.String("value", value))
+			}
+			applied = value
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func rateLimitFlagValue(client *flagsmith.Client, flag string) (string, error) {
+	flags, err := client.GetEnvironmentFlags()
+	if err != nil {
+		return "", err
+	}
+	enabled, err := flags.IsFeatureEnabled(flag)
+	if err != nil || !enabled {
+		return "", err
+	}
+	value, err := flags.GetFeatureValue(flag)
+	if err != nil || value == nil {
+		return "", err
+	}
+	return fmt.Sprint(value), nil
+}
+
+// RateLimitMiddleware waits for a token of the consumption limit of the
+// pipeline before the message is processed.
+type RateLimitMiddleware[M any] struct {
+	Limits   *ratelimit.Registry
+	Pipeline Pipeline
+}
+
+func NewRateLimitMiddleware[M any](limits *ratelimit.Registry, pipeline Pipeline) *RateLimitMiddleware[M] {
+	return &RateLimitMiddleware[M]{
+		Limits:   limits,
+		Pipeline: pipeline,
+	}
+}
+
+func (f *RateLimitMiddleware[M]) Process(ctx context.Context, data event.Event[*kafka.Message, M], next middleware.Handler[*kafka.Message, M]) (*event.Event[*kafka.Message, M], error) {
+	if err := f.Limits.Wait(ctx, ratelimit.DirectionConsume, f.Pipeline); err != nil {
+		return nil, err
+	}
+	return next(ctx, data)
+}
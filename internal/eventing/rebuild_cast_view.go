@@ -0,0 +1,30 @@
+package eventing
+
+import (
+	"context"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_cast"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+// RebuildCastView regenerates anime_cast from the character, staff and link
+// tables in one transaction.
+func RebuildCastView() error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	database := db.NewDB(cfg.DBConfig)
+
+	rows, err := anime_cast.NewAnimeCastRepository(database).Rebuild(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Rebuilt cast view", zap.Int64("rows", rows))
+	return nil
+}
@@ -0,0 +1,84 @@
+package eventing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"github.com/weeb-vip/character-staff-sync/internal/services/rebuild"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// pipelineRebuild is recorded as the pipeline of the changes a live rebuild
+// applies.
+const pipelineRebuild = "rebuild"
+
+type RebuildOptions struct {
+	rebuild.Options
+	// Live writes the live tables, only with Confirm, otherwise the rows are
+	// written to tables named with Prefix.
+	Live    bool
+	Confirm bool
+	Prefix  string
+}
+
+// RebuildAt restores the selected entities to their state at opt.At from the
+// recorded history and writes the steps to w. A live rebuild without
+// Confirm only writes the steps.
+func RebuildAt(opt RebuildOptions, w io.Writer) error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	database := db.NewDB(cfg.DBConfig)
+
+	var target rebuild.Target
+	if opt.Live {
+		var recorder history.Recorder
+		if cfg.HistoryConfig.Enabled {
+			recorder = history.NewRecorder(entity_history.NewEntityHistoryRepository(database), pipelineRebuild)
+		}
+		target = rebuild.NewLiveTarget(database, recorder)
+	} else {
+		var err error
+		target, err = rebuild.NewFreshTarget(database, opt.Prefix)
+		if err != nil {
+			return err
+		}
+	}
+
+	rebuilder := rebuild.NewRebuilder(entity_history.NewEntityHistoryRepository(database), target)
+	steps, err := rebuilder.Plan(ctx, opt.Options)
+	if err != nil {
+		log.Error("Error planning rebuild", zap.Error(err))
+		return err
+	}
+	for _, step := range steps {
+		if _, err := fmt.Fprintf(w, "%s %s %s\n", step.Action, step.EntityType, step.EntityID); err != nil {
+			return err
+		}
+	}
+
+	if opt.Live && !opt.Confirm {
+		log.Info("Dry run, pass --confirm to write the live tables", zap.Int("entities", len(steps)))
+		return nil
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return rebuilder.Apply(db.WithTx(ctx, tx), steps)
+	})
+	if err != nil {
+		log.Error("Error applying rebuild", zap.Error(err))
+		return err
+	}
+
+	log.Info("Rebuilt entities", zap.Time("at", opt.At), zap.Bool("live", opt.Live), zap.Int("entities", len(steps)))
+	return nil
+}
@@ -0,0 +1,164 @@
+package eventing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ThatCatDev/ep/v2/drivers"
+	epKafka "github.com/ThatCatDev/ep/v2/drivers/kafka"
+	"github.com/ThatCatDev/ep/v2/event"
+	"github.com/ThatCatDev/ep/v2/processor"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/character_processor"
+	"github.com/weeb-vip/character-staff-sync/internal/services/character_staff_link_processor"
+	"github.com/weeb-vip/character-staff-sync/internal/services/reconcile"
+	"github.com/weeb-vip/character-staff-sync/internal/services/staff_processor"
+	"go.uber.org/zap"
+)
+
+type ReconcileOptions struct {
+	Table        reconcile.Table
+	Source       string
+	SourceFormat string
+	RangeSize    int
+	// Report is the file the JSON report is written to, empty only logs it.
+	Report string
+	// Corrections is the file the corrective payloads are written to.
+	Corrections string
+	// Apply runs the corrective payloads through the processor of the live
+	// pipeline, with the same redirects, sources, history and cast changes.
+	Apply bool
+}
+
+// Reconcile compares a source export with the target table and optionally
+// corrects the drift through the processors of the live pipeline.
+func Reconcile(opt ReconcileOptions) error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	source, err := reconcile.LoadSource(opt.Source, opt.SourceFormat, opt.Table)
+	if err != nil {
+		return fmt.Errorf("failed to load source: %w", err)
+	}
+
+	database := db.NewDB(cfg.DBConfig)
+	characterRepo := anime_character.NewAnimeCharacterRepository(database)
+	staffRepo := anime_staff.NewAnimeStaffRepository(database)
+	linkRepo := anime_character_staff_link.NewAnimeCharacterStaffLinkRepository(database)
+
+	var target reconcile.Target
+	switch opt.Table {
+	case reconcile.TableCharacter:
+		target = reconcile.NewCharacterTarget(characterRepo)
+	case reconcile.TableStaff:
+		target = reconcile.NewStaffTarget(staffRepo)
+	case reconcile.TableLink:
+		target = reconcile.NewLinkTarget(linkRepo)
+	default:
+		return fmt.Errorf("unknown table %q", opt.Table)
+	}
+
+	reconciler := reconcile.NewReconciler(reconcile.Options{RangeSize: opt.RangeSize}, opt.Table, target)
+	report, err := reconciler.Reconcile(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	for _, diff := range report.Diffs {
+		log.Info("Row drift", zap.String("table", opt.Table), zap.String("id", diff.ID), zap.String("kind", diff.Kind), zap.Any("fields", diff.Fields))
+	}
+
+	if opt.Report != "" {
+		if err := reconcile.WriteReport(opt.Report, report); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+
+	corrections := reconcile.BuildCorrections(report.Diffs)
+	if opt.Corrections != "" {
+		if err := reconcile.WriteCorrections(opt.Corrections, corrections); err != nil {
+			return fmt.Errorf("failed to write corrections: %w", err)
+		}
+	}
+
+	if !opt.Apply || len(corrections) == 0 {
+		return nil
+	}
+
+	driver := epKafka.NewKafkaDriver(&epKafka.KafkaConfig{
+		ConsumerGroupName: cfg.KafkaConfig.ConsumerGroupName,
+		BootstrapServers:  cfg.KafkaConfig.BootstrapServers,
+	})
+	defer func(driver drivers.Driver[*kafka.Message]) {
+		err := driver.Close()
+		if err != nil {
+			log.Error("Error closing Kafka driver", zap.String("error", err.Error()))
+		}
+	}(driver)
+
+	router, err := newRouter(ctx, cfg, driver)
+	if err != nil {
+		log.Error("Error creating router", zap.Error(err))
+		return err
+	}
+	defer closeRouter(ctx, router)
+
+	log.Info("Applying corrections", zap.String("table", opt.Table), zap.Int("corrections", len(corrections)))
+	switch opt.Table {
+	case reconcile.TableCharacter:
+		characterProcessor, stopProcessor, err := newCharacterKafkaProcessor(ctx, cfg, driver, router, database)
+		if err != nil {
+			return err
+		}
+		defer stopProcessor()
+		return applyCorrections[character_processor.Payload](ctx, corrections, characterProcessor.Process)
+	case reconcile.TableStaff:
+		staffProcessor, stopProcessor, err := newStaffKafkaProcessor(ctx, cfg, driver, router, database)
+		if err != nil {
+			return err
+		}
+		defer stopProcessor()
+		return applyCorrections[staff_processor.Payload](ctx, corrections, staffProcessor.Process)
+	default:
+		linkProcessor, stopProcessor, err := newLinkKafkaProcessor(ctx, cfg, driver, router, database)
+		if err != nil {
+			return err
+		}
+		defer stopProcessor()
+		return applyCorrections[character_staff_link_processor.Payload](ctx, corrections, linkProcessor.Process)
+	}
+}
+
+func applyCorrections[M any](ctx context.Context, corrections []reconcile.Correction, process processor.Process[*kafka.Message, M]) error {
+	log := logger.FromCtx(ctx)
+
+	for _, correction := range corrections {
+		data, err := json.Marshal(correction)
+		if err != nil {
+			return err
+		}
+
+		var payload M
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return err
+		}
+
+		if _, err := process(ctx, event.Event[*kafka.Message, M]{
+			Headers: map[string]string{},
+			Payload: payload,
+		}); err != nil {
+			log.Error("Error applying correction", zap.String("value", string(data)), zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
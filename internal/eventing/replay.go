@@ -0,0 +1,97 @@
+package eventing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/routing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/replay"
+	"go.uber.org/zap"
+)
+
+type Pipeline = string
+
+const (
+	PipelineCharacter Pipeline = "character"
+	PipelineStaff     Pipeline = "staff"
+	PipelineLink      Pipeline = "link"
+)
+
+type ReplayOptions struct {
+	Pipeline Pipeline
+	Input    string
+	Format   replay.Format
+	// Output is the file outbound events are written to, empty discards them.
+	Output   string
+	DBConfig config.DBConfig
+}
+
+// Replay runs captured Debezium messages through the same wiring as the live
+// kafka pipeline, without a broker. Only the source is swapped: routing is
+// off, outbound events are kept by the driver and written to the output.
+func Replay(opt ReplayOptions) error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	files, err := replay.ListFiles(opt.Input)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no input files found in %s", opt.Input)
+	}
+
+	cfg.DBConfig = opt.DBConfig
+	cfg.RoutingConfig.Enabled = false
+
+	topic := cfg.KafkaConfig.Topic
+	driver := replay.NewFileDriver(files, opt.Format, retryTopic(topic))
+	database := db.NewDB(cfg.DBConfig)
+
+	log.Info("Replaying captured messages", zap.String("pipeline", opt.Pipeline), zap.Int("files", len(files)))
+
+	switch opt.Pipeline {
+	case PipelineCharacter:
+		err = RunAnimeCharacterKafka(ctx, cfg, driver, database)
+	case PipelineStaff:
+		err = RunAnimeStaffKafka(ctx, cfg, driver, database)
+	case PipelineLink:
+		err = RunAnimeCharacterStaffLinkKafka(ctx, cfg, driver, database)
+	default:
+		return fmt.Errorf("unknown pipeline %q", opt.Pipeline)
+	}
+	if err != nil {
+		log.Error("Error replaying messages", zap.Error(err))
+		return err
+	}
+
+	outbound := driver.Produced(cfg.KafkaConfig.ProducerTopic)
+	if opt.Output != "" {
+		sink := routing.NewFileSink(opt.Output)
+		for _, message := range outbound {
+			if err := sink.Send(ctx, message); err != nil {
+				return fmt.Errorf("failed to write outbound events: %w", err)
+			}
+		}
+	}
+
+	stats := driver.Stats()
+	log.Info("Replay finished",
+		zap.Int("files", stats.Files),
+		zap.Int("messages", stats.Messages),
+		zap.Int("failed", stats.Failed),
+		zap.Int("retried", stats.Retried),
+		zap.Int("outbound", len(outbound)),
+		zap.String("output", opt.Output))
+
+	for _, message := range driver.Produced(retryTopic(topic)) {
+		log.Warn("Message was sent to the retry topic", zap.String("value", string(message.Value)))
+	}
+
+	return nil
+}
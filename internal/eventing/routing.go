@@ -0,0 +1,76 @@
+package eventing
+
+import (
+	"context"
+
+	"github.com/ThatCatDev/ep/v2/drivers"
+	"github.com/ThatCatDev/ep/v2/event"
+	"github.com/ThatCatDev/ep/v2/middleware"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/routing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/ledger"
+	"go.uber.org/zap"
+)
+
+// newRouter returns the outbound router when routing is enabled, nil otherwise.
+func newRouter(ctx context.Context, cfg config.Config, driver drivers.Driver[*kafka.Message]) (routing.Router, error) {
+	if !cfg.RoutingConfig.Enabled {
+		return nil, nil
+	}
+
+	log := logger.FromCtx(ctx)
+	router, err := routing.NewRouter(cfg, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, eventType := range []routing.EventType{routing.EventTypeCharacter, routing.EventTypeStaff, routing.EventTypeLink, routing.EventTypeCast} {
+		for _, sink := range router.Sinks(eventType) {
+			log.Info("Routing outbound events", zap.String("eventType", eventType), zap.String("sink", sink.Name()))
+		}
+	}
+
+	return router, nil
+}
+
+func closeRouter(ctx context.Context, router routing.Router) {
+	if router == nil {
+		return
+	}
+	if err := router.Close(); err != nil {
+		logger.FromCtx(ctx).Error("Error closing router", zap.Error(err))
+	}
+}
+
+// messageIDHeader carries the id of the consumed message into its retries,
+// the backoff retry middleware copies the headers of the message it retries.
+const messageIDHeader = "message-id"
+
+// MessageIDMiddleware gives the router the id of the consumed kafka message,
+// a retried message keeps the id of the message it retries.
+type MessageIDMiddleware[M any] struct {
+	Pipeline Pipeline
+}
+
+func NewMessageIDMiddleware[M any](pipeline Pipeline) *MessageIDMiddleware[M] {
+	return &MessageIDMiddleware[M]{
+		Pipeline: pipeline,
+	}
+}
+
+func (f *MessageIDMiddleware[M]) Process(ctx context.Context, data event.Event[*kafka.Message, M], next middleware.Handler[*kafka.Message, M]) (*event.Event[*kafka.Message, M], error) {
+	if data.DriverMessage == nil {
+		return next(ctx, data)
+	}
+
+	id := data.Headers[messageIDHeader]
+	if id == "" {
+		id = ledger.KafkaEntry(f.Pipeline, data.DriverMessage).MessageKey
+		if id != "" && data.Headers != nil {
+			data.Headers[messageIDHeader] = id
+		}
+	}
+	return next(routing.WithMessageID(ctx, id), data)
+}
@@ -0,0 +1,111 @@
+package eventing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/search_document"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ReindexSearch rewrites the search documents of rows already in the
+// database, used for rows synced before the search index existed.
+func ReindexSearch(entityTypes []string, batchSize int) error {
+	cfg := config.LoadConfigOrPanic()
+	ctx := context.Background()
+	log := logger.Get()
+	ctx = logger.WithCtx(ctx, log)
+
+	database := db.NewDB(cfg.DBConfig)
+	searchRepo := search_document.NewSearchDocumentRepository(database)
+	aliasRepo := entity_alias.NewEntityAliasRepository(database)
+
+	for _, entityType := range entityTypes {
+		var count int
+		var err error
+		switch entityType {
+		case search_document.EntityTypeCharacter:
+			count, err = reindexCharacters(ctx, anime_character.NewAnimeCharacterRepository(database), aliasRepo, searchRepo, batchSize)
+		case search_document.EntityTypeStaff:
+			count, err = reindexStaff(ctx, anime_staff.NewAnimeStaffRepository(database), aliasRepo, searchRepo, batchSize)
+		default:
+			err = fmt.Errorf("unknown entity type %q", entityType)
+		}
+		if err != nil {
+			return err
+		}
+		log.Info("Reindexed search documents", zap.String("type", entityType), zap.Int("rows", count))
+	}
+
+	return nil
+}
+
+func reindexCharacters(ctx context.Context, repo anime_character.AnimeCharacterRepository, aliasRepo entity_alias.EntityAliasRepository, searchRepo search_document.SearchDocumentRepository, batchSize int) (int, error) {
+	count := 0
+	afterID := ""
+	for {
+		characters, err := repo.ListAfterID(ctx, afterID, batchSize, anime_character.ListFilter{})
+		if err != nil {
+			return count, err
+		}
+		if len(characters) == 0 {
+			return count, nil
+		}
+
+		ids := make([]string, len(characters))
+		for i := range characters {
+			ids[i] = characters[i].ID
+		}
+		aliases, err := aliasRepo.ListByEntities(ctx, entity_alias.EntityTypeCharacter, ids)
+		if err != nil {
+			return count, err
+		}
+
+		for i := range characters {
+			characters[i].Aliases = aliases[characters[i].ID]
+			if err := searchRepo.Upsert(ctx, characters[i].SearchDocument()); err != nil {
+				return count, err
+			}
+			count++
+		}
+		afterID = characters[len(characters)-1].ID
+	}
+}
+
+func reindexStaff(ctx context.Context, repo anime_staff.AnimeStaffRepository, aliasRepo entity_alias.EntityAliasRepository, searchRepo search_document.SearchDocumentRepository, batchSize int) (int, error) {
+	count := 0
+	afterID := ""
+	for {
+		staff, err := repo.ListAfterID(ctx, afterID, batchSize, anime_staff.ListFilter{})
+		if err != nil {
+			return count, err
+		}
+		if len(staff) == 0 {
+			return count, nil
+		}
+
+		ids := make([]string, len(staff))
+		for i := range staff {
+			ids[i] = staff[i].ID
+		}
+		aliases, err := aliasRepo.ListByEntities(ctx, entity_alias.EntityTypeStaff, ids)
+		if err != nil {
+			return count, err
+		}
+
+		for i := range staff {
+			staff[i].Aliases = aliases[staff[i].ID]
+			if err := searchRepo.Upsert(ctx, staff[i].SearchDocument()); err != nil {
+				return count, err
+			}
+			count++
+		}
+		afterID = staff[len(staff)-1].ID
+	}
+}
@@ -0,0 +1,45 @@
+package eventing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/field_source"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/sources"
+	"go.uber.org/zap"
+)
+
+// newSourceGuard returns the guard of the source the pipeline ingests from,
+// none when source priorities are disabled.
+func newSourceGuard(ctx context.Context, cfg config.Config, database *db.DB, pipeline Pipeline) (sources.Guard, error) {
+	if !cfg.SourcesConfig.Enabled {
+		return nil, nil
+	}
+
+	registry, err := sources.NewRegistry(cfg.SourcesConfig.Priorities)
+	if err != nil {
+		return nil, err
+	}
+
+	var source string
+	switch pipeline {
+	case PipelineCharacter:
+		source = cfg.SourcesConfig.CharacterSource
+	case PipelineStaff:
+		source = cfg.SourcesConfig.StaffSource
+	case PipelineLink:
+		source = cfg.SourcesConfig.LinkSource
+	default:
+		return nil, fmt.Errorf("unknown pipeline %q", pipeline)
+	}
+
+	guard := sources.NewGuard(registry, field_source.NewFieldSourceRepository(database), source)
+	logger.FromCtx(ctx).Info("Source priorities enabled",
+		zap.String("pipeline", pipeline),
+		zap.String("source", guard.Source().Name),
+		zap.Int("priority", guard.Source().Priority))
+	return guard, nil
+}
@@ -0,0 +1,255 @@
+package harness
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	migrations "github.com/weeb-vip/character-staff-sync/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/drivers/memory"
+	"github.com/weeb-vip/character-staff-sync/internal/eventing"
+	"github.com/weeb-vip/character-staff-sync/internal/services/replay"
+)
+
+const (
+	messagesFile = "messages.jsonl"
+	expectFile   = "expect.json"
+)
+
+// LoadCases returns every directory below root holding a messages.jsonl and
+// an expect.json, sorted by name.
+func LoadCases(root string) ([]Case, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []Case
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+
+		data, err := os.ReadFile(filepath.Join(dir, expectFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var expect Expectation
+		if err := json.Unmarshal(data, &expect); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		cases = append(cases, Case{Name: entry.Name(), Dir: dir, Expect: expect})
+	}
+
+	sort.Slice(cases, func(i, j int) bool {
+		return cases[i].Name < cases[j].Name
+	})
+	return cases, nil
+}
+
+// Run feeds the case messages through the pipeline wiring with an in-memory
+// driver and a scratch sqlite database, then checks the expectations.
+func Run(ctx context.Context, cfg config.Config, c Case) Result {
+	result := Result{Case: c.Name}
+
+	scratch, err := os.MkdirTemp("", "harness-"+c.Name)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer os.RemoveAll(scratch)
+
+	cfg.RoutingConfig.Enabled = false
+	cfg.LedgerConfig.Enabled = c.Expect.Ledger
+	cfg.CastEventsConfig.Enabled = c.Expect.CastEvents
+	cfg.SourcesConfig.Enabled = c.Expect.Source != ""
+	cfg.SourcesConfig.Priorities = c.Expect.SourcePriorities
+	cfg.SourcesConfig.CharacterSource = c.Expect.Source
+	cfg.SourcesConfig.StaffSource = c.Expect.Source
+	cfg.SourcesConfig.LinkSource = c.Expect.Source
+	cfg.HistoryConfig.Enabled = c.Expect.History
+	cfg.DedupeConfig.Enabled = c.Expect.ImageDedupe != ""
+	cfg.DedupeConfig.Backend = c.Expect.ImageDedupe
+	cfg.DBConfig.Dialect = db.DialectSQLite
+	cfg.DBConfig.SQLitePath = filepath.Join(scratch, "harness.db")
+
+	database := db.NewDB(cfg.DBConfig)
+	if err := migrations.MigrateUpDB(database); err != nil {
+		result.Err = fmt.Errorf("failed to migrate: %w", err)
+		return result
+	}
+
+	for table, rows := range c.Expect.Seed {
+		for _, row := range rows {
+			if err := database.DB.Table(table).Create(row).Error; err != nil {
+				result.Err = fmt.Errorf("failed to seed %s: %w", table, err)
+				return result
+			}
+		}
+	}
+
+	driver := memory.NewDriver()
+	driver.StopWhenDrained = true
+	if err := feed(ctx, driver, cfg.KafkaConfig.Topic, filepath.Join(c.Dir, messagesFile)); err != nil {
+		result.Err = err
+		return result
+	}
+
+	switch c.Expect.Pipeline {
+	case eventing.PipelineCharacter:
+		err = eventing.RunAnimeCharacterKafka(ctx, cfg, driver, database)
+	case eventing.PipelineStaff:
+		err = eventing.RunAnimeStaffKafka(ctx, cfg, driver, database)
+	case eventing.PipelineLink:
+		err = eventing.RunAnimeCharacterStaffLinkKafka(ctx, cfg, driver, database)
+	default:
+		err = fmt.Errorf("unknown pipeline %q", c.Expect.Pipeline)
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Failures = append(result.Failures, checkRows(database, c.Expect)...)
+	result.Failures = append(result.Failures, checkEmitted(driver, cfg.KafkaConfig.ProducerTopic, c.Expect)...)
+	if retried := driver.Messages(cfg.KafkaConfig.Topic + "-retry"); len(retried) > 0 {
+		result.Failures = append(result.Failures, fmt.Sprintf("%d message(s) were sent to the retry topic", len(retried)))
+	}
+
+	return result
+}
+
+func feed(ctx context.Context, driver *memory.Driver, topic string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		message, err := replay.ParseLine(scanner.Bytes(), replay.FormatAuto)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := driver.Produce(ctx, topic, message); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func checkRows(database *db.DB, expect Expectation) []string {
+	var failures []string
+
+	for table, rows := range expect.Rows {
+		for _, want := range rows {
+			id := fmt.Sprint(want["id"])
+
+			var got map[string]interface{}
+			err := database.DB.Table(table).Where("id = ?", id).Take(&got).Error
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s %s: %v", table, id, err))
+				continue
+			}
+
+			for column, value := range want {
+				if normalize(got[column]) != normalize(value) {
+					failures = append(failures, fmt.Sprintf("%s %s: %s is %q, want %q", table, id, column, normalize(got[column]), normalize(value)))
+				}
+			}
+		}
+	}
+
+	for table, ids := range expect.Absent {
+		for _, id := range ids {
+			var count int64
+			if err := database.DB.Table(table).Where("id = ?", id).Count(&count).Error; err != nil {
+				failures = append(failures, fmt.Sprintf("%s %s: %v", table, id, err))
+				continue
+			}
+			if count != 0 {
+				failures = append(failures, fmt.Sprintf("%s %s: expected row to be absent", table, id))
+			}
+		}
+	}
+
+	return failures
+}
+
+func checkEmitted(driver *memory.Driver, topic string, expect Expectation) []string {
+	if expect.Emitted == nil {
+		return nil
+	}
+
+	messages := driver.Messages(topic)
+	if len(messages) != len(expect.Emitted) {
+		return []string{fmt.Sprintf("emitted %d message(s), want %d", len(messages), len(expect.Emitted))}
+	}
+
+	var failures []string
+	for i, message := range messages {
+		var got interface{}
+		if err := json.Unmarshal(message.Value, &got); err != nil {
+			failures = append(failures, fmt.Sprintf("emitted message %d is not json: %v", i, err))
+			continue
+		}
+		if !contains(got, expect.Emitted[i]) {
+			failures = append(failures, fmt.Sprintf("emitted message %d is %s", i, string(message.Value)))
+		}
+	}
+	return failures
+}
+
+// contains reports whether want is a subset of got, objects only need the
+// keys of want, everything else must be equal.
+func contains(got interface{}, want interface{}) bool {
+	wantObject, ok := want.(map[string]interface{})
+	if !ok {
+		return reflect.DeepEqual(got, want)
+	}
+	gotObject, ok := got.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for key, value := range wantObject {
+		if !contains(gotObject[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalize(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		// json numbers, large ones would print in exponent form
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
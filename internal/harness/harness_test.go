@@ -0,0 +1,35 @@
+package harness
+
+import (
+	"context"
+	"testing"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestFixtures(t *testing.T) {
+	cases, err := LoadCases("../../testdata/pipelines")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatal("no fixture cases found")
+	}
+
+	cfg := config.LoadConfigOrPanic()
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			result := Run(ctx, cfg, c)
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			for _, failure := range result.Failures {
+				t.Error(failure)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package harness
+
+// Expectation is the expect.json of a fixture case.
+type Expectation struct {
+	// Pipeline is one of character, staff or link.
+	Pipeline string `json:"pipeline"`
+	// Ledger enables the processed-message ledger for the case.
+	Ledger bool `json:"ledger"`
+	// CastEvents enables the anime_cast_changed aggregator for the case, the
+	// pending events are emitted when the pipeline stops.
+	CastEvents bool `json:"cast_events"`
+	// Source, when set, enables source priorities for the case with every
+	// pipeline ingesting from the source, ranked by SourcePriorities.
+	Source           string `json:"source"`
+	SourcePriorities string `json:"source_priorities"`
+	// History records the applied changes in entity_history for the case.
+	History bool `json:"history"`
+	// ImageDedupe, when set, enables the image request cache with the backend
+	// (memory or database) for the case.
+	ImageDedupe string `json:"image_dedupe"`
+	// Seed rows are inserted per table before the messages are fed.
+	Seed map[string][]map[string]interface{} `json:"seed"`
+	// Rows are column subsets that must match the stored row with the same id.
+	Rows map[string][]map[string]interface{} `json:"rows"`
+	// Absent ids must not exist in the table.
+	Absent map[string][]string `json:"absent"`
+	// Emitted are json subsets the outbound messages must match, in order.
+	Emitted []interface{} `json:"emitted"`
+}
+
+type Case struct {
+	Name   string
+	Dir    string
+	Expect Expectation
+}
+
+type Result struct {
+	Case     string
+	Failures []string
+	Err      error
+}
+
+func (r Result) Passed() bool {
+	return r.Err == nil && len(r.Failures) == 0
+}
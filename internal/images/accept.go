@@ -0,0 +1,38 @@
+package images
+
+import (
+	"context"
+
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Entity is the kind of row an image belongs to.
+type Entity = string
+
+const (
+	EntityCharacter Entity = "character"
+	EntityStaff     Entity = "staff"
+
+	resultAccepted = "accepted"
+)
+
+// Accept returns the normalized URL of the image of an entity and whether it
+// may be sent to image-sync. Every result is counted, rejections are logged
+// with their reason.
+func (v *Validator) Accept(ctx context.Context, entity Entity, id string, raw string) (string, bool) {
+	normalized, reason := v.Normalize(raw)
+	if reason == "" {
+		metrics.ImageURLs.WithLabelValues(entity, resultAccepted).Inc()
+		return normalized, true
+	}
+
+	metrics.ImageURLs.WithLabelValues(entity, reason).Inc()
+	logger.FromCtx(ctx).Warn("Rejected image url",
+		zap.String("entity", entity),
+		zap.String("id", id),
+		zap.String("url", raw),
+		zap.String("reason", reason))
+	return "", false
+}
@@ -0,0 +1,177 @@
+package images
+
+import (
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Reason is why an image URL was rejected.
+type Reason = string
+
+const (
+	ReasonEmpty     Reason = "empty"
+	ReasonMalformed Reason = "malformed"
+	ReasonRelative  Reason = "relative"
+	ReasonDataURI   Reason = "data_uri"
+	ReasonScheme    Reason = "scheme"
+	ReasonHost      Reason = "host"
+)
+
+// DefaultTrackingParams are the query parameters added by share links and
+// ad campaigns, they never select a different image.
+var DefaultTrackingParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid", "mc_cid", "mc_eid", "igshid", "_ga", "ref_src",
+}
+
+// Validator checks image URLs against an allowlist and canonicalizes them,
+// so the same image is always requested with the same URL.
+type Validator struct {
+	schemes        map[string]bool
+	hosts          []string
+	trackingParams []string
+}
+
+// NewValidator allows the schemes and hosts, an empty host list allows every
+// host and a host also allows its subdomains. Tracking params are removed
+// from the query, a trailing * matches a prefix.
+func NewValidator(schemes []string, hosts []string, trackingParams []string) *Validator {
+	v := &Validator{schemes: map[string]bool{}}
+	for _, scheme := range schemes {
+		if scheme = strings.ToLower(strings.TrimSpace(scheme)); scheme != "" {
+			v.schemes[scheme] = true
+		}
+	}
+	for _, host := range hosts {
+		if host = strings.Trim(strings.ToLower(strings.TrimSpace(host)), "."); host != "" {
+			v.hosts = append(v.hosts, host)
+		}
+	}
+	for _, param := range trackingParams {
+		if param = strings.ToLower(strings.TrimSpace(param)); param != "" {
+			v.trackingParams = append(v.trackingParams, param)
+		}
+	}
+	return v
+}
+
+// defaultValidator is used by a nil Validator, it allows http and https URLs
+// of every host.
+var defaultValidator = NewValidator([]string{"https", "http"}, nil, DefaultTrackingParams)
+
+// Normalize returns the canonical form of the URL: lowercase scheme and
+// host, no default port, credentials or fragment, a clean path and the
+// query without tracking params, sorted by key. The reason is empty for an
+// accepted URL.
+func (v *Validator) Normalize(raw string) (string, Reason) {
+	if v == nil {
+		v = defaultValidator
+	}
+
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ReasonEmpty
+	}
+	if strings.HasPrefix(strings.ToLower(raw), "data:") {
+		return "", ReasonDataURI
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", ReasonMalformed
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", ReasonRelative
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !v.schemes[u.Scheme] {
+		return "", ReasonScheme
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return "", ReasonMalformed
+	}
+	if !v.allowedHost(host) {
+		return "", ReasonHost
+	}
+	port := u.Port()
+	if (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		// ipv6 literal
+		host = "[" + host + "]"
+	}
+	u.Host = host
+	if port != "" {
+		u.Host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+
+	u.User = nil
+	u.Fragment, u.RawFragment = "", ""
+	if u.RawPath == "" {
+		u.Path = cleanPath(u.Path)
+	}
+	u.RawQuery = v.cleanQuery(u.RawQuery)
+	u.ForceQuery = false
+
+	return u.String(), ""
+}
+
+func (v *Validator) allowedHost(host string) bool {
+	if len(v.hosts) == 0 {
+		return true
+	}
+	for _, allowed := range v.hosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Validator) isTracking(param string) bool {
+	param = strings.ToLower(param)
+	for _, tracking := range v.trackingParams {
+		if prefix, ok := strings.CutSuffix(tracking, "*"); ok && strings.HasPrefix(param, prefix) {
+			return true
+		}
+		if param == tracking {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanQuery drops the tracking params and sorts the rest by key, a query
+// that cannot be parsed is kept as sent.
+func (v *Validator) cleanQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+	for param := range values {
+		if v.isTracking(param) {
+			values.Del(param)
+		}
+	}
+	return values.Encode()
+}
+
+// cleanPath resolves dot segments and repeated slashes, keeping a trailing
+// slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "character_staff_sync"
+
+// AttributeParse counts attribute normalization results by entity,
+// attribute and result (parsed or failed), failures are data-quality issues
+// in the source.
+var AttributeParse = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "attribute_parse_total",
+	Help:      "Free-form attribute values parsed into typed columns, by result.",
+}, []string{"entity", "attribute", "result"})
+
+// CastEvents counts anime_cast_changed events by result (sent or failed).
+var CastEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "cast_events_total",
+	Help:      "Debounced anime_cast_changed events, by result.",
+}, []string{"result"})
+
+// Merges counts merged duplicates by entity (character or staff).
+var Merges = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "merges_total",
+	Help:      "Duplicate characters and staff merged into another row, by entity.",
+}, []string{"entity"})
+
+// Redirects counts inbound rows whose id was rewritten to the entity it was
+// merged into, by entity and operation (upsert, or delete when skipped).
+var Redirects = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "redirects_total",
+	Help:      "Inbound rows of merged ids that followed a redirect, by entity and operation.",
+}, []string{"entity", "operation"})
+
+// ProtectedFields counts inbound field values that were dropped because a
+// source with a higher priority owns the field, by entity, the source of
+// the pipeline and field.
+var ProtectedFields = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "protected_fields_total",
+	Help:      "Inbound field values kept out because a higher priority source owns the field.",
+}, []string{"entity", "source", "field"})
+
+// ProtectedDeletes counts inbound deletes that were skipped because a source
+// with a higher priority owns fields of the row.
+var ProtectedDeletes = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "protected_deletes_total",
+	Help:      "Inbound deletes skipped because a higher priority source owns fields of the row.",
+}, []string{"entity", "source"})
+
+// ImageURLs counts image urls checked before they are sent to image-sync, by
+// entity and result (accepted or the reason of the rejection).
+var ImageURLs = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "image_urls_total",
+	Help:      "Image urls checked before image-sync, by entity and result.",
+}, []string{"entity", "result"})
+
+// ImageRequests counts image requests by entity and result, sent or
+// deduplicated when the same image was sent within the dedupe TTL.
+var ImageRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "image_requests_total",
+	Help:      "Image requests to image-sync, sent or deduplicated.",
+}, []string{"entity", "result"})
+
+// ThrottledSeconds counts the time consumers and producers waited for a rate
+// limit token, by direction (consume or produce) and pipeline or sink name.
+var ThrottledSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "throttled_seconds_total",
+	Help:      "Time spent waiting for a rate limit token, by direction and name.",
+}, []string{"direction", "name"})
+
+// RateLimits is the current rate limit in messages per second by direction
+// and name, 0 when the limit was removed.
+var RateLimits = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "rate_limit",
+	Help:      "Configured rate limit in messages per second, by direction and name.",
+}, []string{"direction", "name"})
+
+// InFlightMessages is the number of pulsar messages dispatched to the workers
+// of a pipeline and not yet processed.
+var InFlightMessages = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "in_flight_messages",
+	Help:      "Pulsar messages dispatched to workers and not yet processed, by pipeline.",
+}, []string{"pipeline"})
@@ -0,0 +1,56 @@
+package names
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// longVowels are the romanizations of long vowels folded into the short
+// vowel, so Yūki, Yuuki and Yuki share a key. Macrons are removed before.
+var longVowels = strings.NewReplacer(
+	"ou", "o",
+	"oo", "o",
+	"uu", "u",
+	"aa", "a",
+	"ii", "i",
+	"ee", "e",
+)
+
+// Tokens splits a name into normalized words: accents of latin letters are
+// removed, text is lowercased, width variants are folded and long vowels are
+// shortened. Kana and kanji keep their voicing marks.
+func Tokens(name string) []string {
+	var b strings.Builder
+	var previous rune
+	for _, r := range norm.NFKD.String(name) {
+		if unicode.Is(unicode.Mn, r) && unicode.Is(unicode.Latin, previous) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+		previous = r
+	}
+
+	words := strings.FieldsFunc(norm.NFC.String(b.String()), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = longVowels.Replace(word)
+	}
+	return words
+}
+
+// Key is the normalized form of a name stored next to the entity. The words
+// are sorted, so family name first and given name first share a key.
+func Key(name string) string {
+	tokens := Tokens(name)
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+// FullName joins a given and family name into a single name.
+func FullName(givenName string, familyName string) string {
+	return strings.TrimSpace(givenName + " " + familyName)
+}
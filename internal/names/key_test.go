@@ -0,0 +1,30 @@
+package names
+
+import "testing"
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "", want: ""},
+		{name: "Mayoi Hachikuji", want: "hachikuji mayoi"},
+		{name: "Hachikuji Mayoi", want: "hachikuji mayoi"},
+		{name: "  HACHIKUJI,  mayoi ", want: "hachikuji mayoi"},
+		{name: "Yūki Kajiura", want: "kajiura yuki"},
+		{name: "Yuuki Kajiura", want: "kajiura yuki"},
+		{name: "Ryouko Shiraishi", want: "ryoko shiraishi"},
+		{name: "Chloé Dupont", want: "chloe dupont"},
+		{name: "Ｍａｙｏｉ", want: "mayoi"},
+		{name: "八九寺 真宵", want: "八九寺 真宵"},
+		{name: "がぎ", want: "がぎ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Key(tt.name); got != tt.want {
+				t.Errorf("Key(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package names
+
+import (
+	"sort"
+	"strings"
+)
+
+// Match is a candidate entity for a searched name, Score is the confidence
+// between 0 and 1 where 1 is a normalized exact match.
+type Match struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+// Score compares two name keys. Keys are compared as written and with the
+// spaces removed, so "hanazawa kana" and "hanazawakana" are close.
+func Score(queryKey string, candidateKey string) float64 {
+	if queryKey == "" || candidateKey == "" {
+		return 0
+	}
+	if queryKey == candidateKey {
+		return 1
+	}
+
+	score := similarity(queryKey, candidateKey)
+	compact := similarity(strings.ReplaceAll(queryKey, " ", ""), strings.ReplaceAll(candidateKey, " ", ""))
+	if compact > score {
+		score = compact
+	}
+	// a normalized exact match without spaces is still not the exact key
+	if score > 0.99 {
+		score = 0.99
+	}
+	return score
+}
+
+// Rank scores the candidates against the query key, drops the ones below
+// minScore and returns at most limit matches, best first. Candidates may
+// repeat an id with different names, an id is returned once.
+func Rank(queryKey string, candidates []Match, keys []string, minScore float64, limit int) []Match {
+	matches := []Match{}
+	for i, candidate := range candidates {
+		candidate.Score = Score(queryKey, keys[i])
+		if candidate.Score >= minScore {
+			matches = append(matches, candidate)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	// an entity matches once, with its best scoring name
+	seen := map[string]bool{}
+	unique := matches[:0]
+	for _, match := range matches {
+		if !seen[match.ID] {
+			seen[match.ID] = true
+			unique = append(unique, match)
+		}
+	}
+	matches = unique
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// similarity is one minus the levenshtein distance relative to the longer
+// string, counted in runes.
+func similarity(a string, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+const (
+	// MinScore is the lowest confidence returned by the repository matchers.
+	MinScore = 0.5
+	// MaxCandidates caps the rows the repository matchers score.
+	MaxCandidates = 500
+)
+
+// fragmentLength is the length of the word prefixes used to look up
+// candidates, short enough to find names written without spaces.
+const fragmentLength = 4
+
+// Fragments returns the distinct word prefixes of a key, used to narrow
+// down the candidates with LIKE before scoring them.
+func Fragments(key string) []string {
+	seen := map[string]bool{}
+	var fragments []string
+	for _, token := range strings.Fields(key) {
+		runes := []rune(token)
+		if len(runes) > fragmentLength {
+			runes = runes[:fragmentLength]
+		}
+		fragment := string(runes)
+		if !seen[fragment] {
+			seen[fragment] = true
+			fragments = append(fragments, fragment)
+		}
+	}
+	return fragments
+}
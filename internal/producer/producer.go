@@ -5,6 +5,7 @@ import (
 	"github.com/apache/pulsar-client-go/pulsar"
 	"github.com/weeb-vip/character-staff-sync/config"
 	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/ratelimit"
 	"go.uber.org/zap"
 )
 
@@ -36,6 +37,10 @@ func NewProducer[T any](ctx context.Context, cfg config.PulsarConfig) Producer[T
 
 func (p *ProducerImpl[T]) Send(ctx context.Context, data []byte) error {
 	log := logger.FromCtx(ctx)
+	// limited like the routed sink of the topic
+	if err := ratelimit.Default.Wait(ctx, ratelimit.DirectionProduce, "pulsar:"+p.config.ProducerTopic); err != nil {
+		return err
+	}
 	producer, err := p.client.CreateProducer(pulsar.ProducerOptions{
 		Topic: p.config.ProducerTopic,
 	})
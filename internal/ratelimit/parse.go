@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseLimits parses a comma separated list of name=rate[/burst] entries,
+// e.g. character=20,staff=5/10. The burst defaults to the rate rounded up.
+// A rate of 0 leaves the name unlimited, character=0 lifts a default limit.
+// Names may contain colons and slashes, so kafka:image-sync=10 limits the
+// sink.
+func ParseLimits(raw string) (map[string]Limit, error) {
+	limits := map[string]Limit{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("rate limit %q must be in the form name=rate[/burst]", entry)
+		}
+		name, value := strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+
+		rateValue, burstValue, hasBurst := strings.Cut(value, "/")
+		rate, err := strconv.ParseFloat(rateValue, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("rate limit %q has an invalid rate", entry)
+		}
+		limit := Limit{Rate: rate, Burst: defaultBurst(rate)}
+		if hasBurst {
+			limit.Burst, err = strconv.Atoi(burstValue)
+			if err != nil || limit.Burst < 1 {
+				return nil, fmt.Errorf("rate limit %q has an invalid burst", entry)
+			}
+		}
+		limits[name] = limit
+	}
+	return limits, nil
+}
+
+func defaultBurst(rate float64) int {
+	return int(math.Max(1, math.Ceil(rate)))
+}
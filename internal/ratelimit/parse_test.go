@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    map[string]Limit
+		wantErr bool
+	}{
+		{raw: "", want: map[string]Limit{}},
+		{raw: "character=20", want: map[string]Limit{"character": {Rate: 20, Burst: 20}}},
+		{raw: "staff=5/10", want: map[string]Limit{"staff": {Rate: 5, Burst: 10}}},
+		{raw: "link=0.5", want: map[string]Limit{"link": {Rate: 0.5, Burst: 1}}},
+		{raw: "link=2.5", want: map[string]Limit{"link": {Rate: 2.5, Burst: 3}}},
+		{raw: "unlimited=0", want: map[string]Limit{"unlimited": {Rate: 0, Burst: 1}}},
+		{
+			raw: " character = 20 ,, kafka:image-sync=10/2, webhook:https://example.com/hook?a=b=3 ",
+			want: map[string]Limit{
+				"character":                            {Rate: 20, Burst: 20},
+				"kafka:image-sync":                     {Rate: 10, Burst: 2},
+				"webhook:https://example.com/hook?a=b": {Rate: 3, Burst: 3},
+			},
+		},
+		{raw: "character", wantErr: true},
+		{raw: "=20", wantErr: true},
+		{raw: "character=fast", wantErr: true},
+		{raw: "character=-1", wantErr: true},
+		{raw: "character=5/0", wantErr: true},
+		{raw: "character=5/x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseLimits(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimits(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLimits(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
+	"golang.org/x/time/rate"
+)
+
+// Registry holds the token buckets of a process. Names without a limit are
+// not throttled. Limits are changed in place, a caller already waiting keeps
+// the delay it reserved and the next ones get the new rate.
+type Registry struct {
+	mu       sync.RWMutex
+	limiters map[Direction]map[string]*rate.Limiter
+	// base are the configured limits, overrides the names set by Override
+	// that replace them.
+	base      Limits
+	overrides Limits
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		limiters:  map[Direction]map[string]*rate.Limiter{},
+		base:      Limits{},
+		overrides: Limits{},
+	}
+}
+
+// Default is the registry of the process, configured by the pipeline and
+// shared by its consumers, producers and sinks.
+var Default = NewRegistry()
+
+// Wait blocks until a token of the named bucket is available or ctx is
+// done, the time spent waiting is counted as throttled.
+func (r *Registry) Wait(ctx context.Context, direction Direction, name string) error {
+	r.mu.RLock()
+	limiter := r.limiters[direction][name]
+	r.mu.RUnlock()
+	if limiter == nil || limiter.Limit() == rate.Inf {
+		return nil
+	}
+
+	start := time.Now()
+	err := limiter.Wait(ctx)
+	if waited := time.Since(start); waited > time.Millisecond {
+		metrics.ThrottledSeconds.WithLabelValues(direction, name).Add(waited.Seconds())
+	}
+	return err
+}
+
+// Set changes the limit of a bucket until the next Configure or Override
+// of the name, a zero rate removes the limit.
+func (r *Registry) Set(direction Direction, name string, limit Limit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.set(direction, name, limit)
+}
+
+// Reset drops the override of a bucket and any limit set since, the bucket
+// goes back to its configured limit.
+func (r *Registry) Reset(direction Direction, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.overrides[direction], name)
+	r.set(direction, name, r.base[direction][name])
+}
+
+// Configure replaces all limits with the given ones and drops the
+// overrides.
+func (r *Registry) Configure(limits Limits) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for direction, limiters := range r.limiters {
+		for name := range limiters {
+			r.set(direction, name, Limit{})
+		}
+	}
+	r.base = copyLimits(limits)
+	r.overrides = Limits{}
+	for direction, named := range limits {
+		for name, limit := range named {
+			r.set(direction, name, limit)
+		}
+	}
+}
+
+// Override applies runtime overrides on top of the configured limits. Names
+// overridden before but left out of overrides go back to their configured
+// limit.
+func (r *Registry) Override(overrides Limits) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for direction, named := range r.overrides {
+		for name := range named {
+			if _, ok := overrides[direction][name]; !ok {
+				r.set(direction, name, r.base[direction][name])
+			}
+		}
+	}
+	r.overrides = copyLimits(overrides)
+	for direction, named := range overrides {
+		for name, limit := range named {
+			r.set(direction, name, limit)
+		}
+	}
+}
+
+// Limits returns the current limits, unlimited names are left out.
+func (r *Registry) Limits() Limits {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	limits := Limits{DirectionConsume: {}, DirectionProduce: {}}
+	for direction, limiters := range r.limiters {
+		for name, limiter := range limiters {
+			if limiter.Limit() == rate.Inf {
+				continue
+			}
+			limits[direction][name] = Limit{Rate: float64(limiter.Limit()), Burst: limiter.Burst()}
+		}
+	}
+	return limits
+}
+
+func (r *Registry) set(direction Direction, name string, limit Limit) {
+	every, burst := rate.Inf, 0
+	if limit.Rate > 0 {
+		every, burst = rate.Limit(limit.Rate), limit.Burst
+		if burst < 1 {
+			burst = defaultBurst(limit.Rate)
+		}
+	}
+
+	if r.limiters[direction] == nil {
+		r.limiters[direction] = map[string]*rate.Limiter{}
+	}
+	limiter := r.limiters[direction][name]
+	if limiter == nil {
+		r.limiters[direction][name] = rate.NewLimiter(every, burst)
+	} else {
+		limiter.SetBurst(burst)
+		limiter.SetLimit(every)
+	}
+	metrics.RateLimits.WithLabelValues(direction, name).Set(limit.Rate)
+}
+
+func copyLimits(limits Limits) Limits {
+	copied := Limits{}
+	for direction, named := range limits {
+		copied[direction] = map[string]Limit{}
+		for name, limit := range named {
+			copied[direction][name] = limit
+		}
+	}
+	return copied
+}
@@ -0,0 +1,21 @@
+package ratelimit
+
+// Direction tells consumption limits, named after the pipeline, from
+// production limits, named after the sink.
+type Direction = string
+
+const (
+	DirectionConsume Direction = "consume"
+	DirectionProduce Direction = "produce"
+)
+
+// Limit is a token bucket refilled with Rate messages per second holding up
+// to Burst messages. A Rate of 0 is unlimited.
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Limits are the limits of every direction keyed by name, e.g.
+// {"consume": {"character": {"rate": 20}}, "produce": {"kafka:image-sync": {"rate": 10}}}.
+type Limits map[Direction]map[string]Limit
@@ -0,0 +1,246 @@
+package routing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThatCatDev/ep/v2/drivers"
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/config"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/producer"
+	"github.com/weeb-vip/character-staff-sync/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+type Router interface {
+	Route(ctx context.Context, eventType EventType, message *kafka.Message) error
+	Producer(eventType EventType) func(ctx context.Context, message *kafka.Message) error
+	Sinks(eventType EventType) []Sink
+	Close() error
+}
+
+type RouterImpl struct {
+	routes     map[EventType][]Sink
+	sinks      map[string]Sink
+	tracker    DeliveryTracker
+	maxRetries uint64
+	pulsar     pulsar.Client
+}
+
+// NewRouter builds the sinks declared in the routing config. Kafka sinks share
+// the given driver, pulsar sinks share a single client created on demand.
+func NewRouter(cfg config.Config, driver drivers.Driver[*kafka.Message]) (Router, error) {
+	r := &RouterImpl{
+		routes:     map[EventType][]Sink{},
+		sinks:      map[string]Sink{},
+		tracker:    NewMemoryDeliveryTracker(30 * time.Minute),
+		maxRetries: cfg.RoutingConfig.MaxRetries,
+	}
+
+	routes := map[EventType]string{
+		EventTypeCharacter: cfg.RoutingConfig.CharacterSinks,
+		EventTypeStaff:     cfg.RoutingConfig.StaffSinks,
+		EventTypeLink:      cfg.RoutingConfig.LinkSinks,
+		EventTypeCast:      cfg.RoutingConfig.CastSinks,
+	}
+
+	for eventType, raw := range routes {
+		specs, err := ParseSinkSpecs(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s routes: %w", eventType, err)
+		}
+		for _, spec := range specs {
+			sink, err := r.sinkFor(cfg, driver, spec)
+			if err != nil {
+				return nil, err
+			}
+			r.routes[eventType] = append(r.routes[eventType], sink)
+		}
+	}
+
+	return r, nil
+}
+
+// ParseSinkSpecs parses a comma separated list of kind:target entries.
+func ParseSinkSpecs(raw string) ([]SinkSpec, error) {
+	var specs []SinkSpec
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kind, target, found := strings.Cut(entry, ":")
+		if !found || target == "" {
+			return nil, fmt.Errorf("sink %q must be in the form kind:target", entry)
+		}
+		switch kind {
+		case SinkKindKafka, SinkKindPulsar, SinkKindWebhook, SinkKindFile:
+		default:
+			return nil, fmt.Errorf("unknown sink kind %q", kind)
+		}
+		specs = append(specs, SinkSpec{Kind: kind, Target: target})
+	}
+	return specs, nil
+}
+
+func (r *RouterImpl) sinkFor(cfg config.Config, driver drivers.Driver[*kafka.Message], spec SinkSpec) (Sink, error) {
+	if sink, ok := r.sinks[spec.String()]; ok {
+		return sink, nil
+	}
+
+	var sink Sink
+	switch spec.Kind {
+	case SinkKindKafka:
+		if driver == nil {
+			return nil, fmt.Errorf("sink %s requires a kafka driver", spec)
+		}
+		sink = NewKafkaSink(driver, spec.Target)
+	case SinkKindPulsar:
+		if r.pulsar == nil {
+			client, err := pulsar.NewClient(pulsar.ClientOptions{
+				URL: cfg.PulsarConfig.URL,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create pulsar client: %w", err)
+			}
+			r.pulsar = client
+		}
+		sink = NewPulsarSink(r.pulsar, spec.Target)
+	case SinkKindWebhook:
+		sink = NewWebhookSink(spec.Target, time.Duration(cfg.RoutingConfig.WebhookTimeoutSeconds)*time.Second)
+	case SinkKindFile:
+		sink = NewFileSink(spec.Target)
+	}
+
+	r.sinks[spec.String()] = sink
+	return sink, nil
+}
+
+// Route delivers the message to every sink of the event type. Sinks that
+// already accepted the same message on an earlier attempt are skipped, so
+// retrying after a partial failure does not duplicate the message.
+func (r *RouterImpl) Route(ctx context.Context, eventType EventType, message *kafka.Message) error {
+	log := logger.FromCtx(ctx)
+
+	sinks := r.routes[eventType]
+	if len(sinks) == 0 {
+		log.Warn("No sinks configured for event type", zap.String("eventType", eventType))
+		return nil
+	}
+
+	key := deliveryKey(ctx, eventType, message)
+
+	var errs []error
+	for _, sink := range sinks {
+		if r.tracker.Status(key, sink.Name()) == DeliveryStatusDelivered {
+			log.Info("Skipping sink, message already delivered", zap.String("eventType", eventType), zap.String("sink", sink.Name()))
+			continue
+		}
+
+		if err := ratelimit.Default.Wait(ctx, ratelimit.DirectionProduce, sink.Name()); err != nil {
+			return err
+		}
+		operation := func() error {
+			return sink.Send(ctx, message)
+		}
+		err := backoff.Retry(operation, backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), r.maxRetries), ctx))
+		if err != nil {
+			log.Error("Failed to deliver message", zap.String("eventType", eventType), zap.String("sink", sink.Name()), zap.Error(err))
+			r.tracker.Mark(key, sink.Name(), DeliveryStatusFailed)
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			continue
+		}
+
+		log.Info("Delivered message", zap.String("eventType", eventType), zap.String("sink", sink.Name()))
+		r.tracker.Mark(key, sink.Name(), DeliveryStatusDelivered)
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	r.tracker.Forget(key)
+	return nil
+}
+
+func (r *RouterImpl) Producer(eventType EventType) func(ctx context.Context, message *kafka.Message) error {
+	return func(ctx context.Context, message *kafka.Message) error {
+		return r.Route(ctx, eventType, message)
+	}
+}
+
+func (r *RouterImpl) Sinks(eventType EventType) []Sink {
+	return r.routes[eventType]
+}
+
+func (r *RouterImpl) Close() error {
+	var errs []error
+	for _, sink := range r.sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if r.pulsar != nil {
+		r.pulsar.Close()
+	}
+	return errors.Join(errs...)
+}
+
+type messageIDKey struct{}
+
+// WithMessageID returns a context carrying the id of the consumed message the
+// routed messages are sent for, so a retry of the message is tracked as the
+// same delivery.
+func WithMessageID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, messageIDKey{}, id)
+}
+
+// deliveryKey identifies a routed message across retries by the id of the
+// consumed message and the key of the routed message, its value when it has
+// no key. Messages sent outside of a consumer, backfills and merges, have no
+// consumed message and are identified by their content.
+func deliveryKey(ctx context.Context, eventType EventType, message *kafka.Message) string {
+	hash := sha256.New()
+	hash.Write([]byte(eventType))
+	hash.Write([]byte{0})
+	if id, ok := ctx.Value(messageIDKey{}).(string); ok && id != "" {
+		hash.Write([]byte(id))
+		hash.Write([]byte{0})
+		if len(message.Key) > 0 {
+			hash.Write(message.Key)
+			return hex.EncodeToString(hash.Sum(nil))
+		}
+	}
+	hash.Write(message.Key)
+	hash.Write([]byte{0})
+	hash.Write(message.Value)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type routedProducer[T any] struct {
+	router    Router
+	eventType EventType
+}
+
+// NewProducer adapts the router to the producer.Producer interface used by
+// the pulsar processors.
+func NewProducer[T any](router Router, eventType EventType) producer.Producer[T] {
+	return &routedProducer[T]{
+		router:    router,
+		eventType: eventType,
+	}
+}
+
+func (p *routedProducer[T]) Send(ctx context.Context, data []byte) error {
+	return p.router.Route(ctx, p.eventType, &kafka.Message{
+		Value: data,
+	})
+}
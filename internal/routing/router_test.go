@@ -0,0 +1,99 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestParseSinkSpecs(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []SinkSpec
+		wantErr bool
+	}{
+		{raw: "", want: nil},
+		{raw: "kafka:character-updates", want: []SinkSpec{{Kind: SinkKindKafka, Target: "character-updates"}}},
+		{
+			raw: " pulsar:persistent://public/default/staff , webhook:https://example.com/hook,,file:/tmp/out.jsonl ",
+			want: []SinkSpec{
+				{Kind: SinkKindPulsar, Target: "persistent://public/default/staff"},
+				{Kind: SinkKindWebhook, Target: "https://example.com/hook"},
+				{Kind: SinkKindFile, Target: "/tmp/out.jsonl"},
+			},
+		},
+		{raw: "kafka", wantErr: true},
+		{raw: "kafka:", wantErr: true},
+		{raw: "redis:characters", wantErr: true},
+		{raw: "kafka:characters,smtp:ops", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseSinkSpecs(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSinkSpecs(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSinkSpecs(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+type countingSink struct {
+	name string
+	fail bool
+	sent int
+}
+
+func (s *countingSink) Name() string { return s.name }
+
+func (s *countingSink) Send(ctx context.Context, message *kafka.Message) error {
+	if s.fail {
+		return errors.New("unavailable")
+	}
+	s.sent++
+	return nil
+}
+
+func (s *countingSink) Close() error { return nil }
+
+func TestRouteTracksDeliveriesByMessageID(t *testing.T) {
+	healthy := &countingSink{name: "file:healthy"}
+	failing := &countingSink{name: "file:failing", fail: true}
+	r := &RouterImpl{
+		routes:  map[EventType][]Sink{EventTypeCharacter: {healthy, failing}},
+		tracker: NewMemoryDeliveryTracker(time.Minute),
+	}
+	message := func() *kafka.Message {
+		return &kafka.Message{Value: []byte(`{"data":{"name":"Edward Elric"}}`)}
+	}
+
+	first := WithMessageID(context.Background(), "kafka:characters/0/1")
+	if err := r.Route(first, EventTypeCharacter, message()); err == nil {
+		t.Fatal("Route with a failing sink returned no error")
+	}
+
+	// another message asking for the same image is a delivery of its own
+	second := WithMessageID(context.Background(), "kafka:characters/0/2")
+	if err := r.Route(second, EventTypeCharacter, message()); err == nil {
+		t.Fatal("Route with a failing sink returned no error")
+	}
+	if healthy.sent != 2 {
+		t.Errorf("healthy sink got %d messages, want 2", healthy.sent)
+	}
+
+	// the retry of the first message only goes to the sink that failed
+	failing.fail = false
+	if err := r.Route(first, EventTypeCharacter, message()); err != nil {
+		t.Fatal(err)
+	}
+	if healthy.sent != 2 || failing.sent != 1 {
+		t.Errorf("after the retry healthy = %d, failing = %d, want 2 and 1", healthy.sent, failing.sent)
+	}
+}
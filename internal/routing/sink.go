@@ -0,0 +1,199 @@
+package routing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ThatCatDev/ep/v2/drivers"
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, message *kafka.Message) error
+	Close() error
+}
+
+type KafkaSink struct {
+	driver drivers.Driver[*kafka.Message]
+	topic  string
+}
+
+func NewKafkaSink(driver drivers.Driver[*kafka.Message], topic string) Sink {
+	return &KafkaSink{
+		driver: driver,
+		topic:  topic,
+	}
+}
+
+func (s *KafkaSink) Name() string {
+	return SinkSpec{Kind: SinkKindKafka, Target: s.topic}.String()
+}
+
+func (s *KafkaSink) Send(ctx context.Context, message *kafka.Message) error {
+	return s.driver.Produce(ctx, s.topic, message)
+}
+
+// Close is a no-op, the driver is owned by the caller.
+func (s *KafkaSink) Close() error {
+	return nil
+}
+
+type PulsarSink struct {
+	client   pulsar.Client
+	topic    string
+	producer pulsar.Producer
+	mu       sync.Mutex
+}
+
+func NewPulsarSink(client pulsar.Client, topic string) Sink {
+	return &PulsarSink{
+		client: client,
+		topic:  topic,
+	}
+}
+
+func (s *PulsarSink) Name() string {
+	return SinkSpec{Kind: SinkKindPulsar, Target: s.topic}.String()
+}
+
+func (s *PulsarSink) Send(ctx context.Context, message *kafka.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.producer == nil {
+		producer, err := s.client.CreateProducer(pulsar.ProducerOptions{
+			Topic: s.topic,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create pulsar producer: %w", err)
+		}
+		s.producer = producer
+	}
+
+	_, err := s.producer.Send(ctx, &pulsar.ProducerMessage{
+		Key:     string(message.Key),
+		Payload: message.Value,
+	})
+	return err
+}
+
+func (s *PulsarSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.producer != nil {
+		s.producer.Close()
+		s.producer = nil
+	}
+	return nil
+}
+
+type WebhookSink struct {
+	client *http.Client
+	url    string
+}
+
+func NewWebhookSink(url string, timeout time.Duration) Sink {
+	return &WebhookSink{
+		client: &http.Client{Timeout: timeout},
+		url:    url,
+	}
+}
+
+func (s *WebhookSink) Name() string {
+	return SinkSpec{Kind: SinkKindWebhook, Target: s.url}.String()
+}
+
+func (s *WebhookSink) Send(ctx context.Context, message *kafka.Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(message.Value))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(message.Key) > 0 {
+		req.Header.Set("X-Message-Key", string(message.Key))
+	}
+	for _, header := range message.Headers {
+		req.Header.Set(header.Key, string(header.Value))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with status %d", s.url, resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *WebhookSink) Close() error {
+	return nil
+}
+
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// FileRecord is a single line written by the FileSink.
+type FileRecord struct {
+	Key   string          `json:"key,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+func NewFileSink(path string) Sink {
+	return &FileSink{
+		path: path,
+	}
+}
+
+func (s *FileSink) Name() string {
+	return SinkSpec{Kind: SinkKindFile, Target: s.path}.String()
+}
+
+func (s *FileSink) Send(ctx context.Context, message *kafka.Message) error {
+	value := json.RawMessage(message.Value)
+	if !json.Valid(message.Value) {
+		// keep the file valid JSONL even when the payload is not json
+		quoted, err := json.Marshal(string(message.Value))
+		if err != nil {
+			return err
+		}
+		value = quoted
+	}
+
+	line, err := json.Marshal(FileRecord{
+		Key:   string(message.Key),
+		Value: value,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+func (s *FileSink) Close() error {
+	return nil
+}
@@ -0,0 +1,83 @@
+package routing
+
+import (
+	"sync"
+	"time"
+)
+
+// DeliveryTracker remembers which sinks already accepted a message so a
+// retried message is only re-sent to the sinks that failed.
+type DeliveryTracker interface {
+	Status(key string, sink string) DeliveryStatus
+	Mark(key string, sink string, status DeliveryStatus)
+	Forget(key string)
+}
+
+type deliveryEntry struct {
+	sinks     map[string]DeliveryStatus
+	updatedAt time.Time
+}
+
+type MemoryDeliveryTracker struct {
+	entries map[string]*deliveryEntry
+	ttl     time.Duration
+	mu      sync.Mutex
+}
+
+func NewMemoryDeliveryTracker(ttl time.Duration) DeliveryTracker {
+	return &MemoryDeliveryTracker{
+		entries: map[string]*deliveryEntry{},
+		ttl:     ttl,
+	}
+}
+
+func (t *MemoryDeliveryTracker) Status(key string, sink string) DeliveryStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.entries[key]
+	if !ok {
+		return DeliveryStatusPending
+	}
+	status, ok := entry.sinks[sink]
+	if !ok {
+		return DeliveryStatusPending
+	}
+	return status
+}
+
+func (t *MemoryDeliveryTracker) Mark(key string, sink string, status DeliveryStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.evictExpired()
+
+	entry, ok := t.entries[key]
+	if !ok {
+		entry = &deliveryEntry{sinks: map[string]DeliveryStatus{}}
+		t.entries[key] = entry
+	}
+	entry.sinks[sink] = status
+	entry.updatedAt = time.Now()
+}
+
+func (t *MemoryDeliveryTracker) Forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.entries, key)
+}
+
+// evictExpired drops entries that were not touched within the ttl, the caller
+// must hold the lock.
+func (t *MemoryDeliveryTracker) evictExpired() {
+	if t.ttl <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-t.ttl)
+	for key, entry := range t.entries {
+		if entry.updatedAt.Before(cutoff) {
+			delete(t.entries, key)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package routing
+
+type EventType = string
+
+const (
+	EventTypeCharacter EventType = "character"
+	EventTypeStaff     EventType = "staff"
+	EventTypeLink      EventType = "link"
+	EventTypeCast      EventType = "cast"
+)
+
+type SinkKind = string
+
+const (
+	SinkKindKafka   SinkKind = "kafka"
+	SinkKindPulsar  SinkKind = "pulsar"
+	SinkKindWebhook SinkKind = "webhook"
+	SinkKindFile    SinkKind = "file"
+)
+
+type DeliveryStatus = string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusDelivered DeliveryStatus = "delivered"
+	DeliveryStatusFailed    DeliveryStatus = "failed"
+)
+
+// SinkSpec is a parsed kind:target entry from the routing config.
+type SinkSpec struct {
+	Kind   SinkKind
+	Target string
+}
+
+func (s SinkSpec) String() string {
+	return s.Kind + ":" + s.Target
+}
@@ -0,0 +1,89 @@
+package attributes
+
+import (
+	"context"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// NormalizeCharacter fills the typed attribute columns of a character from
+// the raw strings, values that cannot be parsed are left nil and reported.
+func NormalizeCharacter(ctx context.Context, character *anime_character.AnimeCharacter) {
+	character.HeightCm = nil
+	if character.Height != "" {
+		height, err := ParseHeight(character.Height)
+		report(ctx, EntityCharacter, AttributeHeight, character.ID, character.Height, err)
+		if err == nil {
+			character.HeightCm = &height
+		}
+	}
+
+	character.WeightKg = nil
+	if character.Weight != "" {
+		weight, err := ParseWeight(character.Weight)
+		report(ctx, EntityCharacter, AttributeWeight, character.ID, character.Weight, err)
+		if err == nil {
+			character.WeightKg = &weight
+		}
+	}
+
+	character.BirthdayMonth, character.BirthdayDay, character.BirthdayYear = nil, nil, nil
+	if character.Birthday != "" {
+		birthday, err := ParseBirthday(character.Birthday)
+		report(ctx, EntityCharacter, AttributeBirthday, character.ID, character.Birthday, err)
+		if err == nil {
+			character.BirthdayMonth, character.BirthdayDay, character.BirthdayYear = &birthday.Month, &birthday.Day, birthday.Year
+		}
+	}
+}
+
+// NormalizeStaff fills the typed attribute columns and the hobby list of a
+// staff member from the raw strings, values that cannot be parsed are left
+// nil and reported.
+func NormalizeStaff(ctx context.Context, staff *anime_staff.AnimeStaff) {
+	staff.BirthdayMonth, staff.BirthdayDay, staff.BirthdayYear = nil, nil, nil
+	if staff.Birthday != "" {
+		birthday, err := ParseBirthday(staff.Birthday)
+		report(ctx, EntityStaff, AttributeBirthday, staff.ID, staff.Birthday, err)
+		if err == nil {
+			staff.BirthdayMonth, staff.BirthdayDay, staff.BirthdayYear = &birthday.Month, &birthday.Day, birthday.Year
+		}
+	}
+
+	staff.BloodTypeCode = nil
+	if staff.BloodType != "" {
+		bloodType, err := ParseBloodType(staff.BloodType)
+		report(ctx, EntityStaff, AttributeBloodType, staff.ID, staff.BloodType, err)
+		if err == nil {
+			staff.BloodTypeCode = &bloodType
+		}
+	}
+
+	// an empty list, unlike nil, clears the stored hobbies
+	staff.HobbyList = ParseHobbies(staff.Hobbies)
+	if staff.HobbyList == nil {
+		staff.HobbyList = []string{}
+	}
+	if staff.Hobbies != "" {
+		report(ctx, EntityStaff, AttributeHobbies, staff.ID, staff.Hobbies, nil)
+	}
+}
+
+func report(ctx context.Context, entity Entity, attribute Attribute, id string, raw string, err error) {
+	if err == nil {
+		metrics.AttributeParse.WithLabelValues(entity, attribute, resultParsed).Inc()
+		return
+	}
+
+	metrics.AttributeParse.WithLabelValues(entity, attribute, resultFailed).Inc()
+	logger.FromCtx(ctx).Warn("Could not normalize attribute",
+		zap.String("entity", entity),
+		zap.String("attribute", attribute),
+		zap.String("id", id),
+		zap.String("raw", raw),
+		zap.Error(err))
+}
@@ -0,0 +1,189 @@
+package attributes
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	centimetresPattern = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(?:cm|centimet(?:er|re)s?)\b`)
+	metresPattern      = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(?:m|met(?:er|re)s?)\b`)
+	feetPattern        = regexp.MustCompile(`(\d+)\s*(?:'|’|ft\.?|feet|foot)\s*(?:(\d+(?:\.\d+)?)\s*(?:"|”|''|in\.?|inch(?:es)?)?)?`)
+	kilogramsPattern   = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(?:kg|kilo(?:gram)?s?)\b`)
+	poundsPattern      = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(?:lbs?|pounds?)\b`)
+	gramsPattern       = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(?:g|grams?)\b`)
+	numberPattern      = regexp.MustCompile(`^\s*(\d+(?:[.,]\d+)?)\s*$`)
+
+	isoDatePattern      = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})`)
+	numericDatePattern  = regexp.MustCompile(`^(\d{1,2})[/.-](\d{1,2})(?:[/.-](\d{4}))?$`)
+	monthFirstPattern   = regexp.MustCompile(`([a-z]+)\.?\s+(\d{1,2})(?:st|nd|rd|th)?\b(?:,?\s*(\d{4}))?`)
+	dayFirstPattern     = regexp.MustCompile(`(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?([a-z]+)\.?(?:,?\s*(\d{4}))?`)
+	japaneseDatePattern = regexp.MustCompile(`(?:(\d{4})年)?(\d{1,2})月(\d{1,2})日`)
+	bloodTypeNoise      = strings.NewReplacer("BLOOD", "", "TYPE", "", "型", "", ":", "", "RH", "", "+", "", "-", "", " ", "", ".", "")
+	hobbySeparators     = regexp.MustCompile(`\s*(?:[,;/\n、・]|\band\b|&)\s*`)
+	maxHobbyLength      = 255
+	kilogramsPerPound   = 0.45359237
+	centimetresPerInch  = 2.54
+	centimetresPerMetre = 100.0
+	metresBareNumberMax = 3.0
+)
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// ParseHeight returns the height in centimetres. Centimetres, metres and
+// feet/inches are understood, a bare number below 3 is read as metres and
+// anything else as centimetres.
+func ParseHeight(raw string) (float64, error) {
+	s := strings.ToLower(strings.TrimSpace(raw))
+
+	if m := centimetresPattern.FindStringSubmatch(s); m != nil {
+		return positive(raw, parseFloat(m[1]))
+	}
+	if m := metresPattern.FindStringSubmatch(s); m != nil {
+		return positive(raw, parseFloat(m[1])*centimetresPerMetre)
+	}
+	if m := feetPattern.FindStringSubmatch(s); m != nil {
+		inches := parseFloat(m[1]) * 12
+		if m[2] != "" {
+			inches += parseFloat(m[2])
+		}
+		return positive(raw, inches*centimetresPerInch)
+	}
+	if m := numberPattern.FindStringSubmatch(s); m != nil {
+		v := parseFloat(m[1])
+		if v < metresBareNumberMax {
+			v *= centimetresPerMetre
+		}
+		return positive(raw, v)
+	}
+
+	return 0, fmt.Errorf("unrecognized height %q", raw)
+}
+
+// ParseWeight returns the weight in kilograms. Kilograms, grams and pounds
+// are understood, a bare number is read as kilograms.
+func ParseWeight(raw string) (float64, error) {
+	s := strings.ToLower(strings.TrimSpace(raw))
+
+	if m := kilogramsPattern.FindStringSubmatch(s); m != nil {
+		return positive(raw, parseFloat(m[1]))
+	}
+	if m := poundsPattern.FindStringSubmatch(s); m != nil {
+		return positive(raw, parseFloat(m[1])*kilogramsPerPound)
+	}
+	if m := gramsPattern.FindStringSubmatch(s); m != nil {
+		return positive(raw, parseFloat(m[1])/1000)
+	}
+	if m := numberPattern.FindStringSubmatch(s); m != nil {
+		return positive(raw, parseFloat(m[1]))
+	}
+
+	return 0, fmt.Errorf("unrecognized weight %q", raw)
+}
+
+// ParseBirthday understands ISO dates, month/day numbers, English month
+// names on either side of the day and Japanese dates, the year is optional.
+func ParseBirthday(raw string) (*Birthday, error) {
+	s := strings.ToLower(strings.TrimSpace(raw))
+
+	var year, month, day string
+	if m := isoDatePattern.FindStringSubmatch(s); m != nil {
+		year, month, day = m[1], m[2], m[3]
+	} else if m := numericDatePattern.FindStringSubmatch(s); m != nil {
+		month, day, year = m[1], m[2], m[3]
+		if atoi(month) > 12 && atoi(day) <= 12 {
+			month, day = day, month
+		}
+	} else if m := japaneseDatePattern.FindStringSubmatch(s); m != nil {
+		year, month, day = m[1], m[2], m[3]
+	} else if m := monthFirstPattern.FindStringSubmatch(s); m != nil && monthNumber(m[1]) > 0 {
+		month, day, year = strconv.Itoa(monthNumber(m[1])), m[2], m[3]
+	} else if m := dayFirstPattern.FindStringSubmatch(s); m != nil && monthNumber(m[2]) > 0 {
+		day, month, year = m[1], strconv.Itoa(monthNumber(m[2])), m[3]
+	} else {
+		return nil, fmt.Errorf("unrecognized birthday %q", raw)
+	}
+
+	birthday := &Birthday{Month: atoi(month), Day: atoi(day)}
+	if year != "" {
+		y := atoi(year)
+		birthday.Year = &y
+	}
+
+	// without a year February 29 has to be allowed, 2000 is a leap year
+	checkYear := 2000
+	if birthday.Year != nil {
+		checkYear = *birthday.Year
+	}
+	if birthday.Month < 1 || birthday.Month > 12 || birthday.Day < 1 ||
+		birthday.Day > time.Date(checkYear, time.Month(birthday.Month)+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+		return nil, fmt.Errorf("invalid birthday %q", raw)
+	}
+
+	return birthday, nil
+}
+
+// ParseBloodType returns the ABO group, rhesus factors and decorations like
+// "Type" or "型" are dropped.
+func ParseBloodType(raw string) (BloodType, error) {
+	s := bloodTypeNoise.Replace(strings.ToUpper(strings.TrimSpace(raw)))
+	switch s {
+	case BloodTypeA, BloodTypeB, BloodTypeAB, BloodTypeO:
+		return s, nil
+	case "BA":
+		return BloodTypeAB, nil
+	default:
+		return "", fmt.Errorf("unrecognized blood type %q", raw)
+	}
+}
+
+// ParseHobbies splits a free-form list of hobbies, duplicates are dropped
+// and the original order is kept.
+func ParseHobbies(raw string) []string {
+	seen := map[string]bool{}
+	var hobbies []string
+	for _, hobby := range hobbySeparators.Split(raw, -1) {
+		hobby = strings.Trim(strings.TrimSpace(hobby), ".")
+		if hobby == "" || seen[strings.ToLower(hobby)] {
+			continue
+		}
+		seen[strings.ToLower(hobby)] = true
+		if len(hobby) > maxHobbyLength {
+			hobby = hobby[:maxHobbyLength]
+		}
+		hobbies = append(hobbies, hobby)
+	}
+	return hobbies
+}
+
+func monthNumber(name string) int {
+	if len(name) < 3 {
+		return 0
+	}
+	return int(months[name[:3]])
+}
+
+func positive(raw string, v float64) (float64, error) {
+	if v <= 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, fmt.Errorf("invalid value %q", raw)
+	}
+	return math.Round(v*10) / 10, nil
+}
+
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	return v
+}
+
+func atoi(s string) int {
+	v, _ := strconv.Atoi(s)
+	return v
+}
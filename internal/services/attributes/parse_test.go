@@ -0,0 +1,170 @@
+package attributes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHeight(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    float64
+		wantErr bool
+	}{
+		{raw: "158cm", want: 158},
+		{raw: "158.5 centimetres", want: 158.5},
+		{raw: "Height: 162 cm", want: 162},
+		{raw: "1.58 m", want: 158},
+		{raw: "1,58m", want: 158},
+		{raw: "2 meters", want: 200},
+		{raw: "5'4\"", want: 162.6},
+		{raw: "5 ft 4 in", want: 162.6},
+		{raw: "6 feet", want: 182.9},
+		{raw: "1.62", want: 162},
+		{raw: "165", want: 165},
+		{raw: "0cm", wantErr: true},
+		{raw: "tall", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseHeight(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHeight(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHeight(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWeight(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    float64
+		wantErr bool
+	}{
+		{raw: "45kg", want: 45},
+		{raw: "45,5 kilograms", want: 45.5},
+		{raw: "100 lbs", want: 45.4},
+		{raw: "1 pound", want: 0.5},
+		{raw: "45000 g", want: 45},
+		{raw: "52", want: 52},
+		{raw: "0", wantErr: true},
+		{raw: "light", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseWeight(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWeight(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWeight(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBirthday(t *testing.T) {
+	year := func(y int) *int {
+		return &y
+	}
+
+	tests := []struct {
+		raw     string
+		want    *Birthday
+		wantErr bool
+	}{
+		{raw: "1995-05-03", want: &Birthday{Month: 5, Day: 3, Year: year(1995)}},
+		{raw: "5/3", want: &Birthday{Month: 5, Day: 3}},
+		{raw: "05.03.1995", want: &Birthday{Month: 5, Day: 3, Year: year(1995)}},
+		{raw: "25/12", want: &Birthday{Month: 12, Day: 25}},
+		{raw: "1995年5月3日", want: &Birthday{Month: 5, Day: 3, Year: year(1995)}},
+		{raw: "5月3日", want: &Birthday{Month: 5, Day: 3}},
+		{raw: "May 3", want: &Birthday{Month: 5, Day: 3}},
+		{raw: "September 21st, 1990", want: &Birthday{Month: 9, Day: 21, Year: year(1990)}},
+		{raw: "Sept. 21", want: &Birthday{Month: 9, Day: 21}},
+		{raw: "3rd of May", want: &Birthday{Month: 5, Day: 3}},
+		{raw: "21 September 1990", want: &Birthday{Month: 9, Day: 21, Year: year(1990)}},
+		{raw: "February 29", want: &Birthday{Month: 2, Day: 29}},
+		{raw: "2000-02-29", want: &Birthday{Month: 2, Day: 29, Year: year(2000)}},
+		{raw: "2001-02-29", wantErr: true},
+		{raw: "April 31", wantErr: true},
+		{raw: "13/13", wantErr: true},
+		{raw: "Smarch 3", wantErr: true},
+		{raw: "unknown", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseBirthday(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBirthday(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBirthday(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBloodType(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    BloodType
+		wantErr bool
+	}{
+		{raw: "A", want: BloodTypeA},
+		{raw: "b", want: BloodTypeB},
+		{raw: "Type AB", want: BloodTypeAB},
+		{raw: "Blood type: O", want: BloodTypeO},
+		{raw: "AB型", want: BloodTypeAB},
+		{raw: "O Rh+", want: BloodTypeO},
+		{raw: "A-", want: BloodTypeA},
+		{raw: "BA", want: BloodTypeAB},
+		{raw: "C", wantErr: true},
+		{raw: "unknown", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseBloodType(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBloodType(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBloodType(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHobbies(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{raw: "", want: nil},
+		{raw: " , ; ", want: nil},
+		{raw: "Reading", want: []string{"Reading"}},
+		{raw: "Reading, cooking; hiking / games", want: []string{"Reading", "cooking", "hiking", "games"}},
+		{raw: "読書、料理・散歩", want: []string{"読書", "料理", "散歩"}},
+		{raw: "Reading and cooking & hiking.", want: []string{"Reading", "cooking", "hiking"}},
+		{raw: "Sandwiches, cooking, Cooking, READING, reading", want: []string{"Sandwiches", "cooking", "READING"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if got := ParseHobbies(tt.raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHobbies(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package attributes
+
+type BloodType = string
+
+const (
+	BloodTypeA  BloodType = "A"
+	BloodTypeB  BloodType = "B"
+	BloodTypeAB BloodType = "AB"
+	BloodTypeO  BloodType = "O"
+)
+
+type Entity = string
+
+const (
+	EntityCharacter Entity = "character"
+	EntityStaff     Entity = "staff"
+)
+
+type Attribute = string
+
+const (
+	AttributeHeight    Attribute = "height"
+	AttributeWeight    Attribute = "weight"
+	AttributeBirthday  Attribute = "birthday"
+	AttributeBloodType Attribute = "blood_type"
+	AttributeHobbies   Attribute = "hobbies"
+)
+
+const (
+	resultParsed = "parsed"
+	resultFailed = "failed"
+)
+
+// Birthday is a month and day with an optional year, sources often leave
+// the year out.
+type Birthday struct {
+	Month int
+	Day   int
+	Year  *int
+}
@@ -0,0 +1,235 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/images"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/producer"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+type Backfill interface {
+	Run(ctx context.Context) (*Stats, error)
+}
+
+type BackfillImpl struct {
+	CharacterRepository anime_character.AnimeCharacterRepository
+	StaffRepository     anime_staff.AnimeStaffRepository
+	Options             Options
+	CharacterProducer   func(ctx context.Context, message *kafka.Message) error
+	StaffProducer       func(ctx context.Context, message *kafka.Message) error
+}
+
+func NewBackfill(opt Options, characterRepo anime_character.AnimeCharacterRepository, staffRepo anime_staff.AnimeStaffRepository, characterProducer func(ctx context.Context, message *kafka.Message) error, staffProducer func(ctx context.Context, message *kafka.Message) error) Backfill {
+	return &BackfillImpl{
+		CharacterRepository: characterRepo,
+		StaffRepository:     staffRepo,
+		Options:             opt,
+		CharacterProducer:   characterProducer,
+		StaffProducer:       staffProducer,
+	}
+}
+
+func (b *BackfillImpl) Run(ctx context.Context) (*Stats, error) {
+	log := logger.FromCtx(ctx)
+
+	if b.Options.MissingCDNImage && b.Options.CDNPrefix == "" {
+		return nil, fmt.Errorf("a cdn prefix is required to filter on missing cdn images")
+	}
+	if b.Options.BatchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive")
+	}
+
+	checkpoint := &Checkpoint{LastIDs: map[string]string{}, Done: map[string]bool{}}
+	if !b.Options.Reset {
+		loaded, err := loadCheckpoint(b.Options.CheckpointFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		checkpoint = loaded
+	}
+
+	keys := map[Entity]string{}
+	finished := true
+	for _, entity := range b.Options.Entities {
+		keys[entity] = checkpointKey(entity, b.Options)
+		finished = finished && checkpoint.Done[keys[entity]]
+	}
+	if finished {
+		// the previous run with these filters completed, this one starts over
+		for _, key := range keys {
+			delete(checkpoint.LastIDs, key)
+			delete(checkpoint.Done, key)
+		}
+	}
+
+	limit := rate.Inf
+	if b.Options.RatePerSecond > 0 {
+		limit = rate.Limit(b.Options.RatePerSecond)
+	}
+	limiter := rate.NewLimiter(limit, 1)
+
+	stats := &Stats{
+		Scanned:  map[Entity]int{},
+		Emitted:  map[Entity]int{},
+		Skipped:  map[Entity]int{},
+		Rejected: map[Entity]int{},
+	}
+
+	for _, entity := range b.Options.Entities {
+		key := keys[entity]
+		if checkpoint.Done[key] {
+			log.Info("Entity already backfilled, skipping", zap.String("entity", entity))
+			continue
+		}
+
+		log.Info("Backfilling images", zap.String("entity", entity), zap.String("after", checkpoint.LastIDs[key]))
+
+		var err error
+		switch entity {
+		case EntityCharacter:
+			err = b.backfillCharacters(ctx, limiter, checkpoint, key, stats)
+		case EntityStaff:
+			err = b.backfillStaff(ctx, limiter, checkpoint, key, stats)
+		default:
+			err = fmt.Errorf("unknown entity %q", entity)
+		}
+		if err != nil {
+			return stats, err
+		}
+
+		checkpoint.Done[key] = true
+		if err := saveCheckpoint(b.Options.CheckpointFile, checkpoint); err != nil {
+			return stats, fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+		log.Info("Finished backfilling images", zap.String("entity", entity),
+			zap.Int("scanned", stats.Scanned[entity]),
+			zap.Int("emitted", stats.Emitted[entity]),
+			zap.Int("skipped", stats.Skipped[entity]),
+			zap.Int("rejected", stats.Rejected[entity]))
+	}
+
+	return stats, nil
+}
+
+func (b *BackfillImpl) backfillCharacters(ctx context.Context, limiter *rate.Limiter, checkpoint *Checkpoint, key string, stats *Stats) error {
+	filter := anime_character.ListFilter{
+		AnimeID:      b.Options.AnimeID,
+		UpdatedSince: b.Options.UpdatedSince,
+	}
+	if b.Options.MissingCDNImage {
+		filter.ImageNotPrefix = b.Options.CDNPrefix
+	}
+
+	for {
+		characters, err := b.CharacterRepository.ListAfterID(ctx, checkpoint.LastIDs[key], b.Options.BatchSize, filter)
+		if err != nil {
+			return err
+		}
+		if len(characters) == 0 {
+			return nil
+		}
+
+		for _, character := range characters {
+			stats.Scanned[EntityCharacter]++
+			if character.Image == "" {
+				stats.Skipped[EntityCharacter]++
+				continue
+			}
+			imageURL, ok := b.Options.Images.Accept(ctx, images.EntityCharacter, character.ID, character.Image)
+			if !ok {
+				stats.Rejected[EntityCharacter]++
+				continue
+			}
+
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+			err := b.emit(ctx, b.CharacterProducer, producer.ImageSchema{
+				Name: character.Name,
+				URL:  imageURL,
+				Type: producer.DataTypeCharacter,
+			})
+			if err != nil {
+				return err
+			}
+			stats.Emitted[EntityCharacter]++
+		}
+
+		checkpoint.LastIDs[key] = characters[len(characters)-1].ID
+		if err := saveCheckpoint(b.Options.CheckpointFile, checkpoint); err != nil {
+			return fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+	}
+}
+
+func (b *BackfillImpl) backfillStaff(ctx context.Context, limiter *rate.Limiter, checkpoint *Checkpoint, key string, stats *Stats) error {
+	filter := anime_staff.ListFilter{
+		AnimeID:      b.Options.AnimeID,
+		UpdatedSince: b.Options.UpdatedSince,
+	}
+	if b.Options.MissingCDNImage {
+		filter.ImageNotPrefix = b.Options.CDNPrefix
+	}
+
+	for {
+		staff, err := b.StaffRepository.ListAfterID(ctx, checkpoint.LastIDs[key], b.Options.BatchSize, filter)
+		if err != nil {
+			return err
+		}
+		if len(staff) == 0 {
+			return nil
+		}
+
+		for _, member := range staff {
+			stats.Scanned[EntityStaff]++
+			if member.Image == "" {
+				stats.Skipped[EntityStaff]++
+				continue
+			}
+			imageURL, ok := b.Options.Images.Accept(ctx, images.EntityStaff, member.ID, member.Image)
+			if !ok {
+				stats.Rejected[EntityStaff]++
+				continue
+			}
+
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+			err := b.emit(ctx, b.StaffProducer, producer.ImageSchema{
+				Name: member.GivenName + "_" + member.FamilyName,
+				URL:  imageURL,
+				Type: producer.DataTypeStaff,
+			})
+			if err != nil {
+				return err
+			}
+			stats.Emitted[EntityStaff]++
+		}
+
+		checkpoint.LastIDs[key] = staff[len(staff)-1].ID
+		if err := saveCheckpoint(b.Options.CheckpointFile, checkpoint); err != nil {
+			return fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+	}
+}
+
+func (b *BackfillImpl) emit(ctx context.Context, produce func(ctx context.Context, message *kafka.Message) error, schema producer.ImageSchema) error {
+	payloadBytes, err := json.Marshal(producer.ImagePayload{Data: schema})
+	if err != nil {
+		return err
+	}
+
+	if err := produce(ctx, &kafka.Message{Value: payloadBytes}); err != nil {
+		logger.FromCtx(ctx).Error("Error sending message to producer", zap.String("name", schema.Name), zap.Error(err))
+		return err
+	}
+	return nil
+}
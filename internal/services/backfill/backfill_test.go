@@ -0,0 +1,71 @@
+package backfill_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/config"
+	migrations "github.com/weeb-vip/character-staff-sync/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/backfill"
+	"go.uber.org/zap"
+)
+
+func TestBackfillCheckpointPerFilters(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+	dir := t.TempDir()
+
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(dir, "backfill.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+	characters := anime_character.NewAnimeCharacterRepository(database)
+	for _, character := range []*anime_character.AnimeCharacter{
+		{ID: "a", AnimeID: "one", Name: "A", Role: "Main", Image: "https://example.com/a.jpg"},
+		{ID: "b", AnimeID: "two", Name: "B", Role: "Main", Image: "https://example.com/b.jpg"},
+	} {
+		if err := characters.Upsert(ctx, character); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	emitted := 0
+	run := func(animeID string) {
+		t.Helper()
+		produce := func(ctx context.Context, message *kafka.Message) error {
+			emitted++
+			return nil
+		}
+		b := backfill.NewBackfill(backfill.Options{
+			Entities:       []backfill.Entity{backfill.EntityCharacter},
+			AnimeID:        animeID,
+			BatchSize:      10,
+			CheckpointFile: filepath.Join(dir, "checkpoint.json"),
+		}, characters, anime_staff.NewAnimeStaffRepository(database), produce, produce)
+		if _, err := b.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		animeID string
+		want    int
+	}{
+		{name: "filtered run", animeID: "two", want: 1},
+		{name: "other filters start from the beginning", animeID: "", want: 2},
+		{name: "finished run starts over", animeID: "two", want: 1},
+	}
+	for _, tt := range tests {
+		emitted = 0
+		run(tt.animeID)
+		if emitted != tt.want {
+			t.Errorf("%s: emitted %d, want %d", tt.name, emitted, tt.want)
+		}
+	}
+}
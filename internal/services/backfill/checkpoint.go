@@ -0,0 +1,78 @@
+package backfill
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// checkpointKey identifies the progress of an entity under the filters of
+// the options, the entity followed by a hash of the filters.
+func checkpointKey(entity Entity, opt Options) string {
+	filters := struct {
+		AnimeID      string     `json:"anime_id"`
+		UpdatedSince *time.Time `json:"updated_since"`
+		CDNPrefix    string     `json:"cdn_prefix"`
+	}{
+		AnimeID:      opt.AnimeID,
+		UpdatedSince: opt.UpdatedSince,
+	}
+	if opt.MissingCDNImage {
+		filters.CDNPrefix = opt.CDNPrefix
+	}
+	data, _ := json.Marshal(filters)
+	hash := sha256.Sum256(data)
+	return entity + ":" + hex.EncodeToString(hash[:8])
+}
+
+func loadCheckpoint(path string) (*Checkpoint, error) {
+	checkpoint := &Checkpoint{
+		LastIDs: map[string]string{},
+		Done:    map[string]bool{},
+	}
+	if path == "" {
+		return checkpoint, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, err
+	}
+	if checkpoint.LastIDs == nil {
+		checkpoint.LastIDs = map[string]string{}
+	}
+	if checkpoint.Done == nil {
+		checkpoint.Done = map[string]bool{}
+	}
+	return checkpoint, nil
+}
+
+// saveCheckpoint writes to a temp file first so a crash never leaves a
+// truncated checkpoint behind.
+func saveCheckpoint(path string, checkpoint *Checkpoint) error {
+	if path == "" {
+		return nil
+	}
+
+	checkpoint.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,47 @@
+package backfill
+
+import (
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/images"
+)
+
+type Entity = string
+
+const (
+	EntityCharacter Entity = "character"
+	EntityStaff     Entity = "staff"
+)
+
+type Options struct {
+	Entities        []Entity
+	AnimeID         string
+	UpdatedSince    *time.Time
+	MissingCDNImage bool
+	CDNPrefix       string
+	BatchSize       int
+	RatePerSecond   float64
+	CheckpointFile  string
+	// Reset ignores an existing checkpoint and starts from the beginning.
+	Reset bool
+	// Images checks and normalizes the image urls before they are emitted,
+	// nil uses the default allowlist.
+	Images *images.Validator
+}
+
+// Checkpoint stores the last emitted id per entity and filters so an
+// interrupted run can pick up where it stopped. Both maps are keyed by
+// checkpointKey, a run with other filters starts from the beginning.
+type Checkpoint struct {
+	LastIDs   map[string]string `json:"last_ids"`
+	Done      map[string]bool   `json:"done"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+type Stats struct {
+	Scanned map[Entity]int
+	Emitted map[Entity]int
+	Skipped map[Entity]int
+	// Rejected counts the stored image urls that failed validation.
+	Rejected map[Entity]int
+}
@@ -0,0 +1,313 @@
+package cast_events
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Recorder takes the cast changes of the processors, link and staff changes
+// are resolved to the anime of the linked characters. Deletions have to be
+// recorded before the row is deleted so the anime can still be resolved,
+// they count once the transaction of the write committed.
+type Recorder interface {
+	// CharacterChanged resolves the anime of the stored character when
+	// animeID is empty, e.g. for delete images without the full row.
+	CharacterChanged(ctx context.Context, animeID string, characterID string, change ChangeType) error
+	LinkChanged(ctx context.Context, characterID string, staffID string, change ChangeType) error
+	StaffChanged(ctx context.Context, staffID string, change ChangeType) error
+}
+
+// Resolver finds the anime a character or staff member belongs to.
+type Resolver interface {
+	CharacterAnimeID(ctx context.Context, characterID string) (string, error)
+	StaffAnimeIDs(ctx context.Context, staffID string) ([]string, error)
+}
+
+type Options struct {
+	// Window is the quiet time after the last change of an anime before its
+	// event is emitted.
+	Window time.Duration
+	// MaxWait caps the delay after the first change, so an anime that keeps
+	// changing still gets events.
+	MaxWait time.Duration
+	// RetryInterval is the delay before an event that could not be sent is
+	// retried, it doubles with every failure up to MaxRetryInterval.
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+}
+
+const (
+	defaultRetryInterval    = time.Second
+	defaultMaxRetryInterval = time.Minute
+	// flushTimeout bounds the emits of Close, which cannot use the context of
+	// the pipeline since it is usually cancelled by then.
+	flushTimeout = 10 * time.Second
+)
+
+type pendingCast struct {
+	characters map[string]ChangeType
+	staff      map[string]ChangeType
+	first      time.Time
+	timer      *time.Timer
+	// attempts counts the failed emits, the changes wait for the retry then.
+	attempts int
+}
+
+// Aggregator groups changes by anime and emits one anime_cast_changed event
+// per anime once the debounce window passed. Events that cannot be sent are
+// retried with a backoff. Pending changes only live in memory, Close emits
+// them.
+type Aggregator struct {
+	ctx      context.Context
+	options  Options
+	resolver Resolver
+	producer func(ctx context.Context, message *kafka.Message) error
+	pending  map[string]*pendingCast
+	closed   bool
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+}
+
+var _ Recorder = &Aggregator{}
+
+func NewAggregator(ctx context.Context, options Options, resolver Resolver, producer func(ctx context.Context, message *kafka.Message) error) *Aggregator {
+	if options.RetryInterval <= 0 {
+		options.RetryInterval = defaultRetryInterval
+	}
+	if options.MaxRetryInterval < options.RetryInterval {
+		options.MaxRetryInterval = max(defaultMaxRetryInterval, options.RetryInterval)
+	}
+	return &Aggregator{
+		ctx:      ctx,
+		options:  options,
+		resolver: resolver,
+		producer: producer,
+		pending:  map[string]*pendingCast{},
+	}
+}
+
+func (a *Aggregator) CharacterChanged(ctx context.Context, animeID string, characterID string, change ChangeType) error {
+	if animeID == "" {
+		resolved, err := a.resolver.CharacterAnimeID(ctx, characterID)
+		if err != nil {
+			return err
+		}
+		animeID = resolved
+	}
+	if animeID == "" {
+		return nil
+	}
+	return a.record(ctx, animeID, func(p *pendingCast) {
+		record(p.characters, characterID, change)
+	})
+}
+
+func (a *Aggregator) LinkChanged(ctx context.Context, characterID string, staffID string, change ChangeType) error {
+	animeID, err := a.resolver.CharacterAnimeID(ctx, characterID)
+	if err != nil {
+		return err
+	}
+	if animeID == "" {
+		logger.FromCtx(ctx).Info("Skipping cast change of unknown character", zap.String("characterID", characterID))
+		return nil
+	}
+	return a.record(ctx, animeID, func(p *pendingCast) {
+		record(p.staff, staffID, change)
+	})
+}
+
+func (a *Aggregator) StaffChanged(ctx context.Context, staffID string, change ChangeType) error {
+	animeIDs, err := a.resolver.StaffAnimeIDs(ctx, staffID)
+	if err != nil {
+		return err
+	}
+	for _, animeID := range animeIDs {
+		err := a.record(ctx, animeID, func(p *pendingCast) {
+			record(p.staff, staffID, change)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record adds the change to the pending event of the anime once the write
+// committed, see db.AfterCommit, so a rolled back or retried write emits
+// nothing. The anime is resolved before, while the rows are still there.
+func (a *Aggregator) record(ctx context.Context, animeID string, apply func(p *pendingCast)) error {
+	return db.AfterCommit(ctx, func(ctx context.Context) error {
+		a.add(animeID, apply)
+		return nil
+	})
+}
+
+func (a *Aggregator) add(animeID string, apply func(p *pendingCast)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return
+	}
+
+	now := time.Now()
+	p, ok := a.pending[animeID]
+	if !ok {
+		p = &pendingCast{
+			characters: map[string]ChangeType{},
+			staff:      map[string]ChangeType{},
+			first:      now,
+		}
+		a.pending[animeID] = p
+	}
+	apply(p)
+	if p.attempts > 0 {
+		// the changes go out with the pending retry
+		return
+	}
+
+	delay := a.options.Window
+	if deadline := p.first.Add(a.options.MaxWait); a.options.MaxWait > 0 && now.Add(delay).After(deadline) {
+		delay = deadline.Sub(now)
+	}
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.timer = time.AfterFunc(delay, func() {
+		a.flush(animeID, p)
+	})
+}
+
+// flush emits the pending changes of the anime unless a later change
+// already replaced them.
+func (a *Aggregator) flush(animeID string, p *pendingCast) {
+	a.mu.Lock()
+	if a.pending[animeID] != p {
+		a.mu.Unlock()
+		return
+	}
+	delete(a.pending, animeID)
+	a.wg.Add(1)
+	a.mu.Unlock()
+
+	defer a.wg.Done()
+	if err := a.emit(a.ctx, animeID, p); err == nil || a.retry(animeID, p) {
+		return
+	}
+
+	// closed in the meantime, a last attempt that does not depend on the
+	// cancelled pipeline context
+	ctx, cancel := a.flushContext()
+	defer cancel()
+	_ = a.emit(ctx, animeID, p)
+}
+
+// retry puts the changes of an event that could not be sent back in front of
+// the changes recorded since and schedules them with an exponential backoff.
+// It returns false once the aggregator is closed.
+func (a *Aggregator) retry(animeID string, failed *pendingCast) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return false
+	}
+
+	failed.attempts++
+	if newer, ok := a.pending[animeID]; ok {
+		newer.timer.Stop()
+		for id, change := range newer.characters {
+			record(failed.characters, id, change)
+		}
+		for id, change := range newer.staff {
+			record(failed.staff, id, change)
+		}
+	}
+	a.pending[animeID] = failed
+
+	delay := a.options.RetryInterval
+	for i := 1; i < failed.attempts && delay < a.options.MaxRetryInterval; i++ {
+		delay *= 2
+	}
+	delay = min(delay, a.options.MaxRetryInterval)
+	logger.FromCtx(a.ctx).Warn("Retrying cast event", zap.String("animeID", animeID),
+		zap.Int("attempts", failed.attempts), zap.Duration("delay", delay))
+	failed.timer = time.AfterFunc(delay, func() {
+		a.flush(animeID, failed)
+	})
+	return true
+}
+
+func (a *Aggregator) emit(ctx context.Context, animeID string, p *pendingCast) error {
+	log := logger.FromCtx(a.ctx)
+
+	if len(p.characters) == 0 && len(p.staff) == 0 {
+		// every change cancelled out
+		return nil
+	}
+
+	payload, err := json.Marshal(Event{
+		Action:     EventAction,
+		AnimeID:    animeID,
+		Characters: summarize(p.characters),
+		Staff:      summarize(p.staff),
+	})
+	if err != nil {
+		log.Error("Error marshaling cast event", zap.Error(err))
+		metrics.CastEvents.WithLabelValues("failed").Inc()
+		// retrying cannot fix the payload
+		return nil
+	}
+
+	err = a.producer(ctx, &kafka.Message{
+		Key:   []byte(animeID),
+		Value: payload,
+	})
+	if err != nil {
+		log.Error("Error sending cast event", zap.String("animeID", animeID), zap.Error(err))
+		metrics.CastEvents.WithLabelValues("failed").Inc()
+		return err
+	}
+	metrics.CastEvents.WithLabelValues("sent").Inc()
+	return nil
+}
+
+// flushContext is a fresh context carrying the logger of the pipeline.
+func (a *Aggregator) flushContext() (context.Context, context.CancelFunc) {
+	ctx := logger.WithCtx(context.Background(), logger.FromCtx(a.ctx))
+	return context.WithTimeout(ctx, flushTimeout)
+}
+
+// Close stops the timers and emits every pending anime right away, in anime
+// id order, including the ones waiting for a retry.
+func (a *Aggregator) Close() {
+	a.mu.Lock()
+	a.closed = true
+	pending := a.pending
+	a.pending = map[string]*pendingCast{}
+	a.mu.Unlock()
+
+	animeIDs := make([]string, 0, len(pending))
+	for animeID, p := range pending {
+		p.timer.Stop()
+		animeIDs = append(animeIDs, animeID)
+	}
+	sort.Strings(animeIDs)
+
+	ctx, cancel := a.flushContext()
+	defer cancel()
+	for _, animeID := range animeIDs {
+		_ = a.emit(ctx, animeID, pending[animeID])
+	}
+
+	a.wg.Wait()
+}
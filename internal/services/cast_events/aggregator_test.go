@@ -0,0 +1,63 @@
+package cast_events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+// sent collects the events of an aggregator.
+type sent struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (s *sent) produce(ctx context.Context, message *kafka.Message) error {
+	var event Event
+	if err := json.Unmarshal(message.Value, &event); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestAggregatorRecordsAfterCommit(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+	events := &sent{}
+	aggregator := NewAggregator(ctx, Options{Window: time.Hour}, nil, events.produce)
+
+	// a rolled back write never calls commit
+	rolledBack, _ := db.WithAfterCommit(ctx)
+	if err := aggregator.CharacterChanged(rolledBack, "anime-1", "character-1", ChangeAdded); err != nil {
+		t.Fatal(err)
+	}
+
+	committed, commit := db.WithAfterCommit(ctx)
+	if err := aggregator.CharacterChanged(committed, "anime-2", "character-2", ChangeUpdated); err != nil {
+		t.Fatal(err)
+	}
+	if pending := len(aggregator.pending); pending != 0 {
+		t.Fatalf("%d anime pending before the commit, want none", pending)
+	}
+	if err := commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	aggregator.Close()
+	if len(events.events) != 1 {
+		t.Fatalf("emitted %+v, want one event", events.events)
+	}
+	event := events.events[0]
+	if event.AnimeID != "anime-2" || len(event.Characters.Updated) != 1 || event.Characters.Updated[0] != "character-2" {
+		t.Errorf("emitted %+v, want character-2 updated in anime-2", event)
+	}
+}
@@ -0,0 +1,52 @@
+package cast_events
+
+import (
+	"sort"
+)
+
+// merge folds a new change of an id into the pending one. An id added and
+// removed within the window cancels out, keep is false then.
+func merge(pending ChangeType, next ChangeType) (result ChangeType, keep bool) {
+	switch {
+	case pending == "":
+		return next, true
+	case pending == ChangeAdded && next == ChangeRemoved:
+		return "", false
+	case pending == ChangeAdded:
+		return ChangeAdded, true
+	case pending == ChangeRemoved && next == ChangeRemoved:
+		return ChangeRemoved, true
+	case pending == ChangeRemoved:
+		// removed and added again is a change of the existing entry
+		return ChangeUpdated, true
+	default:
+		return next, true
+	}
+}
+
+func record(changes map[string]ChangeType, id string, change ChangeType) {
+	result, keep := merge(changes[id], change)
+	if !keep {
+		delete(changes, id)
+		return
+	}
+	changes[id] = result
+}
+
+func summarize(changes map[string]ChangeType) Summary {
+	summary := Summary{Added: []string{}, Removed: []string{}, Updated: []string{}}
+	for id, change := range changes {
+		switch change {
+		case ChangeAdded:
+			summary.Added = append(summary.Added, id)
+		case ChangeRemoved:
+			summary.Removed = append(summary.Removed, id)
+		default:
+			summary.Updated = append(summary.Updated, id)
+		}
+	}
+	sort.Strings(summary.Added)
+	sort.Strings(summary.Removed)
+	sort.Strings(summary.Updated)
+	return summary
+}
@@ -0,0 +1,35 @@
+package cast_events
+
+import (
+	"context"
+	"errors"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"gorm.io/gorm"
+)
+
+type repositoryResolver struct {
+	characters anime_character.AnimeCharacterRepository
+	links      anime_character_staff_link.AnimeCharacterStaffLinkRepository
+}
+
+func NewResolver(characters anime_character.AnimeCharacterRepository, links anime_character_staff_link.AnimeCharacterStaffLinkRepository) Resolver {
+	return &repositoryResolver{characters: characters, links: links}
+}
+
+// CharacterAnimeID returns an empty id for characters that are not synced.
+func (r *repositoryResolver) CharacterAnimeID(ctx context.Context, characterID string) (string, error) {
+	character, err := r.characters.FindByID(ctx, characterID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return character.AnimeID, nil
+}
+
+func (r *repositoryResolver) StaffAnimeIDs(ctx context.Context, staffID string) ([]string, error) {
+	return r.links.ListAnimeIDsByStaff(ctx, staffID)
+}
@@ -0,0 +1,27 @@
+package cast_events
+
+const EventAction = "anime_cast_changed"
+
+type ChangeType = string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeUpdated ChangeType = "updated"
+)
+
+// Summary lists the ids changed within the debounce window, every id is in
+// at most one list.
+type Summary struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Updated []string `json:"updated"`
+}
+
+// Event is the aggregate emitted once per anime and debounce window.
+type Event struct {
+	Action     string  `json:"action"`
+	AnimeID    string  `json:"anime_id"`
+	Characters Summary `json:"characters"`
+	Staff      Summary `json:"staff"`
+}
@@ -3,17 +3,47 @@ package character_processor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/ThatCatDev/ep/v2/event"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
 	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_redirect"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/field_source"
+	"github.com/weeb-vip/character-staff-sync/internal/debezium"
+	"github.com/weeb-vip/character-staff-sync/internal/images"
 	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
 	"github.com/weeb-vip/character-staff-sync/internal/producer"
+	"github.com/weeb-vip/character-staff-sync/internal/services/attributes"
+	"github.com/weeb-vip/character-staff-sync/internal/services/cast_events"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"github.com/weeb-vip/character-staff-sync/internal/services/image_dedupe"
+	"github.com/weeb-vip/character-staff-sync/internal/services/sources"
 	"go.uber.org/zap"
-	"time"
+	"gorm.io/gorm"
 )
 
 type Options struct {
 	NoErrorOnDelete bool
+	// CastChanges, when set, is told about every written character.
+	CastChanges cast_events.Recorder
+	// Redirects, when set, rewrites the ids of merged characters to the
+	// character they were merged into.
+	Redirects entity_redirect.Resolver
+	// Sources, when set, keeps the fields owned by a source with a higher
+	// priority than the source of the pipeline.
+	Sources sources.Guard
+	// History, when set, records every applied change.
+	History history.Recorder
+
+	// Images checks and normalizes the image urls sent to image-sync, nil
+	// uses the default allowlist.
+	Images *images.Validator
+	// ImageRequests, when set, skips images already sent for the entity.
+	ImageRequests image_dedupe.Cache
 }
 
 type CharacterProcessor interface {
@@ -39,20 +69,64 @@ func (p *CharacterProcessorImpl) Process(ctx context.Context, data event.Event[*
 
 	payload := data.Payload
 
+	redirected, err := p.followRedirects(ctx, &payload)
+	if err != nil {
+		return data, err
+	}
+	if redirected && payload.After == nil {
+		// the character it was merged into is still live
+		log.Info("Skipping delete of merged character", zap.String("ID", payload.Before.Id))
+		metrics.Redirects.WithLabelValues(entity_redirect.EntityTypeCharacter, "delete").Inc()
+		return data, nil
+	}
+	if redirected {
+		metrics.Redirects.WithLabelValues(entity_redirect.EntityTypeCharacter, "upsert").Inc()
+	}
+
 	if payload.Before == nil && payload.After != nil {
 		newChar, err := p.parseToEntity(ctx, *payload.After)
 		if err != nil {
 			return data, err
 		}
-		if err := p.Repository.Upsert(newChar); err != nil {
+		_, claimed, err := p.protect(ctx, newChar)
+		if err != nil {
+			return data, err
+		}
+		// snapshot re-reads and replays create rows that are already stored
+		changed, _, err := p.changes(ctx, newChar, nil)
+		if err != nil {
+			return data, err
+		}
+		if len(changed) == 0 {
+			log.Info("Skipping unchanged character", zap.String("ID", newChar.ID))
+			return data, nil
+		}
+		before, err := p.historyBefore(ctx, newChar.ID)
+		if err != nil {
+			return data, err
+		}
+		if err := p.Repository.Upsert(ctx, newChar); err != nil {
+			return data, err
+		}
+		if err := p.recordHistory(ctx, data, before, newChar); err != nil {
+			return data, err
+		}
+		if err := p.claim(ctx, newChar, claimed); err != nil {
+			return data, err
+		}
+		if err := p.recordCast(ctx, newChar.AnimeID, newChar.ID, cast_events.ChangeAdded); err != nil {
 			return data, err
 		}
 
+		imageURL, sendImage := "", false
 		if payload.After.Image != nil {
+			imageURL, sendImage = p.Options.Images.Accept(ctx, images.EntityCharacter, newChar.ID, newChar.Image)
+		}
+		if sendImage {
 			imagePayload := producer.ImagePayload{
 				Data: producer.ImageSchema{
-					Name: *payload.After.Name,
-					URL:  *payload.After.Image,
+					Name: newChar.Name,
+					URL:  imageURL,
 					Type: producer.DataTypeCharacter,
 				},
 			}
@@ -64,8 +138,14 @@ func (p *CharacterProcessorImpl) Process(ctx context.Context, data event.Event[*
 			}
 
 			if p.KafkaProducer != nil {
-				err = p.KafkaProducer(ctx, &kafka.Message{
-					Value: payloadBytes,
+				key := image_dedupe.NewKey(images.EntityCharacter, newChar.ID, imageURL)
+				// sent once the write committed, a rolled back write requests nothing
+				err = db.AfterCommit(ctx, func(ctx context.Context) error {
+					return image_dedupe.Send(ctx, p.Options.ImageRequests, key, func(ctx context.Context) error {
+						return p.KafkaProducer(ctx, &kafka.Message{
+							Value: payloadBytes,
+						})
+					})
 				})
 				if err != nil {
 					log.Error("Error sending message to Kafka producer", zap.Error(err))
@@ -80,13 +160,40 @@ func (p *CharacterProcessorImpl) Process(ctx context.Context, data event.Event[*
 		if err != nil {
 			return data, err
 		}
-		if err := p.Repository.Delete(oldChar); err != nil {
+		if p.Options.Sources != nil {
+			owner, err := p.Options.Sources.Outranked(ctx, field_source.EntityTypeCharacter, oldChar.ID)
+			if err != nil {
+				return data, err
+			}
+			if owner != nil {
+				log.Info("Skipping delete of character owned by a higher priority source",
+					zap.String("ID", oldChar.ID), zap.String("owner", owner.Source))
+				metrics.ProtectedDeletes.WithLabelValues(field_source.EntityTypeCharacter, p.Options.Sources.Source().Name).Inc()
+				return data, nil
+			}
+		}
+		if err := p.recordCast(ctx, oldChar.AnimeID, oldChar.ID, cast_events.ChangeRemoved); err != nil {
+			return data, err
+		}
+		before, err := p.historyBefore(ctx, oldChar.ID)
+		if err != nil {
+			return data, err
+		}
+		if err := p.Repository.Delete(ctx, oldChar); err != nil {
 			if p.Options.NoErrorOnDelete {
 				log.Warn("WARN: error deleting from db:", zap.Error(err))
 				return data, nil
 			}
 			return data, err
 		}
+		if p.Options.Sources != nil {
+			if err := p.Options.Sources.Release(ctx, field_source.EntityTypeCharacter, oldChar.ID); err != nil {
+				return data, err
+			}
+		}
+		if err := p.recordHistory(ctx, data, before, nil); err != nil {
+			return data, err
+		}
 
 		producerPayload := ProducerPayload{
 			Action: DeleteAction,
@@ -100,8 +207,10 @@ func (p *CharacterProcessorImpl) Process(ctx context.Context, data event.Event[*
 		}
 
 		if p.KafkaProducer != nil {
-			err = p.KafkaProducer(ctx, &kafka.Message{
-				Value: payloadBytes,
+			err = db.AfterCommit(ctx, func(ctx context.Context) error {
+				return p.KafkaProducer(ctx, &kafka.Message{
+					Value: payloadBytes,
+				})
 			})
 			if err != nil {
 				log.Error("Error sending message to Kafka producer", zap.Error(err))
@@ -116,13 +225,51 @@ func (p *CharacterProcessorImpl) Process(ctx context.Context, data event.Event[*
 		if err != nil {
 			return data, err
 		}
-		if err := p.Repository.Upsert(newChar); err != nil {
+		protected, claimed, err := p.protect(ctx, newChar)
+		if err != nil {
+			return data, err
+		}
+		changed, previous, err := p.changes(ctx, newChar, payload.Before)
+		if err != nil {
+			return data, err
+		}
+		if len(changed) == 0 {
+			log.Info("Skipping unchanged character", zap.String("ID", newChar.ID))
+			return data, nil
+		}
+		before, err := p.historyBefore(ctx, newChar.ID)
+		if err != nil {
+			return data, err
+		}
+		if err := p.Repository.Upsert(ctx, newChar); err != nil {
+			return data, err
+		}
+		if err := p.recordHistory(ctx, data, before, newChar); err != nil {
+			return data, err
+		}
+		if err := p.claim(ctx, newChar, claimed); err != nil {
+			return data, err
+		}
+		if previousAnimeID, moved := previous["anime_id"].(string); moved {
+			// moved to another anime, it left the cast of the previous one
+			if err := p.recordCast(ctx, previousAnimeID, newChar.ID, cast_events.ChangeRemoved); err != nil {
+				return data, err
+			}
+			err = p.recordCast(ctx, newChar.AnimeID, newChar.ID, cast_events.ChangeAdded)
+		} else {
+			err = p.recordCast(ctx, newChar.AnimeID, newChar.ID, cast_events.ChangeUpdated)
+		}
+		if err != nil {
 			return data, err
 		}
 
 		producerPayload := ProducerPayload{
-			Action: UpdateAction,
-			Data:   payload.After,
+			Action:        UpdateAction,
+			Data:          written(payload.After, newChar, protected),
+			ChangedFields: changed,
+			Previous:      previous,
+			Aliases:       newAliases(newChar.Aliases),
+			Protected:     protected,
 		}
 
 		payloadBytes, err := json.Marshal(producerPayload)
@@ -132,8 +279,10 @@ func (p *CharacterProcessorImpl) Process(ctx context.Context, data event.Event[*
 		}
 
 		if p.KafkaProducer != nil {
-			err = p.KafkaProducer(ctx, &kafka.Message{
-				Value: payloadBytes,
+			err = db.AfterCommit(ctx, func(ctx context.Context) error {
+				return p.KafkaProducer(ctx, &kafka.Message{
+					Value: payloadBytes,
+				})
 			})
 			if err != nil {
 				log.Error("Error sending message to Kafka producer", zap.Error(err))
@@ -149,8 +298,221 @@ func (p *CharacterProcessorImpl) Process(ctx context.Context, data event.Event[*
 	return data, nil
 }
 
+// followRedirects rewrites the ids of the before and after images when the
+// character was merged into another one.
+func (p *CharacterProcessorImpl) followRedirects(ctx context.Context, payload *Payload) (bool, error) {
+	if p.Options.Redirects == nil {
+		return false, nil
+	}
+
+	redirected := false
+	for _, row := range []**Schema{&payload.Before, &payload.After} {
+		if *row == nil {
+			continue
+		}
+		id, err := p.Options.Redirects.Resolve(ctx, entity_redirect.EntityTypeCharacter, (*row).Id)
+		if err != nil {
+			return false, err
+		}
+		if id != (*row).Id {
+			rewritten := **row
+			rewritten.Id = id
+			*row = &rewritten
+			redirected = true
+		}
+	}
+	return redirected, nil
+}
+
+// protect keeps the stored values of the fields owned by a source with a
+// higher priority than the source of the pipeline. It returns the protected
+// fields and the fields the character changes, all of them when it is not
+// stored yet, which the source claims once the character is written.
+func (p *CharacterProcessorImpl) protect(ctx context.Context, next *anime_character.AnimeCharacter) ([]string, []string, error) {
+	if p.Options.Sources == nil {
+		return nil, nil, nil
+	}
+	stored, err := p.Repository.FindByID(ctx, next.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		changed, _, err := diff(nil, next)
+		return nil, changed, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed, _, err := diff(stored, next)
+	if err != nil {
+		return nil, nil, err
+	}
+	protected, err := p.Options.Sources.Protect(ctx, field_source.EntityTypeCharacter, next.ID, stored, next, changed)
+	if err != nil || len(protected) == 0 {
+		return nil, changed, err
+	}
+
+	source := p.Options.Sources.Source().Name
+	for _, field := range protected {
+		if field == aliasesField {
+			next.Aliases = nil
+		}
+		metrics.ProtectedFields.WithLabelValues(field_source.EntityTypeCharacter, source, field).Inc()
+	}
+	// the derived columns follow the restored values
+	attributes.NormalizeCharacter(ctx, next)
+	logger.FromCtx(ctx).Info("Keeping fields owned by a higher priority source",
+		zap.String("ID", next.ID), zap.String("source", source), zap.Strings("fields", protected))
+
+	changed, _, err = diff(stored, next)
+	return protected, changed, err
+}
+
+// claim records the source of the pipeline as the owner of the written
+// fields.
+func (p *CharacterProcessorImpl) claim(ctx context.Context, character *anime_character.AnimeCharacter, fields []string) error {
+	if p.Options.Sources == nil {
+		return nil
+	}
+	return p.Options.Sources.Claim(ctx, field_source.EntityTypeCharacter, character.ID, character, fields)
+}
+
+// historyBefore returns the stored character the history records as the state
+// before a write, nil when the history is disabled or it is not stored.
+func (p *CharacterProcessorImpl) historyBefore(ctx context.Context, id string) (*anime_character.AnimeCharacter, error) {
+	if p.Options.History == nil {
+		return nil, nil
+	}
+	stored, err := p.Repository.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return stored, err
+}
+
+// recordHistory records the write of the message, the newer of before and
+// after identifies the character.
+func (p *CharacterProcessorImpl) recordHistory(ctx context.Context, data event.Event[*kafka.Message, Payload], before *anime_character.AnimeCharacter, after *anime_character.AnimeCharacter) error {
+	if p.Options.History == nil {
+		return nil
+	}
+	source := history.Source{
+		TxID: int64(data.Payload.Source.TxId),
+		Lsn:  int64(data.Payload.Source.Lsn),
+		TsMs: data.Payload.Source.TsMs,
+	}
+	change := history.Change{
+		EntityType: entity_history.EntityTypeCharacter,
+		Before:     before,
+		After:      after,
+		Message:    data.DriverMessage,
+		Source:     source,
+	}
+	for _, row := range []*anime_character.AnimeCharacter{before, after} {
+		if row != nil {
+			change.EntityID, change.AnimeID = row.ID, row.AnimeID
+		}
+	}
+	return p.Options.History.Record(ctx, change)
+}
+
+func (p *CharacterProcessorImpl) recordCast(ctx context.Context, animeID string, characterID string, change cast_events.ChangeType) error {
+	if p.Options.CastChanges == nil {
+		return nil
+	}
+	return p.Options.CastChanges.CharacterChanged(ctx, animeID, characterID, change)
+}
+
+// changes compares the character with the stored one, see db.StoredChanges.
+func (p *CharacterProcessorImpl) changes(ctx context.Context, next *anime_character.AnimeCharacter, before *Schema) ([]string, map[string]interface{}, error) {
+	var previous *anime_character.AnimeCharacter
+	if before != nil {
+		var err error
+		previous, err = p.parseToEntity(ctx, *before)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	find := func() (*anime_character.AnimeCharacter, error) {
+		return p.Repository.FindByID(ctx, next.ID)
+	}
+	return db.StoredChanges(next, previous, find, diff)
+}
+
+// diff compares the columns and, when the source sent aliases, the aliases.
+// A nil previous character reports every column as changed.
+func diff(previous *anime_character.AnimeCharacter, next *anime_character.AnimeCharacter) ([]string, map[string]interface{}, error) {
+	changed, values, err := db.Changes(previous, next)
+	if err != nil {
+		return nil, nil, err
+	}
+	if previous == nil {
+		if len(next.Aliases) > 0 {
+			changed = append(changed, aliasesField)
+		}
+		return changed, values, nil
+	}
+	if entity_alias.Changed(previous.Aliases, next.Aliases) {
+		changed = append(changed, aliasesField)
+		values[aliasesField] = newAliases(previous.Aliases)
+	}
+	return changed, values, nil
+}
+
+// written is the after image with the protected fields set to the stored
+// values that were kept, the character as it was written.
+func written(after *Schema, character *anime_character.AnimeCharacter, protected []string) *Schema {
+	if len(protected) == 0 {
+		return after
+	}
+	row := *after
+	for _, field := range protected {
+		switch field {
+		case "anime_id":
+			row.AnimeID = &character.AnimeID
+		case "name":
+			row.Name = &character.Name
+		case "role":
+			row.Role = &character.Role
+		case "birthday":
+			row.Birthday = &character.Birthday
+		case "zodiac":
+			row.Zodiac = &character.Zodiac
+		case "gender":
+			row.Gender = &character.Gender
+		case "race":
+			row.Race = &character.Race
+		case "height":
+			row.Height = &character.Height
+		case "weight":
+			row.Weight = &character.Weight
+		case "title":
+			row.Title = &character.Title
+		case "martial_status":
+			row.MartialStatus = &character.MartialStatus
+		case "summary":
+			row.Summary = &character.Summary
+		case "image":
+			row.Image = &character.Image
+		case aliasesField:
+			// the stored aliases are sent in Aliases
+			row.NameNative, row.NameRomaji, row.NameEnglish, row.Nicknames = nil, nil, nil, nil
+		}
+	}
+	return &row
+}
+
+func newAliases(aliases []entity_alias.EntityAlias) []Alias {
+	if len(aliases) == 0 {
+		return nil
+	}
+	result := make([]Alias, len(aliases))
+	for i, alias := range aliases {
+		result[i] = Alias{Language: alias.Language, Kind: alias.Kind, Name: alias.Name}
+	}
+	return result
+}
+
 func (p *CharacterProcessorImpl) parseToEntity(ctx context.Context, data Schema) (*anime_character.AnimeCharacter, error) {
-	return &anime_character.AnimeCharacter{
+	character := &anime_character.AnimeCharacter{
 		ID:            data.Id,
 		AnimeID:       ptrToString(data.AnimeID),
 		Name:          ptrToString(data.Name),
@@ -165,9 +527,12 @@ func (p *CharacterProcessorImpl) parseToEntity(ctx context.Context, data Schema)
 		MartialStatus: ptrToString(data.MartialStatus),
 		Summary:       ptrToString(data.Summary),
 		Image:         ptrToString(data.Image),
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}, nil
+		CreatedAt:     debezium.CreatedAt(data.CreatedAt),
+		UpdatedAt:     debezium.UpdatedAt(data.UpdatedAt),
+		Aliases:       entity_alias.Build(entity_alias.LanguageJapanese, data.NameNative, data.NameRomaji, data.NameEnglish, data.Nicknames),
+	}
+	attributes.NormalizeCharacter(ctx, character)
+	return character, nil
 }
 
 func ptrToString(s *string) string {
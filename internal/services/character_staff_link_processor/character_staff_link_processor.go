@@ -3,16 +3,36 @@ package character_staff_link_processor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/ThatCatDev/ep/v2/event"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
 	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_redirect"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/field_source"
+	"github.com/weeb-vip/character-staff-sync/internal/debezium"
 	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
+	"github.com/weeb-vip/character-staff-sync/internal/services/cast_events"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"github.com/weeb-vip/character-staff-sync/internal/services/sources"
 	"go.uber.org/zap"
-	"time"
+	"gorm.io/gorm"
 )
 
 type Options struct {
 	NoErrorOnDelete bool
+	// CastChanges, when set, is told about every written link.
+	CastChanges cast_events.Recorder
+	// Redirects, when set, rewrites links of merged characters and staff to
+	// the entities they were merged into.
+	Redirects entity_redirect.Resolver
+	// Sources, when set, keeps the fields owned by a source with a higher
+	// priority than the source of the pipeline.
+	Sources sources.Guard
+	// History, when set, records every applied change.
+	History history.Recorder
 }
 
 type CharacterStaffLinkProcessor interface {
@@ -38,18 +58,48 @@ func (p *CharacterStaffLinkProcessorImpl) Process(ctx context.Context, data even
 
 	payload := data.Payload
 
+	if err := p.followRedirects(ctx, &payload); err != nil {
+		return data, err
+	}
+
 	if payload.Before == nil && payload.After != nil {
 		newLink, err := p.parseToEntity(ctx, *payload.After)
 		if err != nil {
 			return data, err
 		}
-		if err := p.Repository.Upsert(newLink); err != nil {
+		protected, claimed, err := p.protect(ctx, newLink)
+		if err != nil {
+			return data, err
+		}
+		// snapshot re-reads and replays create rows that are already stored
+		changed, _, err := p.changes(ctx, newLink, nil)
+		if err != nil {
+			return data, err
+		}
+		if len(changed) == 0 {
+			log.Info("Skipping unchanged link", zap.String("ID", newLink.ID))
+			return data, nil
+		}
+		before, err := p.historyBefore(ctx, newLink.ID)
+		if err != nil {
+			return data, err
+		}
+		if err := p.Repository.Upsert(ctx, newLink); err != nil {
+			return data, err
+		}
+		if err := p.recordHistory(ctx, data, before, newLink); err != nil {
+			return data, err
+		}
+		if err := p.claim(ctx, newLink, claimed); err != nil {
+			return data, err
+		}
+		if err := p.recordCast(ctx, newLink, cast_events.ChangeAdded); err != nil {
 			return data, err
 		}
 
 		producerPayload := ProducerPayload{
 			Action: CreateAction,
-			Data:   payload.After,
+			Data:   written(payload.After, newLink, protected),
 		}
 
 		payloadBytes, err := json.Marshal(producerPayload)
@@ -59,8 +109,10 @@ func (p *CharacterStaffLinkProcessorImpl) Process(ctx context.Context, data even
 		}
 
 		if p.KafkaProducer != nil {
-			err = p.KafkaProducer(ctx, &kafka.Message{
-				Value: payloadBytes,
+			err = db.AfterCommit(ctx, func(ctx context.Context) error {
+				return p.KafkaProducer(ctx, &kafka.Message{
+					Value: payloadBytes,
+				})
 			})
 			if err != nil {
 				log.Error("Error sending message to Kafka producer", zap.Error(err))
@@ -74,13 +126,40 @@ func (p *CharacterStaffLinkProcessorImpl) Process(ctx context.Context, data even
 		if err != nil {
 			return data, err
 		}
-		if err := p.Repository.Delete(oldLink); err != nil {
+		if p.Options.Sources != nil {
+			owner, err := p.Options.Sources.Outranked(ctx, field_source.EntityTypeLink, oldLink.ID)
+			if err != nil {
+				return data, err
+			}
+			if owner != nil {
+				log.Info("Skipping delete of link owned by a higher priority source",
+					zap.String("ID", oldLink.ID), zap.String("owner", owner.Source))
+				metrics.ProtectedDeletes.WithLabelValues(field_source.EntityTypeLink, p.Options.Sources.Source().Name).Inc()
+				return data, nil
+			}
+		}
+		if err := p.recordCast(ctx, oldLink, cast_events.ChangeRemoved); err != nil {
+			return data, err
+		}
+		before, err := p.historyBefore(ctx, oldLink.ID)
+		if err != nil {
+			return data, err
+		}
+		if err := p.Repository.Delete(ctx, oldLink); err != nil {
 			if p.Options.NoErrorOnDelete {
 				log.Warn("WARN: error deleting from db:", zap.Error(err))
 				return data, nil
 			}
 			return data, err
 		}
+		if p.Options.Sources != nil {
+			if err := p.Options.Sources.Release(ctx, field_source.EntityTypeLink, oldLink.ID); err != nil {
+				return data, err
+			}
+		}
+		if err := p.recordHistory(ctx, data, before, nil); err != nil {
+			return data, err
+		}
 
 		producerPayload := ProducerPayload{
 			Action: DeleteAction,
@@ -94,8 +173,10 @@ func (p *CharacterStaffLinkProcessorImpl) Process(ctx context.Context, data even
 		}
 
 		if p.KafkaProducer != nil {
-			err = p.KafkaProducer(ctx, &kafka.Message{
-				Value: payloadBytes,
+			err = db.AfterCommit(ctx, func(ctx context.Context) error {
+				return p.KafkaProducer(ctx, &kafka.Message{
+					Value: payloadBytes,
+				})
 			})
 			if err != nil {
 				log.Error("Error sending message to Kafka producer", zap.Error(err))
@@ -110,13 +191,41 @@ func (p *CharacterStaffLinkProcessorImpl) Process(ctx context.Context, data even
 		if err != nil {
 			return data, err
 		}
-		if err := p.Repository.Upsert(newLink); err != nil {
+		protected, claimed, err := p.protect(ctx, newLink)
+		if err != nil {
+			return data, err
+		}
+		changed, previous, err := p.changes(ctx, newLink, payload.Before)
+		if err != nil {
+			return data, err
+		}
+		if len(changed) == 0 {
+			log.Info("Skipping unchanged link", zap.String("ID", newLink.ID))
+			return data, nil
+		}
+		before, err := p.historyBefore(ctx, newLink.ID)
+		if err != nil {
+			return data, err
+		}
+		if err := p.Repository.Upsert(ctx, newLink); err != nil {
+			return data, err
+		}
+		if err := p.recordHistory(ctx, data, before, newLink); err != nil {
+			return data, err
+		}
+		if err := p.claim(ctx, newLink, claimed); err != nil {
+			return data, err
+		}
+		if err := p.recordCast(ctx, newLink, cast_events.ChangeUpdated); err != nil {
 			return data, err
 		}
 
 		producerPayload := ProducerPayload{
-			Action: UpdateAction,
-			Data:   payload.After,
+			Action:        UpdateAction,
+			Data:          written(payload.After, newLink, protected),
+			ChangedFields: changed,
+			Previous:      previous,
+			Protected:     protected,
 		}
 
 		payloadBytes, err := json.Marshal(producerPayload)
@@ -126,8 +235,10 @@ func (p *CharacterStaffLinkProcessorImpl) Process(ctx context.Context, data even
 		}
 
 		if p.KafkaProducer != nil {
-			err = p.KafkaProducer(ctx, &kafka.Message{
-				Value: payloadBytes,
+			err = db.AfterCommit(ctx, func(ctx context.Context) error {
+				return p.KafkaProducer(ctx, &kafka.Message{
+					Value: payloadBytes,
+				})
 			})
 			if err != nil {
 				log.Error("Error sending message to Kafka producer", zap.Error(err))
@@ -143,6 +254,188 @@ func (p *CharacterStaffLinkProcessorImpl) Process(ctx context.Context, data even
 	return data, nil
 }
 
+// followRedirects rewrites the character and staff ids of the before and
+// after images that point at merged entities.
+func (p *CharacterStaffLinkProcessorImpl) followRedirects(ctx context.Context, payload *Payload) error {
+	if p.Options.Redirects == nil {
+		return nil
+	}
+
+	for _, row := range []**Schema{&payload.Before, &payload.After} {
+		if *row == nil {
+			continue
+		}
+		characterID, err := p.Options.Redirects.Resolve(ctx, entity_redirect.EntityTypeCharacter, (*row).CharacterID)
+		if err != nil {
+			return err
+		}
+		staffID, err := p.Options.Redirects.Resolve(ctx, entity_redirect.EntityTypeStaff, (*row).StaffID)
+		if err != nil {
+			return err
+		}
+		if characterID != (*row).CharacterID || staffID != (*row).StaffID {
+			rewritten := **row
+			rewritten.CharacterID = characterID
+			rewritten.StaffID = staffID
+			*row = &rewritten
+		}
+	}
+	return nil
+}
+
+// protect keeps the stored values of the fields owned by a source with a
+// higher priority than the source of the pipeline. It returns the protected
+// fields and the fields the link changes, all of them when it is not stored
+// yet, which the source claims once the link is written.
+func (p *CharacterStaffLinkProcessorImpl) protect(ctx context.Context, next *anime_character_staff_link.AnimeCharacterStaffLink) ([]string, []string, error) {
+	if p.Options.Sources == nil {
+		return nil, nil, nil
+	}
+	stored, err := p.Repository.FindByID(ctx, next.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		changed, _, err := db.Changes(nil, next)
+		return nil, changed, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed, _, err := db.Changes(stored, next)
+	if err != nil {
+		return nil, nil, err
+	}
+	protected, err := p.Options.Sources.Protect(ctx, field_source.EntityTypeLink, next.ID, stored, next, changed)
+	if err != nil || len(protected) == 0 {
+		return nil, changed, err
+	}
+
+	source := p.Options.Sources.Source().Name
+	for _, field := range protected {
+		metrics.ProtectedFields.WithLabelValues(field_source.EntityTypeLink, source, field).Inc()
+	}
+	logger.FromCtx(ctx).Info("Keeping fields owned by a higher priority source",
+		zap.String("ID", next.ID), zap.String("source", source), zap.Strings("fields", protected))
+
+	changed, _, err = db.Changes(stored, next)
+	return protected, changed, err
+}
+
+// claim records the source of the pipeline as the owner of the written
+// fields.
+func (p *CharacterStaffLinkProcessorImpl) claim(ctx context.Context, link *anime_character_staff_link.AnimeCharacterStaffLink, fields []string) error {
+	if p.Options.Sources == nil {
+		return nil
+	}
+	return p.Options.Sources.Claim(ctx, field_source.EntityTypeLink, link.ID, link, fields)
+}
+
+// historyBefore returns the stored link the history records as the state
+// before a write, nil when the history is disabled or it is not stored.
+func (p *CharacterStaffLinkProcessorImpl) historyBefore(ctx context.Context, id string) (*anime_character_staff_link.AnimeCharacterStaffLink, error) {
+	if p.Options.History == nil {
+		return nil, nil
+	}
+	stored, err := p.Repository.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return stored, err
+}
+
+// recordHistory records the write of the message, the newer of before and
+// after identifies the link.
+func (p *CharacterStaffLinkProcessorImpl) recordHistory(ctx context.Context, data event.Event[*kafka.Message, Payload], before *anime_character_staff_link.AnimeCharacterStaffLink, after *anime_character_staff_link.AnimeCharacterStaffLink) error {
+	if p.Options.History == nil {
+		return nil
+	}
+	source := history.Source{
+		TxID: int64(data.Payload.Source.TxId),
+		Lsn:  int64(data.Payload.Source.Lsn),
+		TsMs: data.Payload.Source.TsMs,
+	}
+	change := history.Change{
+		EntityType: entity_history.EntityTypeLink,
+		Before:     before,
+		After:      after,
+		Message:    data.DriverMessage,
+		Source:     source,
+	}
+	for _, row := range []*anime_character_staff_link.AnimeCharacterStaffLink{before, after} {
+		if row != nil {
+			change.EntityID, change.CharacterID = row.ID, row.CharacterID
+		}
+	}
+	return p.Options.History.Record(ctx, change)
+}
+
+// recordCast reports the staff member of the link as changed in the cast of
+// the character's anime. Delete images may only carry the id, the stored link
+// fills in the character then.
+func (p *CharacterStaffLinkProcessorImpl) recordCast(ctx context.Context, link *anime_character_staff_link.AnimeCharacterStaffLink, change cast_events.ChangeType) error {
+	if p.Options.CastChanges == nil {
+		return nil
+	}
+	if link.CharacterID == "" {
+		stored, err := p.Repository.FindByID(ctx, link.ID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		link = stored
+	}
+	return p.Options.CastChanges.LinkChanged(ctx, link.CharacterID, link.StaffID, change)
+}
+
+// changes compares the link with the stored one, see db.StoredChanges.
+func (p *CharacterStaffLinkProcessorImpl) changes(ctx context.Context, next *anime_character_staff_link.AnimeCharacterStaffLink, before *Schema) ([]string, map[string]interface{}, error) {
+	var previous *anime_character_staff_link.AnimeCharacterStaffLink
+	if before != nil {
+		var err error
+		previous, err = p.parseToEntity(ctx, *before)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	find := func() (*anime_character_staff_link.AnimeCharacterStaffLink, error) {
+		return p.Repository.FindByID(ctx, next.ID)
+	}
+	return db.StoredChanges(next, previous, find, func(previous *anime_character_staff_link.AnimeCharacterStaffLink, next *anime_character_staff_link.AnimeCharacterStaffLink) ([]string, map[string]interface{}, error) {
+		return db.Changes(previous, next)
+	})
+}
+
+// written is the after image with the protected fields set to the stored
+// values that were kept, the link as it was written.
+func written(after *Schema, link *anime_character_staff_link.AnimeCharacterStaffLink, protected []string) *Schema {
+	if len(protected) == 0 {
+		return after
+	}
+	row := *after
+	for _, field := range protected {
+		switch field {
+		case "character_id":
+			row.CharacterID = link.CharacterID
+		case "staff_id":
+			row.StaffID = link.StaffID
+		case "character_name":
+			row.CharacterName = &link.CharacterName
+		case "staff_given_name":
+			row.StaffGivenName = &link.StaffGivenName
+		case "staff_family_name":
+			row.StaffFamilyName = &link.StaffFamilyName
+		case "language":
+			row.Language = &link.Language
+		case "role_type":
+			row.RoleType = &link.RoleType
+		case "notes":
+			row.Notes = link.Notes
+		}
+	}
+	return &row
+}
+
 func (p *CharacterStaffLinkProcessorImpl) parseToEntity(ctx context.Context, data Schema) (*anime_character_staff_link.AnimeCharacterStaffLink, error) {
 	return &anime_character_staff_link.AnimeCharacterStaffLink{
 		ID:              data.ID,
@@ -151,8 +444,11 @@ func (p *CharacterStaffLinkProcessorImpl) parseToEntity(ctx context.Context, dat
 		CharacterName:   ptrToString(data.CharacterName),
 		StaffGivenName:  ptrToString(data.StaffGivenName),
 		StaffFamilyName: ptrToString(data.StaffFamilyName),
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+		Language:        ptrToString(data.Language),
+		RoleType:        roleType(data.RoleType),
+		Notes:           data.Notes,
+		CreatedAt:       debezium.CreatedAt(data.CreatedAt),
+		UpdatedAt:       debezium.UpdatedAt(data.UpdatedAt),
 	}, nil
 }
 
@@ -161,4 +457,13 @@ func ptrToString(s *string) string {
 		return ""
 	}
 	return *s
-}
\ No newline at end of file
+}
+
+// roleType defaults to a voice credit, producers that predate role types
+// only sent voice acting links.
+func roleType(s *string) string {
+	if s == nil || *s == "" {
+		return anime_character_staff_link.RoleTypeVoice
+	}
+	return *s
+}
@@ -1,5 +1,7 @@
 package character_staff_link_processor
 
+import "github.com/weeb-vip/character-staff-sync/internal/debezium"
+
 type Action = string
 
 const (
@@ -8,15 +10,20 @@ const (
 	DeleteAction Action = "delete"
 )
 
+// Schema is a link row, Language, RoleType and Notes are optional since
+// older producers omit them.
 type Schema struct {
-	ID              string  `json:"id"`
-	CharacterID     string  `json:"character_id"`
-	StaffID         string  `json:"staff_id"`
-	CharacterName   *string `json:"character_name"`
-	StaffGivenName  *string `json:"staff_given_name"`
-	StaffFamilyName *string `json:"staff_family_name"`
-	CreatedAt       *int64  `json:"created_at"`
-	UpdatedAt       *int64  `json:"updated_at"`
+	ID              string              `json:"id"`
+	CharacterID     string              `json:"character_id"`
+	StaffID         string              `json:"staff_id"`
+	CharacterName   *string             `json:"character_name"`
+	StaffGivenName  *string             `json:"staff_given_name"`
+	StaffFamilyName *string             `json:"staff_family_name"`
+	Language        *string             `json:"language,omitempty"`
+	RoleType        *string             `json:"role_type,omitempty"`
+	Notes           *string             `json:"notes,omitempty"`
+	CreatedAt       *debezium.Timestamp `json:"created_at"`
+	UpdatedAt       *debezium.Timestamp `json:"updated_at"`
 }
 
 type Source struct {
@@ -43,4 +50,11 @@ type Payload struct {
 type ProducerPayload struct {
 	Action string  `json:"action"`
 	Data   *Schema `json:"data"`
-}
\ No newline at end of file
+	// ChangedFields and Previous are only set on updates, Previous holds the
+	// values of the changed fields before the update.
+	ChangedFields []string               `json:"changed_fields,omitempty"`
+	Previous      map[string]interface{} `json:"previous,omitempty"`
+	// Protected are the fields the source sent other values for that were
+	// kept because a source with a higher priority owns them.
+	Protected []string `json:"protected_fields,omitempty"`
+}
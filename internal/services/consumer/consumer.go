@@ -5,8 +5,8 @@ import (
 	"github.com/apache/pulsar-client-go/pulsar"
 	"github.com/weeb-vip/character-staff-sync/config"
 	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/ratelimit"
 	"go.uber.org/zap"
-	"time"
 )
 
 type Consumer[T any] interface {
@@ -17,9 +17,13 @@ type ConsumerImpl[T any] struct {
 	client   pulsar.Client
 	consumer *pulsar.Consumer
 	config   config.PulsarConfig
+	// pipeline names the consumption rate limit applied to the messages.
+	pipeline string
+	// concurrency is the number of workers processing the messages.
+	concurrency int
 }
 
-func NewConsumer[T any](ctx context.Context, cfg config.PulsarConfig) Consumer[T] {
+func NewConsumer[T any](ctx context.Context, cfg config.PulsarConfig, pipeline string, concurrency int) Consumer[T] {
 	log := logger.FromCtx(ctx)
 	client, err := pulsar.NewClient(pulsar.ClientOptions{
 		URL: cfg.URL,
@@ -30,9 +34,15 @@ func NewConsumer[T any](ctx context.Context, cfg config.PulsarConfig) Consumer[T
 		return nil
 	}
 
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
 	return &ConsumerImpl[T]{
-		config: cfg,
-		client: client,
+		config:      cfg,
+		client:      client,
+		pipeline:    pipeline,
+		concurrency: concurrency,
 	}
 }
 
@@ -55,6 +65,11 @@ func (c *ConsumerImpl[T]) Receive(ctx context.Context, process func(ctx context.
 
 	defer consumer.Close()
 
+	// the workers are closed first so the dispatched messages are processed
+	// and acked before the consumer goes away
+	workers := newWorkerPool(ctx, consumer, c.pipeline, c.concurrency, process)
+	defer workers.Close()
+
 	for {
 		msg, err := consumer.Receive(ctx)
 		if err != nil {
@@ -63,13 +78,12 @@ func (c *ConsumerImpl[T]) Receive(ctx context.Context, process func(ctx context.
 
 		log.Info("Received message", zap.String("msgId", msg.ID().String()))
 
-		err = process(ctx, msg)
-		if err != nil {
-			log.Warn("error processing message: ", zap.String("error", err.Error()))
-			continue
+		if err := ratelimit.Default.Wait(ctx, ratelimit.DirectionConsume, c.pipeline); err != nil {
+			return err
 		}
-		consumer.Ack(msg)
-		time.Sleep(50 * time.Millisecond)
-	}
 
+		if err := workers.Dispatch(ctx, msg); err != nil {
+			return err
+		}
+	}
 }
@@ -0,0 +1,155 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// workerPool processes messages on a fixed number of workers. A message is
+// always handed to the worker its ordering key hashes to, so the events of one
+// entity are processed in the order they were received while unrelated
+// entities are processed in parallel. A failed message is nacked and the later
+// messages of its key wait until its redelivery was processed.
+type workerPool struct {
+	consumer pulsar.Consumer
+	process  func(ctx context.Context, msg pulsar.Message) error
+	pipeline string
+	queues   []chan pulsar.Message
+	wg       sync.WaitGroup
+}
+
+// blockedKey is an ordering key waiting for the redelivery of a failed
+// message, with the messages of the key received since.
+type blockedKey struct {
+	failed string
+	held   []pulsar.Message
+}
+
+func newWorkerPool(ctx context.Context, consumer pulsar.Consumer, pipeline string, workers int, process func(ctx context.Context, msg pulsar.Message) error) *workerPool {
+	pool := &workerPool{
+		consumer: consumer,
+		process:  process,
+		pipeline: pipeline,
+		queues:   make([]chan pulsar.Message, workers),
+	}
+	for i := range pool.queues {
+		// a single slot per worker bounds the queued messages to twice the
+		// number of workers, held messages come on top
+		pool.queues[i] = make(chan pulsar.Message, 1)
+		pool.wg.Add(1)
+		go pool.work(ctx, pool.queues[i])
+	}
+	return pool
+}
+
+// Dispatch hands the message to its worker, blocking while the worker is
+// busy. It returns the context error when the context ends first.
+func (p *workerPool) Dispatch(ctx context.Context, msg pulsar.Message) error {
+	queue := p.queues[workerIndex(orderingKey(msg), len(p.queues))]
+	// counted before the send, the worker may be done with it right away
+	metrics.InFlightMessages.WithLabelValues(p.pipeline).Inc()
+	select {
+	case queue <- msg:
+		return nil
+	case <-ctx.Done():
+		metrics.InFlightMessages.WithLabelValues(p.pipeline).Dec()
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting messages and waits for the dispatched ones to be
+// processed. Messages still held behind a failed one are left unacked.
+func (p *workerPool) Close() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+func (p *workerPool) work(ctx context.Context, queue <-chan pulsar.Message) {
+	defer p.wg.Done()
+
+	blocked := map[string]*blockedKey{}
+	for msg := range queue {
+		key := orderingKey(msg)
+		pending := []pulsar.Message{msg}
+		if b, ok := blocked[key]; ok {
+			if msg.ID().String() != b.failed {
+				// waits behind the failed message of its key
+				b.held = append(b.held, msg)
+				continue
+			}
+			pending = append(pending, b.held...)
+			delete(blocked, key)
+		}
+
+		for i, next := range pending {
+			ok := handle(ctx, p.consumer, next, p.process)
+			metrics.InFlightMessages.WithLabelValues(p.pipeline).Dec()
+			if !ok {
+				blocked[key] = &blockedKey{failed: next.ID().String(), held: pending[i+1:]}
+				break
+			}
+		}
+	}
+
+	for _, b := range blocked {
+		metrics.InFlightMessages.WithLabelValues(p.pipeline).Sub(float64(len(b.held)))
+	}
+}
+
+// handle processes the message and acks it once it was processed. A failed
+// message is nacked for redelivery, handle reports false.
+func handle(ctx context.Context, consumer pulsar.Consumer, msg pulsar.Message, process func(ctx context.Context, msg pulsar.Message) error) bool {
+	log := logger.FromCtx(ctx)
+
+	if err := process(ctx, msg); err != nil {
+		log.Warn("error processing message: ", zap.String("msgId", msg.ID().String()), zap.String("error", err.Error()))
+		consumer.Nack(msg)
+		return false
+	}
+	if err := consumer.Ack(msg); err != nil {
+		log.Warn("error acking message: ", zap.String("msgId", msg.ID().String()), zap.String("error", err.Error()))
+	}
+	return true
+}
+
+// orderingKey is the message key, or the id of the changed row when the
+// message has no key.
+func orderingKey(msg pulsar.Message) string {
+	if key := msg.Key(); key != "" {
+		return key
+	}
+
+	var change struct {
+		Before *struct {
+			ID string `json:"id"`
+		} `json:"before"`
+		After *struct {
+			ID string `json:"id"`
+		} `json:"after"`
+	}
+	if err := json.Unmarshal(msg.Payload(), &change); err != nil {
+		return ""
+	}
+	if change.After != nil && change.After.ID != "" {
+		return change.After.ID
+	}
+	if change.Before != nil {
+		return change.Before.ID
+	}
+	return ""
+}
+
+func workerIndex(key string, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
@@ -0,0 +1,176 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
+	"go.uber.org/zap"
+)
+
+type testMessage struct {
+	pulsar.Message
+	id  pulsar.MessageID
+	key string
+}
+
+func (m *testMessage) ID() pulsar.MessageID { return m.id }
+func (m *testMessage) Key() string          { return m.key }
+func (m *testMessage) Payload() []byte      { return nil }
+
+func newTestMessage(entry int64, key string) *testMessage {
+	return &testMessage{id: pulsar.NewMessageID(1, entry, -1, 0), key: key}
+}
+
+// testConsumer records the acked and nacked messages.
+type testConsumer struct {
+	pulsar.Consumer
+	mu     sync.Mutex
+	acked  []string
+	nacked []string
+}
+
+func (c *testConsumer) Ack(msg pulsar.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.acked = append(c.acked, msg.ID().String())
+	return nil
+}
+
+func (c *testConsumer) Nack(msg pulsar.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nacked = append(c.nacked, msg.ID().String())
+}
+
+// processed records the processed messages by ordering key.
+type processed struct {
+	mu    sync.Mutex
+	byKey map[string][]string
+}
+
+func (p *processed) add(msg pulsar.Message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.byKey[msg.Key()] = append(p.byKey[msg.Key()], msg.ID().String())
+}
+
+// inFlight reads the in-flight gauge of the pipeline.
+func inFlight(t *testing.T, pipeline string) float64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := metrics.InFlightMessages.WithLabelValues(pipeline).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetGauge().GetValue()
+}
+
+func TestWorkerPoolKeepsKeyOrder(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+	consumer := &testConsumer{}
+	seen := &processed{byKey: map[string][]string{}}
+	pool := newWorkerPool(ctx, consumer, "test-order", 4, func(ctx context.Context, msg pulsar.Message) error {
+		seen.add(msg)
+		return nil
+	})
+
+	want := map[string][]string{}
+	for i := int64(0); i < 200; i++ {
+		msg := newTestMessage(i, fmt.Sprintf("entity-%d", i%7))
+		want[msg.key] = append(want[msg.key], msg.id.String())
+		if err := pool.Dispatch(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pool.Close()
+
+	if !reflect.DeepEqual(seen.byKey, want) {
+		t.Errorf("processed %v, want %v", seen.byKey, want)
+	}
+	if len(consumer.acked) != 200 || len(consumer.nacked) != 0 {
+		t.Errorf("acked %d and nacked %d messages, want 200 and 0", len(consumer.acked), len(consumer.nacked))
+	}
+	if n := inFlight(t, "test-order"); n != 0 {
+		t.Errorf("%v messages in flight after close, want 0", n)
+	}
+}
+
+func TestWorkerPoolHoldsKeyBehindFailure(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+	consumer := &testConsumer{}
+	seen := &processed{byKey: map[string][]string{}}
+	failed := newTestMessage(1, "a")
+	attempts := 0
+	pool := newWorkerPool(ctx, consumer, "test-failure", 2, func(ctx context.Context, msg pulsar.Message) error {
+		if msg.ID().String() == failed.id.String() {
+			attempts++
+			if attempts == 1 {
+				return errors.New("write failed")
+			}
+		}
+		seen.add(msg)
+		return nil
+	})
+
+	later := newTestMessage(2, "a")
+	other := newTestMessage(3, "b")
+	for _, msg := range []pulsar.Message{failed, later, other} {
+		if err := pool.Dispatch(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the broker redelivers the nacked message
+	if err := pool.Dispatch(ctx, newTestMessage(1, "a")); err != nil {
+		t.Fatal(err)
+	}
+	pool.Close()
+
+	if want := []string{failed.id.String()}; !reflect.DeepEqual(consumer.nacked, want) {
+		t.Errorf("nacked %v, want %v", consumer.nacked, want)
+	}
+	want := map[string][]string{
+		"a": {failed.id.String(), later.id.String()},
+		"b": {other.id.String()},
+	}
+	if !reflect.DeepEqual(seen.byKey, want) {
+		t.Errorf("processed %v, want %v", seen.byKey, want)
+	}
+	if len(consumer.acked) != 3 {
+		t.Errorf("acked %v, want 3 messages", consumer.acked)
+	}
+	if n := inFlight(t, "test-failure"); n != 0 {
+		t.Errorf("%v messages in flight after close, want 0", n)
+	}
+}
+
+func TestWorkerPoolLeavesHeldMessagesOnClose(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+	consumer := &testConsumer{}
+	pool := newWorkerPool(ctx, consumer, "test-close", 1, func(ctx context.Context, msg pulsar.Message) error {
+		if msg.Key() == "a" {
+			return errors.New("write failed")
+		}
+		return nil
+	})
+
+	for _, msg := range []pulsar.Message{newTestMessage(1, "a"), newTestMessage(2, "a"), newTestMessage(3, "b")} {
+		if err := pool.Dispatch(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pool.Close()
+
+	if len(consumer.nacked) != 1 || len(consumer.acked) != 1 {
+		t.Errorf("nacked %v and acked %v, want the failed message nacked and b acked", consumer.nacked, consumer.acked)
+	}
+	if n := inFlight(t, "test-close"); n != 0 {
+		t.Errorf("%v messages in flight after close, want 0", n)
+	}
+}
@@ -0,0 +1,214 @@
+package duplicates
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/names"
+)
+
+type Detector interface {
+	Find(ctx context.Context, opt Options) ([]Candidate, error)
+}
+
+type DetectorImpl struct {
+	CharacterRepository anime_character.AnimeCharacterRepository
+	StaffRepository     anime_staff.AnimeStaffRepository
+	LinkRepository      anime_character_staff_link.AnimeCharacterStaffLinkRepository
+}
+
+func NewDetector(characterRepo anime_character.AnimeCharacterRepository, staffRepo anime_staff.AnimeStaffRepository, linkRepo anime_character_staff_link.AnimeCharacterStaffLinkRepository) Detector {
+	return &DetectorImpl{
+		CharacterRepository: characterRepo,
+		StaffRepository:     staffRepo,
+		LinkRepository:      linkRepo,
+	}
+}
+
+// profile is the part of a character or staff member compared between rows
+// sharing a name key. Rows with a different scope, the anime of a character
+// or the language of a staff member, are never duplicates.
+type profile struct {
+	ID            string
+	Name          string
+	Scope         string
+	Birthday      string
+	BirthdayMonth *int
+	BirthdayDay   *int
+	BirthdayYear  *int
+	Image         string
+	CreatedAt     time.Time
+	Links         int64
+}
+
+// Find groups rows by name key and proposes merging every row of a group
+// into the row kept, scored by their birthdays and images.
+func (d *DetectorImpl) Find(ctx context.Context, opt Options) ([]Candidate, error) {
+	if opt.BatchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive")
+	}
+
+	candidates := []Candidate{}
+	afterKey := ""
+	for {
+		keys, err := d.listKeys(ctx, opt.EntityType, afterKey, opt.BatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return candidates, nil
+		}
+
+		for _, key := range keys {
+			profiles, err := d.listProfiles(ctx, opt.EntityType, key)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, candidatesOf(opt, profiles)...)
+		}
+		afterKey = keys[len(keys)-1]
+	}
+}
+
+func (d *DetectorImpl) listKeys(ctx context.Context, entityType EntityType, afterKey string, limit int) ([]string, error) {
+	switch entityType {
+	case EntityCharacter:
+		return d.CharacterRepository.ListDuplicateNameKeys(ctx, afterKey, limit)
+	case EntityStaff:
+		return d.StaffRepository.ListDuplicateNameKeys(ctx, afterKey, limit)
+	}
+	return nil, fmt.Errorf("unknown entity type %q", entityType)
+}
+
+func (d *DetectorImpl) listProfiles(ctx context.Context, entityType EntityType, key string) ([]profile, error) {
+	var profiles []profile
+	switch entityType {
+	case EntityCharacter:
+		characters, err := d.CharacterRepository.ListByNameKey(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range characters {
+			links, err := d.LinkRepository.Count(ctx, anime_character_staff_link.ListFilter{CharacterID: c.ID})
+			if err != nil {
+				return nil, err
+			}
+			profiles = append(profiles, profile{
+				ID:            c.ID,
+				Name:          c.Name,
+				Scope:         c.AnimeID,
+				Birthday:      c.Birthday,
+				BirthdayMonth: c.BirthdayMonth,
+				BirthdayDay:   c.BirthdayDay,
+				BirthdayYear:  c.BirthdayYear,
+				Image:         c.Image,
+				CreatedAt:     c.CreatedAt,
+				Links:         links,
+			})
+		}
+	case EntityStaff:
+		staff, err := d.StaffRepository.ListByNameKey(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range staff {
+			links, err := d.LinkRepository.Count(ctx, anime_character_staff_link.ListFilter{StaffID: s.ID})
+			if err != nil {
+				return nil, err
+			}
+			profiles = append(profiles, profile{
+				ID:            s.ID,
+				Name:          names.FullName(s.GivenName, s.FamilyName),
+				Scope:         s.Language,
+				Birthday:      s.Birthday,
+				BirthdayMonth: s.BirthdayMonth,
+				BirthdayDay:   s.BirthdayDay,
+				BirthdayYear:  s.BirthdayYear,
+				Image:         s.Image,
+				CreatedAt:     s.CreatedAt,
+				Links:         links,
+			})
+		}
+	}
+	return profiles, nil
+}
+
+// candidatesOf compares every profile of a scope with the one kept.
+func candidatesOf(opt Options, profiles []profile) []Candidate {
+	scopes := map[string][]profile{}
+	var order []string
+	for _, p := range profiles {
+		if _, ok := scopes[p.Scope]; !ok {
+			order = append(order, p.Scope)
+		}
+		scopes[p.Scope] = append(scopes[p.Scope], p)
+	}
+
+	var candidates []Candidate
+	for _, scope := range order {
+		group := scopes[scope]
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].Links != group[j].Links {
+				return group[i].Links > group[j].Links
+			}
+			if !group[i].CreatedAt.Equal(group[j].CreatedAt) {
+				return group[i].CreatedAt.Before(group[j].CreatedAt)
+			}
+			return group[i].ID < group[j].ID
+		})
+
+		keep := group[0]
+		for _, other := range group[1:] {
+			score, reasons, ok := compare(keep, other)
+			if !ok || score < opt.MinScore {
+				continue
+			}
+			candidates = append(candidates, Candidate{
+				EntityType: opt.EntityType,
+				Name:       keep.Name,
+				KeepID:     keep.ID,
+				MergeID:    other.ID,
+				Score:      score,
+				Reasons:    reasons,
+			})
+		}
+	}
+	return candidates
+}
+
+// compare scores two profiles sharing a name key, ok is false when their
+// birthdays contradict each other.
+func compare(a profile, b profile) (float64, []string, bool) {
+	score := nameScore
+	reasons := []string{ReasonName}
+
+	switch {
+	case a.BirthdayMonth != nil && a.BirthdayDay != nil && b.BirthdayMonth != nil && b.BirthdayDay != nil:
+		if *a.BirthdayMonth != *b.BirthdayMonth || *a.BirthdayDay != *b.BirthdayDay {
+			return 0, nil, false
+		}
+		if a.BirthdayYear != nil && b.BirthdayYear != nil && *a.BirthdayYear != *b.BirthdayYear {
+			return 0, nil, false
+		}
+		score += birthdayScore
+		reasons = append(reasons, ReasonBirthday)
+	case a.Birthday != "" && a.Birthday == b.Birthday:
+		score += birthdayScore
+		reasons = append(reasons, ReasonBirthday)
+	}
+
+	if a.Image != "" && a.Image == b.Image {
+		score += imageScore
+		reasons = append(reasons, ReasonImage)
+	}
+	return math.Round(score*100) / 100, reasons, true
+}
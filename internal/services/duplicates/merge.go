@@ -0,0 +1,261 @@
+package duplicates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_redirect"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
+	"github.com/weeb-vip/character-staff-sync/internal/services/cast_events"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type MergeOptions struct {
+	// CastChanges, when set, is told about the casts the merge changed.
+	CastChanges cast_events.Recorder
+	// History, when set, records the deleted row, the survivor and the moved
+	// links.
+	History history.Recorder
+}
+
+type Merger interface {
+	Merge(ctx context.Context, entityType EntityType, fromID string, intoID string) (*MergeResult, error)
+}
+
+type MergerImpl struct {
+	DB                  *db.DB
+	CharacterRepository anime_character.AnimeCharacterRepository
+	StaffRepository     anime_staff.AnimeStaffRepository
+	LinkRepository      anime_character_staff_link.AnimeCharacterStaffLinkRepository
+	RedirectRepository  entity_redirect.EntityRedirectRepository
+	Options             MergeOptions
+	CharacterProducer   func(ctx context.Context, message *kafka.Message) error
+	StaffProducer       func(ctx context.Context, message *kafka.Message) error
+}
+
+func NewMerger(opt MergeOptions, database *db.DB, characterProducer func(ctx context.Context, message *kafka.Message) error, staffProducer func(ctx context.Context, message *kafka.Message) error) Merger {
+	return &MergerImpl{
+		DB:                  database,
+		CharacterRepository: anime_character.NewAnimeCharacterRepository(database),
+		StaffRepository:     anime_staff.NewAnimeStaffRepository(database),
+		LinkRepository:      anime_character_staff_link.NewAnimeCharacterStaffLinkRepository(database),
+		RedirectRepository:  entity_redirect.NewEntityRedirectRepository(database),
+		Options:             opt,
+		CharacterProducer:   characterProducer,
+		StaffProducer:       staffProducer,
+	}
+}
+
+// Merge moves the links and aliases of fromID to intoID, deletes fromID and
+// redirects it to intoID in one transaction, then emits a merge event.
+func (m *MergerImpl) Merge(ctx context.Context, entityType EntityType, fromID string, intoID string) (*MergeResult, error) {
+	log := logger.FromCtx(ctx)
+
+	if fromID == intoID {
+		return nil, fmt.Errorf("cannot merge %s %s into itself", entityType, fromID)
+	}
+
+	result := &MergeResult{EntityType: entityType, FromID: fromID, IntoID: intoID}
+	var producer func(ctx context.Context, message *kafka.Message) error
+	var recordCast func() error
+
+	err := m.DB.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		ctx := db.WithTx(ctx, tx)
+
+		var err error
+		switch entityType {
+		case EntityCharacter:
+			recordCast, err = m.mergeCharacter(ctx, result)
+			producer = m.CharacterProducer
+		case EntityStaff:
+			recordCast, err = m.mergeStaff(ctx, result)
+			producer = m.StaffProducer
+		default:
+			err = fmt.Errorf("unknown entity type %q", entityType)
+		}
+		if err != nil {
+			return err
+		}
+		return m.RedirectRepository.Redirect(ctx, entityType, fromID, intoID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.Merges.WithLabelValues(entityType).Inc()
+	log.Info("Merged duplicate", zap.String("entityType", entityType),
+		zap.String("from", fromID), zap.String("into", intoID), zap.Int64("links", result.Links))
+
+	if m.Options.CastChanges != nil {
+		if err := recordCast(); err != nil {
+			return result, err
+		}
+	}
+
+	if producer == nil {
+		return result, nil
+	}
+	payloadBytes, err := json.Marshal(MergeEvent{
+		Action: MergeAction,
+		Data: MergeData{
+			EntityType: entityType,
+			ID:         intoID,
+			MergedID:   fromID,
+			Links:      result.Links,
+		},
+	})
+	if err != nil {
+		log.Error("Error marshaling producer payload", zap.Error(err))
+		return result, err
+	}
+	if err := producer(ctx, &kafka.Message{Value: payloadBytes}); err != nil {
+		log.Error("Error sending message to producer", zap.Error(err))
+		return result, err
+	}
+	return result, nil
+}
+
+func (m *MergerImpl) mergeCharacter(ctx context.Context, result *MergeResult) (func() error, error) {
+	from, err := m.CharacterRepository.FindByID(ctx, result.FromID)
+	if err != nil {
+		return nil, fmt.Errorf("character %s: %w", result.FromID, err)
+	}
+	into, err := m.CharacterRepository.FindByID(ctx, result.IntoID)
+	if err != nil {
+		return nil, fmt.Errorf("character %s: %w", result.IntoID, err)
+	}
+	if from.AnimeID != into.AnimeID {
+		return nil, fmt.Errorf("characters %s and %s belong to different anime", from.ID, into.ID)
+	}
+
+	moved, err := m.LinkRepository.RepointCharacter(ctx, from.ID, into.ID, into.Name)
+	if err != nil {
+		return nil, err
+	}
+	result.Links = int64(len(moved))
+	if err := m.CharacterRepository.Delete(ctx, from); err != nil {
+		return nil, err
+	}
+	survivor := *into
+	survivor.Aliases = mergeAliases(into.Aliases, from.Aliases)
+	if err := m.CharacterRepository.Upsert(ctx, &survivor); err != nil {
+		return nil, err
+	}
+
+	changes := []history.Change{
+		{EntityType: entity_history.EntityTypeCharacter, EntityID: from.ID, AnimeID: from.AnimeID, Before: from},
+		{EntityType: entity_history.EntityTypeCharacter, EntityID: into.ID, AnimeID: into.AnimeID, Before: into, After: &survivor},
+	}
+	for i := range moved {
+		after := moved[i]
+		after.CharacterID, after.CharacterName = into.ID, into.Name
+		changes = append(changes, history.Change{EntityType: entity_history.EntityTypeLink, EntityID: after.ID, CharacterID: after.CharacterID, Before: &moved[i], After: &after})
+	}
+	if err := m.recordHistory(ctx, changes); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		if err := m.Options.CastChanges.CharacterChanged(ctx, from.AnimeID, from.ID, cast_events.ChangeRemoved); err != nil {
+			return err
+		}
+		return m.Options.CastChanges.CharacterChanged(ctx, into.AnimeID, into.ID, cast_events.ChangeUpdated)
+	}, nil
+}
+
+func (m *MergerImpl) mergeStaff(ctx context.Context, result *MergeResult) (func() error, error) {
+	from, err := m.StaffRepository.FindByID(ctx, result.FromID)
+	if err != nil {
+		return nil, fmt.Errorf("staff %s: %w", result.FromID, err)
+	}
+	into, err := m.StaffRepository.FindByID(ctx, result.IntoID)
+	if err != nil {
+		return nil, fmt.Errorf("staff %s: %w", result.IntoID, err)
+	}
+
+	moved, err := m.LinkRepository.RepointStaff(ctx, from.ID, into.ID, into.GivenName, into.FamilyName)
+	if err != nil {
+		return nil, err
+	}
+	result.Links = int64(len(moved))
+	if err := m.StaffRepository.Delete(ctx, from); err != nil {
+		return nil, err
+	}
+	survivor := *into
+	survivor.Aliases = mergeAliases(into.Aliases, from.Aliases)
+	if err := m.StaffRepository.Upsert(ctx, &survivor); err != nil {
+		return nil, err
+	}
+
+	changes := []history.Change{
+		{EntityType: entity_history.EntityTypeStaff, EntityID: from.ID, Before: from},
+		{EntityType: entity_history.EntityTypeStaff, EntityID: into.ID, Before: into, After: &survivor},
+	}
+	for i := range moved {
+		after := moved[i]
+		after.StaffID, after.StaffGivenName, after.StaffFamilyName = into.ID, into.GivenName, into.FamilyName
+		changes = append(changes, history.Change{EntityType: entity_history.EntityTypeLink, EntityID: after.ID, CharacterID: after.CharacterID, Before: &moved[i], After: &after})
+	}
+	if err := m.recordHistory(ctx, changes); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return m.Options.CastChanges.StaffChanged(ctx, into.ID, cast_events.ChangeUpdated)
+	}, nil
+}
+
+func (m *MergerImpl) recordHistory(ctx context.Context, changes []history.Change) error {
+	if m.Options.History == nil {
+		return nil
+	}
+	for _, change := range changes {
+		if err := m.Options.History.Record(ctx, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeAliases returns the aliases of the survivor followed by the aliases of
+// the merged row it does not have yet. A native, romaji or english alias
+// whose language the survivor already fills is kept as a nickname.
+func mergeAliases(into []entity_alias.EntityAlias, from []entity_alias.EntityAlias) []entity_alias.EntityAlias {
+	merged := append([]entity_alias.EntityAlias{}, into...)
+	next := func(language string, kind entity_alias.Kind) int {
+		position := 0
+		for _, alias := range merged {
+			if alias.Language == language && alias.Kind == kind {
+				position++
+			}
+		}
+		return position
+	}
+
+	for _, alias := range from {
+		known := false
+		for _, existing := range merged {
+			known = known || existing.Name == alias.Name
+		}
+		if known {
+			continue
+		}
+		language, kind := alias.Language, alias.Kind
+		if kind != entity_alias.KindNickname && next(language, kind) > 0 {
+			language, kind = entity_alias.LanguageUndetermined, entity_alias.KindNickname
+		}
+		merged = append(merged, entity_alias.EntityAlias{Language: language, Kind: kind, Position: next(language, kind), Name: alias.Name})
+	}
+	return merged
+}
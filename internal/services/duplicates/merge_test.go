@@ -0,0 +1,100 @@
+package duplicates_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	migrations "github.com/weeb-vip/character-staff-sync/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/duplicates"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"go.uber.org/zap"
+)
+
+func TestMergeCharacter(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(t.TempDir(), "merge.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+	characters := anime_character.NewAnimeCharacterRepository(database)
+	links := anime_character_staff_link.NewAnimeCharacterStaffLinkRepository(database)
+	historyRepo := entity_history.NewEntityHistoryRepository(database)
+
+	for _, character := range []*anime_character.AnimeCharacter{
+		{ID: "keep", AnimeID: "anime", Name: "Edward Elric", Role: "Main", Aliases: []entity_alias.EntityAlias{
+			{Language: entity_alias.LanguageJapanese, Kind: entity_alias.KindNative, Name: "エドワード・エルリック"},
+		}},
+		{ID: "dupe", AnimeID: "anime", Name: "Elric Edward", Role: "Main", Aliases: []entity_alias.EntityAlias{
+			{Language: entity_alias.LanguageJapanese, Kind: entity_alias.KindNative, Name: "エド"},
+			{Language: entity_alias.LanguageEnglish, Kind: entity_alias.KindEnglish, Name: "Fullmetal Alchemist"},
+			{Language: entity_alias.LanguageJapanese, Kind: entity_alias.KindNative, Position: 1, Name: "エドワード・エルリック"},
+		}},
+	} {
+		if err := characters.Upsert(ctx, character); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := &anime_character_staff_link.AnimeCharacterStaffLink{ID: "link", CharacterID: "dupe", StaffID: "staff", CharacterName: "Elric Edward", Language: "Japanese", RoleType: anime_character_staff_link.RoleTypeVoice}
+	if err := links.Upsert(ctx, link); err != nil {
+		t.Fatal(err)
+	}
+
+	merger := duplicates.NewMerger(duplicates.MergeOptions{History: history.NewRecorder(historyRepo, "merge")}, database, nil, nil)
+	result, err := merger.Merge(ctx, duplicates.EntityCharacter, "dupe", "keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Links != 1 {
+		t.Errorf("links = %d, want 1", result.Links)
+	}
+
+	moved, err := links.FindByID(ctx, "link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.CharacterID != "keep" || moved.CharacterName != "Edward Elric" {
+		t.Errorf("link = %s %q, want keep %q", moved.CharacterID, moved.CharacterName, "Edward Elric")
+	}
+
+	kept, err := characters.FindByID(ctx, "keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"エドワード・エルリック", "Fullmetal Alchemist", "エド"}
+	got := entity_alias.Names(kept.Aliases)
+	if len(got) != len(want) {
+		t.Fatalf("aliases = %q, want %q", got, want)
+	}
+	for _, alias := range kept.Aliases {
+		if alias.Name == "エド" && alias.Kind != entity_alias.KindNickname {
+			t.Errorf("alias %q kind = %s, want nickname", alias.Name, alias.Kind)
+		}
+	}
+
+	for _, tt := range []struct {
+		entityType string
+		entityID   string
+		operation  string
+	}{
+		{entity_history.EntityTypeCharacter, "dupe", entity_history.OperationDelete},
+		{entity_history.EntityTypeCharacter, "keep", entity_history.OperationUpdate},
+		{entity_history.EntityTypeLink, "link", entity_history.OperationUpdate},
+	} {
+		entries, err := historyRepo.List(ctx, tt.entityType, tt.entityID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Operation != tt.operation || entries[0].Pipeline != "merge" {
+			t.Errorf("history of %s %s = %+v, want one %s by merge", tt.entityType, tt.entityID, entries, tt.operation)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package duplicates
+
+import "github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_redirect"
+
+type EntityType = string
+
+const (
+	EntityCharacter EntityType = entity_redirect.EntityTypeCharacter
+	EntityStaff     EntityType = entity_redirect.EntityTypeStaff
+)
+
+const MergeAction = "merge"
+
+// Reasons a candidate pair is considered the same entity.
+const (
+	ReasonName     = "name"
+	ReasonBirthday = "birthday"
+	ReasonImage    = "image"
+)
+
+// Scores added by every reason, a shared name key alone scores
+// nameScore.
+const (
+	nameScore     = 0.6
+	birthdayScore = 0.25
+	imageScore    = 0.15
+)
+
+type Options struct {
+	EntityType EntityType
+	// MinScore drops candidates with a lower score.
+	MinScore  float64
+	BatchSize int
+}
+
+// Candidate proposes merging MergeID into KeepID. KeepID is the entity with
+// the most links, then the oldest.
+type Candidate struct {
+	EntityType EntityType `json:"entity_type"`
+	Name       string     `json:"name"`
+	KeepID     string     `json:"keep_id"`
+	MergeID    string     `json:"merge_id"`
+	Score      float64    `json:"score"`
+	Reasons    []string   `json:"reasons"`
+}
+
+type MergeResult struct {
+	EntityType EntityType `json:"entity_type"`
+	FromID     string     `json:"from_id"`
+	IntoID     string     `json:"into_id"`
+	// Links is the number of links moved to IntoID.
+	Links int64 `json:"links"`
+}
+
+// MergeEvent is emitted after a merge, consumers should treat MergedID as
+// an alias of ID from then on.
+type MergeEvent struct {
+	Action string    `json:"action"`
+	Data   MergeData `json:"data"`
+}
+
+type MergeData struct {
+	EntityType EntityType `json:"entity_type"`
+	ID         string     `json:"id"`
+	MergedID   string     `json:"merged_id"`
+	Links      int64      `json:"links"`
+}
@@ -0,0 +1,193 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/services/ledger"
+)
+
+type RecorderImpl struct {
+	Repository entity_history.EntityHistoryRepository
+	Pipeline   string
+}
+
+func NewRecorder(repo entity_history.EntityHistoryRepository, pipeline string) Recorder {
+	return &RecorderImpl{
+		Repository: repo,
+		Pipeline:   pipeline,
+	}
+}
+
+func (r *RecorderImpl) Record(ctx context.Context, change Change) error {
+	before, after := isSet(change.Before), isSet(change.After)
+	entry := &entity_history.EntityHistory{
+		EntityType:  change.EntityType,
+		EntityID:    change.EntityID,
+		AnimeID:     change.AnimeID,
+		CharacterID: change.CharacterID,
+		Pipeline:    r.Pipeline,
+		SourceTxID:  change.Source.TxID,
+		SourceLsn:   change.Source.Lsn,
+		SourceTsMs:  change.Source.TsMs,
+		RecordedAt:  time.Now(),
+	}
+
+	switch {
+	case before && after:
+		entry.Operation = entity_history.OperationUpdate
+		changed, _, err := db.Changes(change.Before, change.After)
+		if err != nil {
+			return err
+		}
+		changed = append(changed, ChangedLists(change.Before, change.After)...)
+		if len(changed) == 0 {
+			return nil
+		}
+		entry.ChangedFields = strings.Join(changed, ",")
+	case after:
+		entry.Operation = entity_history.OperationCreate
+	case before:
+		entry.Operation = entity_history.OperationDelete
+	default:
+		return nil
+	}
+
+	var err error
+	if entry.Before, err = snapshot(change.Before); err != nil {
+		return err
+	}
+	if entry.After, err = snapshot(change.After); err != nil {
+		return err
+	}
+	if change.Message != nil {
+		message := ledger.KafkaEntry(r.Pipeline, change.Message)
+		entry.MessageID = message.MessageKey
+		if entry.MessageID == "" {
+			entry.MessageID = message.SourcePosition
+		}
+	} else if id, ok := ctx.Value(messageIDKey{}).(string); ok {
+		entry.MessageID = id
+	}
+	return r.Repository.Create(ctx, entry)
+}
+
+type messageIDKey struct{}
+
+// WithMessageID returns a context carrying the id of the message being
+// applied, recorded for changes without a kafka message.
+func WithMessageID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, messageIDKey{}, id)
+}
+
+// ChangedLists returns the snapshot keys of the lists that differ between
+// before and after. A list that is not loaded on either side, nil, is not
+// compared.
+func ChangedLists(before interface{}, after interface{}) []string {
+	previous, next := lists(before), lists(after)
+	var changed []string
+	for _, field := range []string{FieldAliases, FieldHobbies} {
+		previousList, loaded := previous[field]
+		nextList, nextLoaded := next[field]
+		if loaded && nextLoaded && !reflect.DeepEqual(previousList, nextList) {
+			changed = append(changed, field)
+		}
+	}
+	return changed
+}
+
+// lists returns the loaded lists of the row keyed by snapshot key.
+func lists(row interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	var aliases []entity_alias.EntityAlias
+	switch row := row.(type) {
+	case *anime_character.AnimeCharacter:
+		if row == nil {
+			return result
+		}
+		aliases = row.Aliases
+	case *anime_staff.AnimeStaff:
+		if row == nil {
+			return result
+		}
+		aliases = row.Aliases
+		if row.HobbyList != nil {
+			result[FieldHobbies] = row.HobbyList
+		}
+	}
+	if aliases != nil {
+		encoded := make([]Alias, len(aliases))
+		for i, alias := range aliases {
+			encoded[i] = Alias{Language: alias.Language, Kind: alias.Kind, Position: alias.Position, Name: alias.Name}
+		}
+		result[FieldAliases] = encoded
+	}
+	return result
+}
+
+// snapshot encodes the columns and loaded lists of the row as a JSON object,
+// an unset row as an empty string.
+func snapshot(row interface{}) (string, error) {
+	if !isSet(row) {
+		return "", nil
+	}
+	columns, err := db.Columns(row)
+	if err != nil {
+		return "", err
+	}
+	for field, list := range lists(row) {
+		columns[field] = list
+	}
+	encoded, err := json.Marshal(columns)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// DecodeSnapshot sets the columns and lists of row from a snapshot. Lists
+// the snapshot does not hold, recorded before lists were kept, stay nil.
+func DecodeSnapshot(data []byte, row interface{}) error {
+	if err := db.DecodeColumns(data, row); err != nil {
+		return err
+	}
+	var fields struct {
+		Aliases []Alias  `json:"aliases"`
+		Hobbies []string `json:"hobby_list"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var aliases []entity_alias.EntityAlias
+	if fields.Aliases != nil {
+		aliases = make([]entity_alias.EntityAlias, len(fields.Aliases))
+		for i, alias := range fields.Aliases {
+			aliases[i] = entity_alias.EntityAlias{Language: alias.Language, Kind: alias.Kind, Position: alias.Position, Name: alias.Name}
+		}
+	}
+	switch row := row.(type) {
+	case *anime_character.AnimeCharacter:
+		row.Aliases = aliases
+	case *anime_staff.AnimeStaff:
+		row.Aliases = aliases
+		row.HobbyList = fields.Hobbies
+	}
+	return nil
+}
+
+func isSet(row interface{}) bool {
+	if row == nil {
+		return false
+	}
+	value := reflect.ValueOf(row)
+	return !(value.Kind() == reflect.Ptr && value.IsNil())
+}
@@ -0,0 +1,126 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+)
+
+// Version is a recorded change as shown by the history command.
+type Version struct {
+	ID            uint64                 `json:"id"`
+	EntityType    string                 `json:"entity_type"`
+	EntityID      string                 `json:"entity_id"`
+	Operation     string                 `json:"operation"`
+	ChangedFields []string               `json:"changed_fields,omitempty"`
+	Before        map[string]interface{} `json:"before,omitempty"`
+	After         map[string]interface{} `json:"after,omitempty"`
+	Pipeline      string                 `json:"pipeline"`
+	MessageID     string                 `json:"message_id,omitempty"`
+	Source        VersionSource          `json:"source"`
+	RecordedAt    time.Time              `json:"recorded_at"`
+}
+
+type VersionSource struct {
+	TxID int64 `json:"tx_id"`
+	Lsn  int64 `json:"lsn"`
+	TsMs int64 `json:"ts_ms"`
+}
+
+func NewVersion(entry entity_history.EntityHistory) (Version, error) {
+	version := Version{
+		ID:         entry.ID,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		Operation:  entry.Operation,
+		Pipeline:   entry.Pipeline,
+		MessageID:  entry.MessageID,
+		Source: VersionSource{
+			TxID: entry.SourceTxID,
+			Lsn:  entry.SourceLsn,
+			TsMs: entry.SourceTsMs,
+		},
+		RecordedAt: entry.RecordedAt,
+	}
+	if entry.ChangedFields != "" {
+		version.ChangedFields = strings.Split(entry.ChangedFields, ",")
+	}
+	for _, field := range []struct {
+		raw    string
+		target *map[string]interface{}
+	}{{entry.Before, &version.Before}, {entry.After, &version.After}} {
+		if field.raw == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(field.raw), field.target); err != nil {
+			return Version{}, fmt.Errorf("invalid snapshot of history entry %d: %w", entry.ID, err)
+		}
+	}
+	return version, nil
+}
+
+// WriteTimeline writes the versions oldest first, one header line per
+// version followed by the changed values.
+func WriteTimeline(w io.Writer, versions []Version) error {
+	for _, version := range versions {
+		header := fmt.Sprintf("#%d %s %s pipeline=%s", version.ID,
+			version.RecordedAt.UTC().Format(time.RFC3339), version.Operation, version.Pipeline)
+		if version.MessageID != "" {
+			header += " message=" + version.MessageID
+		}
+		header += fmt.Sprintf(" tx=%d lsn=%d", version.Source.TxID, version.Source.Lsn)
+		if version.Source.TsMs > 0 {
+			header += " source_ts=" + time.UnixMilli(version.Source.TsMs).UTC().Format(time.RFC3339)
+		}
+		if _, err := fmt.Fprintln(w, header); err != nil {
+			return err
+		}
+
+		for _, line := range changeLines(version) {
+			if _, err := fmt.Fprintln(w, "    "+line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// changeLines lists the changed values of an update and the set values of a
+// created row, a delete has none.
+func changeLines(version Version) []string {
+	var lines []string
+	switch version.Operation {
+	case entity_history.OperationUpdate:
+		for _, field := range version.ChangedFields {
+			lines = append(lines, fmt.Sprintf("%s: %s -> %s", field, format(version.Before[field]), format(version.After[field])))
+		}
+	case entity_history.OperationCreate:
+		fields := make([]string, 0, len(version.After))
+		for field, value := range version.After {
+			if list, ok := value.([]interface{}); ok && len(list) == 0 {
+				continue
+			}
+			if value != nil && value != "" {
+				fields = append(fields, field)
+			}
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			lines = append(lines, fmt.Sprintf("%s: %s", field, format(version.After[field])))
+		}
+	}
+	return lines
+}
+
+func format(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,52 @@
+package history
+
+import (
+	"context"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Source are the Debezium source coordinates of a change.
+type Source struct {
+	TxID int64
+	Lsn  int64
+	TsMs int64
+}
+
+// Change is a write applied by a pipeline. Before is the stored row before
+// the write, nil when it was created, After the written row, nil when it was
+// deleted.
+type Change struct {
+	EntityType string
+	EntityID   string
+	// AnimeID is the anime of a character, CharacterID the character of a
+	// link.
+	AnimeID     string
+	CharacterID string
+	Before      interface{}
+	After       interface{}
+	Message     *kafka.Message
+	Source      Source
+}
+
+// Snapshot keys of the lists stored next to the row in their own tables, the
+// aliases of characters and staff and the hobbies of staff.
+const (
+	FieldAliases = "aliases"
+	FieldHobbies = "hobby_list"
+)
+
+// Alias is an alias as kept in a snapshot.
+type Alias struct {
+	Language string `json:"language"`
+	Kind     string `json:"kind"`
+	Position int    `json:"position"`
+	Name     string `json:"name"`
+}
+
+// Recorder keeps the history of the rows written by a pipeline.
+type Recorder interface {
+	// Record stores the change, writes that changed no column and no list
+	// are not recorded.
+	Record(ctx context.Context, change Change) error
+}
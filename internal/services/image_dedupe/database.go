@@ -0,0 +1,64 @@
+package image_dedupe
+
+import (
+	"context"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/image_request"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+// DatabaseCache stores the sent requests in the image_request table. With
+// the ledger enabled a request is remembered in the transaction of the
+// message, so a message that is rolled back sends its images again when it
+// is retried.
+type DatabaseCache struct {
+	Repository image_request.ImageRequestRepository
+	ttl        time.Duration
+}
+
+func NewDatabaseCache(database *db.DB, ttl time.Duration) *DatabaseCache {
+	return &DatabaseCache{
+		Repository: image_request.NewImageRequestRepository(database),
+		ttl:        ttl,
+	}
+}
+
+func (c *DatabaseCache) Seen(ctx context.Context, key Key) (bool, error) {
+	return c.Repository.Exists(ctx, key.EntityType, key.EntityID, key.URLHash, time.Now())
+}
+
+func (c *DatabaseCache) Remember(ctx context.Context, key Key) error {
+	return c.Repository.Upsert(ctx, &image_request.ImageRequest{
+		EntityType: key.EntityType,
+		EntityID:   key.EntityID,
+		URLHash:    key.URLHash,
+		ExpiresAt:  time.Now().Add(c.ttl),
+	})
+}
+
+// RunRetention deletes the expired requests every interval until ctx is
+// done.
+func (c *DatabaseCache) RunRetention(ctx context.Context, interval time.Duration) {
+	log := logger.FromCtx(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pruned, err := c.Repository.DeleteExpired(ctx, time.Now())
+		if err != nil {
+			log.Error("Error pruning image requests", zap.Error(err))
+		} else if pruned > 0 {
+			log.Info("Pruned image requests", zap.Int64("rows", pruned))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package image_dedupe
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+// MemoryCache keeps the most recently sent requests of the process, the
+// least recently used ones are evicted first.
+type MemoryCache struct {
+	lru *expirable.LRU[Key, struct{}]
+}
+
+func NewMemoryCache(size int, ttl time.Duration) Cache {
+	return &MemoryCache{lru: expirable.NewLRU[Key, struct{}](size, nil, ttl)}
+}
+
+func (c *MemoryCache) Seen(ctx context.Context, key Key) (bool, error) {
+	_, ok := c.lru.Get(key)
+	return ok, nil
+}
+
+func (c *MemoryCache) Remember(ctx context.Context, key Key) error {
+	c.lru.Add(key, struct{}{})
+	return nil
+}
@@ -0,0 +1,32 @@
+package image_dedupe
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "character-staff-sync:image-request:"
+
+// RedisCache stores the sent requests as redis keys expiring after the TTL.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisCache(client *redis.Client, ttl time.Duration) Cache {
+	return &RedisCache{client: client, ttl: ttl}
+}
+
+func (c *RedisCache) Seen(ctx context.Context, key Key) (bool, error) {
+	count, err := c.client.Exists(ctx, redisKeyPrefix+key.String()).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (c *RedisCache) Remember(ctx context.Context, key Key) error {
+	return c.client.Set(ctx, redisKeyPrefix+key.String(), 1, c.ttl).Err()
+}
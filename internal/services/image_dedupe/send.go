@@ -0,0 +1,45 @@
+package image_dedupe
+
+import (
+	"context"
+
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	resultSent         = "sent"
+	resultDeduplicated = "deduplicated"
+)
+
+// Send calls send unless the request was already sent within the TTL, and
+// remembers it once send succeeded. A nil cache always sends. Cache errors
+// are logged and the request is sent, a duplicate is cheaper than a lost
+// image.
+func Send(ctx context.Context, cache Cache, key Key, send func(ctx context.Context) error) error {
+	if cache == nil {
+		return send(ctx)
+	}
+	log := logger.FromCtx(ctx)
+
+	seen, err := cache.Seen(ctx, key)
+	if err != nil {
+		log.Warn("Error reading image request cache", zap.String("key", key.String()), zap.Error(err))
+	}
+	if seen {
+		log.Info("Skipping image already sent", zap.String("entity", key.EntityType), zap.String("id", key.EntityID))
+		metrics.ImageRequests.WithLabelValues(key.EntityType, resultDeduplicated).Inc()
+		return nil
+	}
+
+	if err := send(ctx); err != nil {
+		return err
+	}
+	metrics.ImageRequests.WithLabelValues(key.EntityType, resultSent).Inc()
+
+	if err := cache.Remember(ctx, key); err != nil {
+		log.Warn("Error writing image request cache", zap.String("key", key.String()), zap.Error(err))
+	}
+	return nil
+}
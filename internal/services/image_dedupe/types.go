@@ -0,0 +1,41 @@
+package image_dedupe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+type Backend = string
+
+const (
+	BackendMemory   Backend = "memory"
+	BackendDatabase Backend = "database"
+	BackendRedis    Backend = "redis"
+)
+
+// Key identifies an image request, the image url of an entity. URLs are
+// hashed after normalization, so the same image always has the same key.
+type Key struct {
+	EntityType string
+	EntityID   string
+	URLHash    string
+}
+
+// NewKey returns the key of the normalized image url of an entity.
+func NewKey(entityType string, entityID string, url string) Key {
+	sum := sha256.Sum256([]byte(url))
+	return Key{EntityType: entityType, EntityID: entityID, URLHash: hex.EncodeToString(sum[:])}
+}
+
+func (k Key) String() string {
+	return k.EntityType + ":" + k.EntityID + ":" + k.URLHash
+}
+
+// Cache remembers the image requests sent to image-sync for a TTL.
+type Cache interface {
+	// Seen reports whether the request was sent within the TTL.
+	Seen(ctx context.Context, key Key) (bool, error)
+	// Remember records a sent request, it is seen until the TTL passed.
+	Remember(ctx context.Context, key Key) error
+}
@@ -0,0 +1,108 @@
+package ledger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/processed_message"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type Ledger interface {
+	// Apply runs apply in a transaction together with recording the entry.
+	// It reports false without running apply when the entry was already
+	// recorded. Functions apply defers with db.AfterCommit run once the
+	// transaction committed.
+	Apply(ctx context.Context, entry Entry, apply func(ctx context.Context) error) (bool, error)
+	Prune(ctx context.Context, before time.Time) (int64, error)
+	// RunRetention prunes entries older than retention every interval until
+	// ctx is done.
+	RunRetention(ctx context.Context, retention time.Duration, interval time.Duration)
+}
+
+type LedgerImpl struct {
+	db         *db.DB
+	Repository processed_message.ProcessedMessageRepository
+}
+
+func NewLedger(database *db.DB) Ledger {
+	return &LedgerImpl{
+		db:         database,
+		Repository: processed_message.NewProcessedMessageRepository(database),
+	}
+}
+
+func (l *LedgerImpl) Apply(ctx context.Context, entry Entry, apply func(ctx context.Context) error) (bool, error) {
+	if entry.MessageKey == "" && entry.SourcePosition == "" {
+		return true, apply(ctx)
+	}
+
+	ctx, commit := db.WithAfterCommit(ctx)
+	recorded := false
+	err := l.db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := db.WithTx(ctx, tx)
+
+		var err error
+		recorded, err = l.Repository.Record(txCtx, newProcessedMessage(entry))
+		if err != nil || !recorded {
+			return err
+		}
+
+		return apply(txCtx)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return recorded, commit()
+}
+
+func (l *LedgerImpl) Prune(ctx context.Context, before time.Time) (int64, error) {
+	return l.Repository.DeleteBefore(ctx, before)
+}
+
+func (l *LedgerImpl) RunRetention(ctx context.Context, retention time.Duration, interval time.Duration) {
+	log := logger.FromCtx(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pruned, err := l.Prune(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Error("Error pruning processed message ledger", zap.Error(err))
+		} else if pruned > 0 {
+			log.Info("Pruned processed message ledger", zap.Int64("rows", pruned))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func newProcessedMessage(entry Entry) *processed_message.ProcessedMessage {
+	sum := sha256.Sum256([]byte(entry.Pipeline + "\x00" + entry.MessageKey + "\x00" + entry.SourcePosition))
+
+	return &processed_message.ProcessedMessage{
+		ID:             hex.EncodeToString(sum[:]),
+		Pipeline:       entry.Pipeline,
+		MessageKey:     nullable(entry.MessageKey),
+		SourcePosition: nullable(entry.SourcePosition),
+		ProcessedAt:    time.Now(),
+	}
+}
+
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
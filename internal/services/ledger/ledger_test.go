@@ -0,0 +1,78 @@
+package ledger_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	migrations "github.com/weeb-vip/character-staff-sync/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/services/ledger"
+)
+
+func newLedger(t *testing.T) ledger.Ledger {
+	t.Helper()
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(t.TempDir(), "ledger.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+	return ledger.NewLedger(database)
+}
+
+func TestApplyPublishesAfterCommit(t *testing.T) {
+	messageLedger := newLedger(t)
+	ctx := context.Background()
+	entry := ledger.Entry{Pipeline: "character", MessageKey: "pulsar:1:2:0"}
+
+	var published []string
+	publish := func(ctx context.Context) error {
+		return db.AfterCommit(ctx, func(ctx context.Context) error {
+			published = append(published, entry.MessageKey)
+			return nil
+		})
+	}
+
+	failed := errors.New("write failed")
+	applied, err := messageLedger.Apply(ctx, entry, func(ctx context.Context) error {
+		if err := publish(ctx); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) || applied {
+		t.Fatalf("Apply = %v, %v, want false, %v", applied, err, failed)
+	}
+	if len(published) != 0 {
+		t.Fatalf("rolled back apply published %v", published)
+	}
+
+	// the failed attempt recorded nothing, the retry applies
+	applied, err = messageLedger.Apply(ctx, entry, publish)
+	if err != nil || !applied {
+		t.Fatalf("Apply = %v, %v, want true, nil", applied, err)
+	}
+	if len(published) != 1 {
+		t.Fatalf("published %v, want one message", published)
+	}
+
+	applied, err = messageLedger.Apply(ctx, entry, publish)
+	if err != nil || applied {
+		t.Fatalf("Apply of a recorded entry = %v, %v, want false, nil", applied, err)
+	}
+	if len(published) != 1 {
+		t.Fatalf("published %v after a redelivery, want one message", published)
+	}
+}
+
+func TestAfterCommitWithoutTransaction(t *testing.T) {
+	ran := false
+	err := db.AfterCommit(context.Background(), func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	if err != nil || !ran {
+		t.Fatalf("AfterCommit without a transaction = %v, ran %v, want it run right away", err, ran)
+	}
+}
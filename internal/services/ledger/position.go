@@ -0,0 +1,94 @@
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+type debeziumSource struct {
+	Db       string      `json:"db"`
+	Schema   string      `json:"schema"`
+	Table    string      `json:"table"`
+	Snapshot interface{} `json:"snapshot"`
+	Lsn      json.Number `json:"lsn"`
+	File     string      `json:"file"`
+	Pos      json.Number `json:"pos"`
+	Row      json.Number `json:"row"`
+}
+
+type debeziumChange struct {
+	Op     string          `json:"op"`
+	Source *debeziumSource `json:"source"`
+}
+
+// KafkaEntry builds the entry of a kafka message, messages without a topic
+// or offset only carry the source position.
+func KafkaEntry(pipeline string, message *kafka.Message) Entry {
+	entry := Entry{
+		Pipeline:       pipeline,
+		SourcePosition: SourcePosition(message.Value),
+	}
+	if message.TopicPartition.Topic != nil && message.TopicPartition.Offset >= 0 {
+		entry.MessageKey = fmt.Sprintf("kafka:%s/%d/%d", *message.TopicPartition.Topic, message.TopicPartition.Partition, message.TopicPartition.Offset)
+	}
+	return entry
+}
+
+// PulsarEntry builds the entry of a pulsar message.
+func PulsarEntry(pipeline string, message pulsar.Message) Entry {
+	return Entry{
+		Pipeline:       pipeline,
+		MessageKey:     fmt.Sprintf("pulsar:%s/%s", message.Topic(), message.ID().String()),
+		SourcePosition: SourcePosition(message.Payload()),
+	}
+}
+
+// SourcePosition returns the position of the change in the source database
+// from a Debezium message with or without the schema envelope. Snapshot
+// reads share a position and return an empty string, as does a message
+// without a known position.
+func SourcePosition(raw []byte) string {
+	var envelope struct {
+		Payload *debeziumChange `json:"payload"`
+	}
+	var change debeziumChange
+
+	if err := json.Unmarshal(raw, &envelope); err == nil && envelope.Payload != nil {
+		change = *envelope.Payload
+	} else if err := json.Unmarshal(raw, &change); err != nil {
+		return ""
+	}
+
+	source := change.Source
+	if source == nil || isSnapshot(source.Snapshot) {
+		return ""
+	}
+
+	var position string
+	switch {
+	case source.Lsn != "" && source.Lsn != "0":
+		position = "lsn:" + source.Lsn.String()
+	case source.File != "":
+		position = fmt.Sprintf("binlog:%s:%s:%s", source.File, source.Pos, source.Row)
+	default:
+		return ""
+	}
+
+	table := strings.Trim(strings.Join([]string{source.Db, source.Schema, source.Table}, "."), ".")
+	return fmt.Sprintf("%s@%s:%s", table, position, change.Op)
+}
+
+func isSnapshot(snapshot interface{}) bool {
+	switch s := snapshot.(type) {
+	case bool:
+		return s
+	case string:
+		return s != "" && s != "false"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,13 @@
+package ledger
+
+// Entry identifies a consumed message for a pipeline. At least one of
+// MessageKey and SourcePosition has to be set for the message to be recorded.
+type Entry struct {
+	Pipeline string
+	// MessageKey is the broker position, topic/partition/offset for kafka and
+	// the message id for pulsar.
+	MessageKey string
+	// SourcePosition is the Debezium source position of the change, it
+	// catches changes that are published again under a new broker position.
+	SourcePosition string
+}
@@ -45,10 +45,28 @@ func (p *Processor[T]) Process(ctx context.Context, payload string, fn Processor
 		return fn(ctx, *data)
 	}
 
-	err = backoff.Retry(operation, backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 10))
+	err = Retry(operation)
 	if err != nil {
 		// Handle error.
 		return err
 	}
 	return nil
 }
+
+// ProcessOnce parses the payload and runs fn once, for callers retrying
+// around a transaction of their own. A payload that cannot be parsed is a
+// permanent error.
+func (p *Processor[T]) ProcessOnce(ctx context.Context, payload string, fn ProcessorFunc[T]) error {
+	log.Println("Processing payload:", payload)
+	data, err := p.Parse(ctx, payload)
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	return fn(ctx, *data)
+}
+
+// Retry runs operation until it succeeds, at most 10 retries with an
+// exponential backoff.
+func Retry(operation func() error) error {
+	return backoff.Retry(operation, backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 10))
+}
@@ -0,0 +1,94 @@
+package pulsar_anime_character_postgres_processor
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Flagsmith/flagsmith-go-client/v2"
+	"github.com/weeb-vip/character-staff-sync/config"
+	migrations "github.com/weeb-vip/character-staff-sync/db"
+	"github.com/weeb-vip/character-staff-sync/internal"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/search_document"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestProcessWritesAliases(t *testing.T) {
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(t.TempDir(), "pulsar.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+	// flags cannot be fetched, kafka stays disabled
+	ctx = context.WithValue(ctx, internal.FFClient{}, flagsmith.NewClient("test", flagsmith.WithBaseURL("http://127.0.0.1:1/api/v1/")))
+
+	processor := NewPulsarAnimeCharacterPostgresProcessor(Options{}, database, nil, nil)
+	aliases := entity_alias.NewEntityAliasRepository(database)
+	str := func(s string) *string {
+		return &s
+	}
+	const id = "0b6f3a4e-5a43-4f57-9d55-0c2f6a1d7c11"
+	row := Schema{
+		Id:         id,
+		AnimeID:    str("anime-1"),
+		Name:       str("Hachikuji Mayoi"),
+		NameNative: str("八九寺 真宵"),
+		Nicknames:  str("Snail girl; Lost child"),
+	}
+
+	names := func() []string {
+		t.Helper()
+		stored, err := aliases.List(ctx, entity_alias.EntityTypeCharacter, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entity_alias.Names(stored)
+	}
+	searchAliases := func() string {
+		t.Helper()
+		var document search_document.SearchDocument
+		if err := database.DB.First(&document, "entity_id = ?", id).Error; err != nil {
+			t.Fatal(err)
+		}
+		return document.Aliases
+	}
+
+	if err := processor.Process(ctx, Payload{After: &row}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"八九寺 真宵", "Snail girl", "Lost child"}
+	if got := names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("aliases after create = %q, want %q", got, want)
+	}
+	if got := searchAliases(); got != "八九寺 真宵, Snail girl, Lost child" {
+		t.Errorf("search document aliases = %q", got)
+	}
+
+	// a source without the alias fields keeps the stored aliases
+	withoutAliases := row
+	withoutAliases.NameNative, withoutAliases.Nicknames = nil, nil
+	withoutAliases.Summary = str("Lost on the way")
+	if err := processor.Process(ctx, Payload{Before: &row, After: &withoutAliases}); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("aliases after an update without aliases = %q, want %q", got, want)
+	}
+
+	renamed := row
+	renamed.Nicknames = str("Snail girl")
+	if err := processor.Process(ctx, Payload{Before: &row, After: &renamed}); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"八九寺 真宵", "Snail girl"}
+	if got := names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("aliases after renaming = %q, want %q", got, want)
+	}
+	if got := searchAliases(); got != "八九寺 真宵, Snail girl" {
+		t.Errorf("search document aliases after renaming = %q", got)
+	}
+}
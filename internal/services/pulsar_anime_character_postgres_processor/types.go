@@ -1,5 +1,7 @@
 package pulsar_anime_character_postgres_processor
 
+import "github.com/weeb-vip/character-staff-sync/internal/debezium"
+
 type Action = string
 
 const (
@@ -8,23 +10,33 @@ const (
 	DeleteAction Action = "delete"
 )
 
+// aliasesField is reported as changed when the aliases change, they are not
+// a column of the row.
+const aliasesField = "aliases"
+
+// Schema is the anime_character row. The name fields and nicknames are
+// optional, when a source sends none of them the stored aliases are kept.
 type Schema struct {
-	Id            string  `json:"id"`
-	AnimeID       *string `json:"anime_id"`
-	Name          *string `json:"name"`
-	Role          *string `json:"role"`
-	Birthday      *string `json:"birthday"`
-	Zodiac        *string `json:"zodiac"`
-	Gender        *string `json:"gender"`
-	Race          *string `json:"race"`
-	Height        *string `json:"height"`
-	Weight        *string `json:"weight"`
-	Title         *string `json:"title"`
-	MartialStatus *string `json:"martial_status"`
-	Summary       *string `json:"summary"`
-	Image         *string `json:"image"`
-	CreatedAt     *int64  `json:"created_at"`
-	UpdatedAt     *int64  `json:"updated_at"`
+	Id            string              `json:"id"`
+	AnimeID       *string             `json:"anime_id"`
+	Name          *string             `json:"name"`
+	Role          *string             `json:"role"`
+	Birthday      *string             `json:"birthday"`
+	Zodiac        *string             `json:"zodiac"`
+	Gender        *string             `json:"gender"`
+	Race          *string             `json:"race"`
+	Height        *string             `json:"height"`
+	Weight        *string             `json:"weight"`
+	Title         *string             `json:"title"`
+	MartialStatus *string             `json:"martial_status"`
+	Summary       *string             `json:"summary"`
+	Image         *string             `json:"image"`
+	NameNative    *string             `json:"name_native,omitempty"`
+	NameRomaji    *string             `json:"name_romaji,omitempty"`
+	NameEnglish   *string             `json:"name_english,omitempty"`
+	Nicknames     *string             `json:"nicknames,omitempty"`
+	CreatedAt     *debezium.Timestamp `json:"created_at"`
+	UpdatedAt     *debezium.Timestamp `json:"updated_at"`
 }
 
 type Source struct {
@@ -3,18 +3,39 @@ package pulsar_anime_character_staff_link_postgres_processor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
-	"time"
 
 	"github.com/weeb-vip/character-staff-sync/internal/db"
 	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
 	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
 	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_redirect"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/field_source"
+	"github.com/weeb-vip/character-staff-sync/internal/debezium"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
 	"github.com/weeb-vip/character-staff-sync/internal/producer"
+	"github.com/weeb-vip/character-staff-sync/internal/services/cast_events"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"github.com/weeb-vip/character-staff-sync/internal/services/sources"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type Options struct {
 	NoErrorOnDelete bool
+	// CastChanges, when set, is told about every written link.
+	CastChanges cast_events.Recorder
+	// Redirects, when set, rewrites the character and staff ids of links to
+	// merged entities.
+	Redirects entity_redirect.Resolver
+	// Sources, when set, keeps the fields owned by a source with a higher
+	// priority than the source of the pipeline.
+	Sources sources.Guard
+	// History, when set, records every applied change.
+	History history.Recorder
 }
 
 type PulsarAnimeCharacterStaffLinkPostgresProcessor interface {
@@ -45,31 +66,233 @@ func (p *PulsarAnimeCharacterStaffLinkPostgresProcessorImpl) Process(ctx context
 		return nil
 	}
 
-	link := &anime_character_staff_link.AnimeCharacterStaffLink{
-		ID:              data.After.ID,
-		CharacterID:     data.After.CharacterID,
-		StaffID:         data.After.StaffID,
-		CharacterName:   ptrToString(data.After.CharacterName),
-		StaffGivenName:  ptrToString(data.After.StaffGivenName),
-		StaffFamilyName: ptrToString(data.After.StaffFamilyName),
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+	if err := p.followRedirects(ctx, &data); err != nil {
+		return err
+	}
+
+	link := p.parseToEntity(*data.After)
+	protected, claimed, err := p.protect(ctx, link)
+	if err != nil {
+		return err
+	}
+	changed, _, err := p.changes(ctx, link, data.Before)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		log.Println("INFO: skipping unchanged link", link.ID)
+		return nil
 	}
 
-	err := p.LinkRepo.Upsert(link)
+	before, err := p.historyBefore(ctx, link.ID)
 	if err != nil {
 		return err
 	}
+	err = p.LinkRepo.Upsert(ctx, link)
+	if err != nil {
+		return err
+	}
+	if err := p.recordHistory(ctx, data, before, link); err != nil {
+		return err
+	}
+	if err := p.claim(ctx, link, claimed); err != nil {
+		return err
+	}
+	if p.Options.CastChanges != nil {
+		change := cast_events.ChangeUpdated
+		if data.Before == nil {
+			change = cast_events.ChangeAdded
+		}
+		if err := p.Options.CastChanges.LinkChanged(ctx, link.CharacterID, link.StaffID, change); err != nil {
+			return err
+		}
+	}
 
 	jsonLink, err := json.Marshal(ProducerPayload{
 		Action: CreateAction,
-		Data:   data.After,
+		Data:   written(data.After, link, protected),
 	})
 	if err != nil {
 		return err
 	}
 
-	return p.Producer.Send(ctx, jsonLink)
+	return db.AfterCommit(ctx, func(ctx context.Context) error {
+		return p.Producer.Send(ctx, jsonLink)
+	})
+}
+
+// followRedirects points the character and staff ids of the before and after
+// images to the entities they were merged into.
+func (p *PulsarAnimeCharacterStaffLinkPostgresProcessorImpl) followRedirects(ctx context.Context, data *Payload) error {
+	if p.Options.Redirects == nil {
+		return nil
+	}
+
+	for _, row := range []**Schema{&data.Before, &data.After} {
+		if *row == nil {
+			continue
+		}
+		characterID, err := p.Options.Redirects.Resolve(ctx, entity_redirect.EntityTypeCharacter, (*row).CharacterID)
+		if err != nil {
+			return err
+		}
+		staffID, err := p.Options.Redirects.Resolve(ctx, entity_redirect.EntityTypeStaff, (*row).StaffID)
+		if err != nil {
+			return err
+		}
+		if characterID != (*row).CharacterID || staffID != (*row).StaffID {
+			rewritten := **row
+			rewritten.CharacterID = characterID
+			rewritten.StaffID = staffID
+			*row = &rewritten
+		}
+	}
+	return nil
+}
+
+// protect keeps the stored values of the fields owned by a source with a
+// higher priority than the source of the pipeline. It returns the protected
+// fields and the fields the link changes, all of them when it is not stored
+// yet, which the source claims once the link is written.
+func (p *PulsarAnimeCharacterStaffLinkPostgresProcessorImpl) protect(ctx context.Context, next *anime_character_staff_link.AnimeCharacterStaffLink) ([]string, []string, error) {
+	if p.Options.Sources == nil {
+		return nil, nil, nil
+	}
+	stored, err := p.LinkRepo.FindByID(ctx, next.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		changed, _, err := db.Changes(nil, next)
+		return nil, changed, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed, _, err := db.Changes(stored, next)
+	if err != nil {
+		return nil, nil, err
+	}
+	protected, err := p.Options.Sources.Protect(ctx, field_source.EntityTypeLink, next.ID, stored, next, changed)
+	if err != nil || len(protected) == 0 {
+		return nil, changed, err
+	}
+
+	source := p.Options.Sources.Source().Name
+	for _, field := range protected {
+		metrics.ProtectedFields.WithLabelValues(field_source.EntityTypeLink, source, field).Inc()
+	}
+	logger.FromCtx(ctx).Info("Keeping fields owned by a higher priority source",
+		zap.String("ID", next.ID), zap.String("source", source), zap.Strings("fields", protected))
+
+	changed, _, err = db.Changes(stored, next)
+	return protected, changed, err
+}
+
+// claim records the source of the pipeline as the owner of the written
+// fields.
+func (p *PulsarAnimeCharacterStaffLinkPostgresProcessorImpl) claim(ctx context.Context, link *anime_character_staff_link.AnimeCharacterStaffLink, fields []string) error {
+	if p.Options.Sources == nil {
+		return nil
+	}
+	return p.Options.Sources.Claim(ctx, field_source.EntityTypeLink, link.ID, link, fields)
+}
+
+// historyBefore returns the stored link the history records as the state
+// before a write, nil when the history is disabled or it is not stored.
+func (p *PulsarAnimeCharacterStaffLinkPostgresProcessorImpl) historyBefore(ctx context.Context, id string) (*anime_character_staff_link.AnimeCharacterStaffLink, error) {
+	if p.Options.History == nil {
+		return nil, nil
+	}
+	stored, err := p.LinkRepo.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return stored, err
+}
+
+// recordHistory records the write of the message, the newer of before and
+// after identifies the link.
+func (p *PulsarAnimeCharacterStaffLinkPostgresProcessorImpl) recordHistory(ctx context.Context, data Payload, before *anime_character_staff_link.AnimeCharacterStaffLink, after *anime_character_staff_link.AnimeCharacterStaffLink) error {
+	if p.Options.History == nil {
+		return nil
+	}
+	source := history.Source{
+		TxID: int64(data.Source.TxId),
+		Lsn:  int64(data.Source.Lsn),
+		TsMs: data.Source.TsMs,
+	}
+	change := history.Change{
+		EntityType: entity_history.EntityTypeLink,
+		Before:     before,
+		After:      after,
+		Source:     source,
+	}
+	for _, row := range []*anime_character_staff_link.AnimeCharacterStaffLink{before, after} {
+		if row != nil {
+			change.EntityID, change.CharacterID = row.ID, row.CharacterID
+		}
+	}
+	return p.Options.History.Record(ctx, change)
+}
+
+// changes compares the link with the stored one, see db.StoredChanges.
+func (p *PulsarAnimeCharacterStaffLinkPostgresProcessorImpl) changes(ctx context.Context, next *anime_character_staff_link.AnimeCharacterStaffLink, before *Schema) ([]string, map[string]interface{}, error) {
+	var previous *anime_character_staff_link.AnimeCharacterStaffLink
+	if before != nil {
+		previous = p.parseToEntity(*before)
+	}
+	find := func() (*anime_character_staff_link.AnimeCharacterStaffLink, error) {
+		return p.LinkRepo.FindByID(ctx, next.ID)
+	}
+	diff := func(previous *anime_character_staff_link.AnimeCharacterStaffLink, next *anime_character_staff_link.AnimeCharacterStaffLink) ([]string, map[string]interface{}, error) {
+		return db.Changes(previous, next)
+	}
+	return db.StoredChanges(next, previous, find, diff)
+}
+
+// written is the after image with the protected fields set to the stored
+// values that were kept, the link as it was written.
+func written(after *Schema, link *anime_character_staff_link.AnimeCharacterStaffLink, protected []string) *Schema {
+	if len(protected) == 0 {
+		return after
+	}
+	row := *after
+	for _, field := range protected {
+		switch field {
+		case "character_id":
+			row.CharacterID = link.CharacterID
+		case "staff_id":
+			row.StaffID = link.StaffID
+		case "character_name":
+			row.CharacterName = &link.CharacterName
+		case "staff_given_name":
+			row.StaffGivenName = &link.StaffGivenName
+		case "staff_family_name":
+			row.StaffFamilyName = &link.StaffFamilyName
+		case "language":
+			row.Language = &link.Language
+		case "role_type":
+			row.RoleType = &link.RoleType
+		case "notes":
+			row.Notes = link.Notes
+		}
+	}
+	return &row
+}
+
+func (p *PulsarAnimeCharacterStaffLinkPostgresProcessorImpl) parseToEntity(data Schema) *anime_character_staff_link.AnimeCharacterStaffLink {
+	return &anime_character_staff_link.AnimeCharacterStaffLink{
+		ID:              data.ID,
+		CharacterID:     data.CharacterID,
+		StaffID:         data.StaffID,
+		CharacterName:   ptrToString(data.CharacterName),
+		StaffGivenName:  ptrToString(data.StaffGivenName),
+		StaffFamilyName: ptrToString(data.StaffFamilyName),
+		Language:        ptrToString(data.Language),
+		RoleType:        roleType(data.RoleType),
+		Notes:           data.Notes,
+		CreatedAt:       debezium.CreatedAt(data.CreatedAt),
+		UpdatedAt:       debezium.UpdatedAt(data.UpdatedAt),
+	}
 }
 
 func ptrToString(s *string) string {
@@ -78,3 +301,12 @@ func ptrToString(s *string) string {
 	}
 	return *s
 }
+
+// roleType defaults to a voice credit, producers that predate role types
+// only sent voice acting links.
+func roleType(s *string) string {
+	if s == nil || *s == "" {
+		return anime_character_staff_link.RoleTypeVoice
+	}
+	return *s
+}
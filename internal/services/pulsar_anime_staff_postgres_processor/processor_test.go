@@ -0,0 +1,60 @@
+package pulsar_anime_staff_postgres_processor
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Flagsmith/flagsmith-go-client/v2"
+	"github.com/weeb-vip/character-staff-sync/config"
+	migrations "github.com/weeb-vip/character-staff-sync/db"
+	"github.com/weeb-vip/character-staff-sync/internal"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestProcessWritesAliases(t *testing.T) {
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(t.TempDir(), "pulsar.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+	// flags cannot be fetched, kafka stays disabled
+	ctx = context.WithValue(ctx, internal.FFClient{}, flagsmith.NewClient("test", flagsmith.WithBaseURL("http://127.0.0.1:1/api/v1/")))
+
+	processor := NewPulsarAnimeStaffPostgresProcessor(Options{}, database, nil, nil)
+	str := func(s string) *string {
+		return &s
+	}
+	const id = "5d1c0f1e-8a2b-4c3d-9e4f-a0b1c2d3e4f5"
+	row := Schema{
+		Id:         id,
+		Language:   str("Japanese"),
+		GivenName:  str("Emiri"),
+		FamilyName: str("Katou"),
+		NameNative: str("加藤 英美里"),
+		NameRomaji: str("Katou Emiri"),
+	}
+	if err := processor.Process(ctx, Payload{After: &row}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := entity_alias.NewEntityAliasRepository(database).List(ctx, entity_alias.EntityTypeStaff, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []entity_alias.EntityAlias{
+		{Language: "ja", Kind: entity_alias.KindNative, Name: "加藤 英美里"},
+		{Language: "ja-Latn", Kind: entity_alias.KindRomaji, Name: "Katou Emiri"},
+	}
+	got := make([]entity_alias.EntityAlias, len(stored))
+	for i, alias := range stored {
+		got[i] = entity_alias.EntityAlias{Language: alias.Language, Kind: alias.Kind, Name: alias.Name}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("aliases = %+v, want %+v", got, want)
+	}
+}
@@ -1,5 +1,7 @@
 package pulsar_anime_staff_postgres_processor
 
+import "github.com/weeb-vip/character-staff-sync/internal/debezium"
+
 type Action = string
 
 const (
@@ -8,19 +10,30 @@ const (
 	DeleteAction Action = "delete"
 )
 
+// aliasesField is reported as changed when the aliases change, they are not
+// a column of the row.
+const aliasesField = "aliases"
+
 type Schema struct {
-	Id         string  `json:"id"`
-	Language   *string `json:"language"`
-	GivenName  *string `json:"given_name"`
-	FamilyName *string `json:"family_name"`
-	Image      *string `json:"image"`
-	Birthday   *string `json:"birthday"`
-	BirthPlace *string `json:"birth_place"`
-	BloodType  *string `json:"blood_type"`
-	Hobbies    *string `json:"hobbies"`
-	Summary    *string `json:"summary"`
-	CreatedAt  *int64  `json:"created_at"`
-	UpdatedAt  *int64  `json:"updated_at"`
+	Id         string              `json:"id"`
+	Language   *string             `json:"language"`
+	GivenName  *string             `json:"given_name"`
+	FamilyName *string             `json:"family_name"`
+	Image      *string             `json:"image"`
+	Birthday   *string             `json:"birthday"`
+	BirthPlace *string             `json:"birth_place"`
+	BloodType  *string             `json:"blood_type"`
+	Hobbies    *string             `json:"hobbies"`
+	Summary    *string             `json:"summary"`
+	CreatedAt  *debezium.Timestamp `json:"created_at"`
+	UpdatedAt  *debezium.Timestamp `json:"updated_at"`
+
+	// optional names in other scripts and nicknames, when a source sends
+	// none of them the stored aliases are kept
+	NameNative  *string `json:"name_native,omitempty"`
+	NameRomaji  *string `json:"name_romaji,omitempty"`
+	NameEnglish *string `json:"name_english,omitempty"`
+	Nicknames   *string `json:"nicknames,omitempty"`
 }
 
 type Source struct {
@@ -0,0 +1,181 @@
+package rebuild
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"go.uber.org/zap"
+)
+
+type Rebuilder struct {
+	History entity_history.EntityHistoryRepository
+	Target  Target
+}
+
+func NewRebuilder(history entity_history.EntityHistoryRepository, target Target) *Rebuilder {
+	return &Rebuilder{
+		History: history,
+		Target:  target,
+	}
+}
+
+// Plan compares the selected entities in the target with their state at the
+// rebuild time, the columns and the aliases and hobbies loaded on both sides.
+// Entities without recorded changes are left out, they did not change since
+// the history was enabled.
+func (r *Rebuilder) Plan(ctx context.Context, opt Options) ([]Step, error) {
+	selected, err := r.selectEntities(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []Step{}
+	for _, entity := range selected {
+		row, known, err := r.stateAt(ctx, entity.entityType, entity.id, opt)
+		if err != nil {
+			return nil, err
+		}
+		if !known {
+			continue
+		}
+		current, err := r.Target.Find(ctx, entity.entityType, entity.id)
+		if err != nil {
+			return nil, err
+		}
+
+		step := Step{EntityType: entity.entityType, EntityID: entity.id, row: row, current: current}
+		switch {
+		case row == nil && current == nil:
+			step.Action = ActionUnchanged
+		case row == nil:
+			step.Action = ActionDelete
+		case current == nil:
+			step.Action = ActionRestore
+		default:
+			changed, _, err := db.Changes(current, row)
+			if err != nil {
+				return nil, err
+			}
+			changed = append(changed, history.ChangedLists(current, row)...)
+			step.Action = ActionUnchanged
+			if len(changed) > 0 {
+				step.Action = ActionRestore
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// Apply writes the restored rows and deletes the rows that did not exist at
+// the rebuild time.
+func (r *Rebuilder) Apply(ctx context.Context, steps []Step) error {
+	log := logger.FromCtx(ctx)
+	for _, step := range steps {
+		var err error
+		switch step.Action {
+		case ActionRestore:
+			err = r.Target.Write(ctx, step.current, step.row)
+		case ActionDelete:
+			err = r.Target.Delete(ctx, step.current)
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s %s %s: %w", step.Action, step.EntityType, step.EntityID, err)
+		}
+		log.Info("Rebuilt entity", zap.String("entityType", step.EntityType),
+			zap.String("ID", step.EntityID), zap.String("action", step.Action))
+	}
+	return nil
+}
+
+type entityRef struct {
+	entityType string
+	id         string
+}
+
+// selectEntities returns the entity of the options or the characters of the
+// anime followed by their links.
+func (r *Rebuilder) selectEntities(ctx context.Context, opt Options) ([]entityRef, error) {
+	if opt.EntityID != "" {
+		if _, err := newRow(opt.EntityType); err != nil {
+			return nil, err
+		}
+		return []entityRef{{entityType: opt.EntityType, id: opt.EntityID}}, nil
+	}
+	if opt.AnimeID == "" {
+		return nil, fmt.Errorf("an entity id or anime id is required")
+	}
+
+	characterIDs, err := r.History.ListEntityIDs(ctx, entity_history.EntityTypeCharacter, entity_history.ListFilter{AnimeID: opt.AnimeID})
+	if err != nil {
+		return nil, err
+	}
+	linkIDs, err := r.History.ListEntityIDs(ctx, entity_history.EntityTypeLink, entity_history.ListFilter{CharacterIDs: characterIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]entityRef, 0, len(characterIDs)+len(linkIDs))
+	for _, id := range characterIDs {
+		selected = append(selected, entityRef{entityType: entity_history.EntityTypeCharacter, id: id})
+	}
+	for _, id := range linkIDs {
+		selected = append(selected, entityRef{entityType: entity_history.EntityTypeLink, id: id})
+	}
+	return selected, nil
+}
+
+// stateAt returns the entity as it was at the rebuild time: the row after the
+// last change recorded by then, or the row before the first change recorded
+// later. It is nil when the entity did not exist and known is false when no
+// change of the entity was recorded.
+func (r *Rebuilder) stateAt(ctx context.Context, entityType string, entityID string, opt Options) (interface{}, bool, error) {
+	last, next, err := r.History.FindAround(ctx, entityType, entityID, opt.At)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var snapshot string
+	switch {
+	case last != nil:
+		snapshot = last.After
+	case next != nil:
+		snapshot = next.Before
+	default:
+		return nil, false, nil
+	}
+	if snapshot == "" {
+		return nil, true, nil
+	}
+
+	row, err := newRow(entityType)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := history.DecodeSnapshot([]byte(snapshot), row); err != nil {
+		return nil, false, fmt.Errorf("invalid snapshot of %s %s: %w", entityType, entityID, err)
+	}
+	return row, true, nil
+}
+
+func newRow(entityType string) (interface{}, error) {
+	switch entityType {
+	case entity_history.EntityTypeCharacter:
+		return &anime_character.AnimeCharacter{}, nil
+	case entity_history.EntityTypeStaff:
+		return &anime_staff.AnimeStaff{}, nil
+	case entity_history.EntityTypeLink:
+		return &anime_character_staff_link.AnimeCharacterStaffLink{}, nil
+	default:
+		return nil, fmt.Errorf("unknown entity type %q", entityType)
+	}
+}
@@ -0,0 +1,180 @@
+package rebuild_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	migrations "github.com/weeb-vip/character-staff-sync/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"github.com/weeb-vip/character-staff-sync/internal/services/rebuild"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestRebuildAnimeFromHistory(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(t.TempDir(), "rebuild.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+	historyRepo := entity_history.NewEntityHistoryRepository(database)
+	characters := anime_character.NewAnimeCharacterRepository(database)
+	links := anime_character_staff_link.NewAnimeCharacterStaffLinkRepository(database)
+	// writes through the live target are recorded like the pipelines record them
+	live := rebuild.NewLiveTarget(database, history.NewRecorder(historyRepo, "test"))
+
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	find := func(entityType string, id string) interface{} {
+		t.Helper()
+		row, err := live.Find(ctx, entityType, id)
+		must(err)
+		return row
+	}
+	// history times are compared with the rebuild time, keep them apart
+	tick := func() time.Time {
+		time.Sleep(2 * time.Millisecond)
+		now := time.Now()
+		time.Sleep(2 * time.Millisecond)
+		return now
+	}
+
+	edward := &anime_character.AnimeCharacter{ID: "edward", AnimeID: "anime", Name: "Edward Elric", Role: "Main", Aliases: []entity_alias.EntityAlias{
+		{Language: entity_alias.LanguageJapanese, Kind: entity_alias.KindNative, Name: "エドワード・エルリック"},
+	}}
+	must(live.Write(ctx, nil, edward))
+	must(live.Write(ctx, nil, &anime_character_staff_link.AnimeCharacterStaffLink{ID: "park", CharacterID: "edward", StaffID: "romi", StaffGivenName: "Romi", StaffFamilyName: "Park", Language: "Japanese", RoleType: anime_character_staff_link.RoleTypeVoice}))
+	stored, err := characters.FindByID(ctx, "edward")
+	must(err)
+	createdAt := stored.CreatedAt
+
+	at := tick()
+
+	must(live.Write(ctx, find(entity_history.EntityTypeCharacter, "edward"), &anime_character.AnimeCharacter{ID: "edward", AnimeID: "anime", Name: "Ed", Role: "Supporting", Aliases: []entity_alias.EntityAlias{}}))
+	must(live.Delete(ctx, find(entity_history.EntityTypeLink, "park")))
+	must(live.Write(ctx, nil, &anime_character_staff_link.AnimeCharacterStaffLink{ID: "mignogna", CharacterID: "edward", StaffID: "vic", StaffGivenName: "Vic", StaffFamilyName: "Mignogna", Language: "English", RoleType: anime_character_staff_link.RoleTypeVoice}))
+	must(live.Write(ctx, nil, &anime_character.AnimeCharacter{ID: "winry", AnimeID: "anime", Name: "Winry Rockbell", Role: "Supporting"}))
+	// rows written without history are not rebuilt
+	must(characters.Upsert(ctx, &anime_character.AnimeCharacter{ID: "untracked", AnimeID: "anime", Name: "Untracked", Role: "Main"}))
+
+	rebuilder := rebuild.NewRebuilder(historyRepo, live)
+	opt := rebuild.Options{At: at, AnimeID: "anime"}
+	steps, err := rebuilder.Plan(ctx, opt)
+	must(err)
+
+	got := map[string]string{}
+	for _, step := range steps {
+		got[step.EntityType+" "+step.EntityID] = step.Action
+	}
+	want := map[string]string{
+		"character edward": rebuild.ActionRestore,
+		"character winry":  rebuild.ActionDelete,
+		"link park":        rebuild.ActionRestore,
+		"link mignogna":    rebuild.ActionDelete,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("plan = %v, want %v", got, want)
+	}
+
+	must(rebuilder.Apply(ctx, steps))
+
+	restored, err := characters.FindByID(ctx, "edward")
+	must(err)
+	if restored.Name != "Edward Elric" || restored.Role != "Main" {
+		t.Errorf("edward = %q %q, want %q %q", restored.Name, restored.Role, "Edward Elric", "Main")
+	}
+	if names := entity_alias.Names(restored.Aliases); !reflect.DeepEqual(names, []string{"エドワード・エルリック"}) {
+		t.Errorf("edward aliases = %q, want the native name", names)
+	}
+	if !restored.CreatedAt.Equal(createdAt) {
+		t.Errorf("edward created_at = %s, want %s", restored.CreatedAt, createdAt)
+	}
+	if _, err := links.FindByID(ctx, "park"); err != nil {
+		t.Errorf("restored link: %v", err)
+	}
+	if _, err := characters.FindByID(ctx, "winry"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("character winry after rebuild: %v, want not found", err)
+	}
+	if _, err := links.FindByID(ctx, "mignogna"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("link mignogna after rebuild: %v, want not found", err)
+	}
+	if _, err := characters.FindByID(ctx, "untracked"); err != nil {
+		t.Errorf("untracked character after rebuild: %v", err)
+	}
+
+	// the rebuild was recorded, planning the same time again changes nothing
+	steps, err = rebuilder.Plan(ctx, opt)
+	must(err)
+	for _, step := range steps {
+		if step.Action != rebuild.ActionUnchanged {
+			t.Errorf("second plan %s %s = %s, want unchanged", step.EntityType, step.EntityID, step.Action)
+		}
+	}
+}
+
+func TestRebuildIntoFreshTables(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(t.TempDir(), "rebuild.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+	historyRepo := entity_history.NewEntityHistoryRepository(database)
+	characters := anime_character.NewAnimeCharacterRepository(database)
+	live := rebuild.NewLiveTarget(database, history.NewRecorder(historyRepo, "test"))
+
+	if err := live.Write(ctx, nil, &anime_character.AnimeCharacter{ID: "edward", AnimeID: "anime", Name: "Edward Elric", Role: "Main"}); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh, err := rebuild.NewFreshTarget(database, "rebuilt_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebuilder := rebuild.NewRebuilder(historyRepo, fresh)
+	steps, err := rebuilder.Plan(ctx, rebuild.Options{At: time.Now(), EntityType: entity_history.EntityTypeCharacter, EntityID: "edward"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].Action != rebuild.ActionRestore {
+		t.Fatalf("plan = %+v, want one restore", steps)
+	}
+	if err := rebuilder.Apply(ctx, steps); err != nil {
+		t.Fatal(err)
+	}
+
+	row, err := fresh.Find(ctx, entity_history.EntityTypeCharacter, "edward")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if character, ok := row.(*anime_character.AnimeCharacter); !ok || character.Name != "Edward Elric" {
+		t.Errorf("rebuilt row = %+v, want Edward Elric", row)
+	}
+
+	entries, err := historyRepo.List(ctx, entity_history.EntityTypeCharacter, "edward", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("history has %d entries, a fresh rebuild records none", len(entries))
+	}
+	if _, err := characters.FindByID(ctx, "edward"); err != nil {
+		t.Errorf("live row: %v", err)
+	}
+}
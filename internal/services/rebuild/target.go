@@ -0,0 +1,171 @@
+package rebuild
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/services/attributes"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// LiveTarget writes the live tables through the repositories, so search
+// documents, the cast view and hobbies follow. Every write is recorded in the
+// history when History is set.
+type LiveTarget struct {
+	Characters anime_character.AnimeCharacterRepository
+	Staff      anime_staff.AnimeStaffRepository
+	Links      anime_character_staff_link.AnimeCharacterStaffLinkRepository
+	History    history.Recorder
+}
+
+func NewLiveTarget(database *db.DB, recorder history.Recorder) Target {
+	return &LiveTarget{
+		Characters: anime_character.NewAnimeCharacterRepository(database),
+		Staff:      anime_staff.NewAnimeStaffRepository(database),
+		Links:      anime_character_staff_link.NewAnimeCharacterStaffLinkRepository(database),
+		History:    recorder,
+	}
+}
+
+func (t *LiveTarget) Find(ctx context.Context, entityType string, entityID string) (interface{}, error) {
+	var row interface{}
+	var err error
+	switch entityType {
+	case entity_history.EntityTypeCharacter:
+		row, err = t.Characters.FindByID(ctx, entityID)
+	case entity_history.EntityTypeStaff:
+		row, err = t.Staff.FindByID(ctx, entityID)
+	case entity_history.EntityTypeLink:
+		row, err = t.Links.FindByID(ctx, entityID)
+	default:
+		return nil, fmt.Errorf("unknown entity type %q", entityType)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+func (t *LiveTarget) Write(ctx context.Context, current interface{}, row interface{}) error {
+	var err error
+	switch row := row.(type) {
+	case *anime_character.AnimeCharacter:
+		err = t.Characters.Upsert(ctx, row)
+	case *anime_staff.AnimeStaff:
+		if row.HobbyList == nil {
+			// snapshots recorded before the hobby list was kept
+			attributes.NormalizeStaff(ctx, row)
+		}
+		err = t.Staff.Upsert(ctx, row)
+	case *anime_character_staff_link.AnimeCharacterStaffLink:
+		err = t.Links.Upsert(ctx, row)
+	default:
+		return fmt.Errorf("cannot write %T", row)
+	}
+	if err != nil {
+		return err
+	}
+	return t.record(ctx, current, row)
+}
+
+func (t *LiveTarget) Delete(ctx context.Context, current interface{}) error {
+	var err error
+	switch current := current.(type) {
+	case *anime_character.AnimeCharacter:
+		err = t.Characters.Delete(ctx, current)
+	case *anime_staff.AnimeStaff:
+		err = t.Staff.Delete(ctx, current)
+	case *anime_character_staff_link.AnimeCharacterStaffLink:
+		err = t.Links.Delete(ctx, current)
+	default:
+		return fmt.Errorf("cannot delete %T", current)
+	}
+	if err != nil {
+		return err
+	}
+	return t.record(ctx, current, nil)
+}
+
+func (t *LiveTarget) record(ctx context.Context, before interface{}, after interface{}) error {
+	if t.History == nil {
+		return nil
+	}
+	change := history.Change{Before: before, After: after}
+	for _, row := range []interface{}{before, after} {
+		switch row := row.(type) {
+		case *anime_character.AnimeCharacter:
+			change.EntityType, change.EntityID, change.AnimeID = entity_history.EntityTypeCharacter, row.ID, row.AnimeID
+		case *anime_staff.AnimeStaff:
+			change.EntityType, change.EntityID = entity_history.EntityTypeStaff, row.ID
+		case *anime_character_staff_link.AnimeCharacterStaffLink:
+			change.EntityType, change.EntityID, change.CharacterID = entity_history.EntityTypeLink, row.ID, row.CharacterID
+		}
+	}
+	return t.History.Record(ctx, change)
+}
+
+// FreshTarget writes copies of the tables named with a prefix, created on
+// first use, and leaves the live tables alone.
+type FreshTarget struct {
+	db     *db.DB
+	prefix string
+}
+
+func NewFreshTarget(database *db.DB, prefix string) (Target, error) {
+	if prefix == "" {
+		return nil, fmt.Errorf("a table prefix is required")
+	}
+	target := &FreshTarget{db: database, prefix: prefix}
+	for _, entityType := range []string{entity_history.EntityTypeCharacter, entity_history.EntityTypeStaff, entity_history.EntityTypeLink} {
+		row, _ := newRow(entityType)
+		table := target.table(row)
+		migrator := database.DB.Table(table).Migrator()
+		if migrator.HasTable(table) {
+			continue
+		}
+		if err := migrator.CreateTable(row); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", table, err)
+		}
+	}
+	return target, nil
+}
+
+func (t *FreshTarget) table(row interface{}) string {
+	return t.prefix + row.(schema.Tabler).TableName()
+}
+
+func (t *FreshTarget) Find(ctx context.Context, entityType string, entityID string) (interface{}, error) {
+	row, err := newRow(entityType)
+	if err != nil {
+		return nil, err
+	}
+	result := t.db.Conn(ctx).Table(t.table(row)).Where("id = ?", entityID).Limit(1).Find(row)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return row, nil
+}
+
+// Write upserts the row like the repositories, created_at keeps the time the
+// row was first written to the table.
+func (t *FreshTarget) Write(ctx context.Context, current interface{}, row interface{}) error {
+	return t.db.UpsertTable(ctx, t.table(row), row)
+}
+
+func (t *FreshTarget) Delete(ctx context.Context, current interface{}) error {
+	return t.db.Conn(ctx).Table(t.table(current)).Delete(current).Error
+}
@@ -0,0 +1,41 @@
+package rebuild
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	ActionRestore   = "restore"
+	ActionDelete    = "delete"
+	ActionUnchanged = "unchanged"
+)
+
+// Options selects what is rebuilt, one entity by EntityType and EntityID or
+// the characters and links of AnimeID, as they were at At.
+type Options struct {
+	At         time.Time
+	EntityType string
+	EntityID   string
+	AnimeID    string
+}
+
+// Step brings an entity of the target back to its state at the rebuild
+// time.
+type Step struct {
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	Action     string `json:"action"`
+	// row is the entity at the rebuild time, nil when it did not exist, and
+	// current the entity in the target, nil when it is not stored
+	row     interface{}
+	current interface{}
+}
+
+// Target is where the rebuilt rows are written.
+type Target interface {
+	// Find returns the stored entity, nil when it is not stored.
+	Find(ctx context.Context, entityType string, entityID string) (interface{}, error)
+	Write(ctx context.Context, current interface{}, row interface{}) error
+	Delete(ctx context.Context, current interface{}) error
+}
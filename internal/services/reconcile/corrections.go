@@ -0,0 +1,64 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Correction is a Debezium style payload that brings the target in line with
+// the source when run through the pipeline processor.
+type Correction struct {
+	Before map[string]string `json:"before"`
+	After  map[string]string `json:"after"`
+	Source map[string]string `json:"source"`
+}
+
+// BuildCorrections turns missing and changed rows into upserts and extra rows
+// into deletes.
+func BuildCorrections(diffs []Diff) []Correction {
+	corrections := make([]Correction, 0, len(diffs))
+	for _, diff := range diffs {
+		correction := Correction{
+			Source: map[string]string{"connector": "reconcile"},
+		}
+		switch diff.Kind {
+		case DiffMissing:
+			correction.After = diff.Source.Fields
+		case DiffChanged:
+			correction.Before = diff.Target.Fields
+			correction.After = diff.Source.Fields
+		case DiffExtra:
+			correction.Before = diff.Target.Fields
+		default:
+			continue
+		}
+		corrections = append(corrections, correction)
+	}
+	return corrections
+}
+
+// WriteCorrections writes one correction per line, the file can be fed to the
+// replay command as decoded messages.
+func WriteCorrections(path string, corrections []Correction) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	for _, correction := range corrections {
+		if err := encoder.Encode(correction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func WriteReport(path string, report *Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,175 @@
+package reconcile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+type Options struct {
+	// RangeSize is the number of source rows per checksum range.
+	RangeSize int
+}
+
+type Reconciler interface {
+	Reconcile(ctx context.Context, source []Row) (*Report, error)
+}
+
+type ReconcilerImpl struct {
+	Table   Table
+	Target  Target
+	Options Options
+}
+
+func NewReconciler(opt Options, table Table, target Target) Reconciler {
+	return &ReconcilerImpl{
+		Table:   table,
+		Target:  target,
+		Options: opt,
+	}
+}
+
+// Reconcile splits the sorted source rows into id ranges and compares the
+// checksum of every range with the same range of the target. Only ranges with
+// a different checksum are compared row by row. A final open ended range
+// catches target rows past the last source id.
+func (r *ReconcilerImpl) Reconcile(ctx context.Context, source []Row) (*Report, error) {
+	log := logger.FromCtx(ctx)
+
+	if r.Options.RangeSize <= 0 {
+		return nil, fmt.Errorf("range size must be positive")
+	}
+
+	report := &Report{
+		Table:      r.Table,
+		SourceRows: len(source),
+	}
+
+	afterID := ""
+	for start := 0; start < len(source); start += r.Options.RangeSize {
+		end := start + r.Options.RangeSize
+		if end > len(source) {
+			end = len(source)
+		}
+		chunk := source[start:end]
+		throughID := chunk[len(chunk)-1].ID
+
+		if err := r.compareRange(ctx, report, afterID, throughID, chunk); err != nil {
+			return nil, err
+		}
+		afterID = throughID
+	}
+
+	if err := r.compareRange(ctx, report, afterID, "", nil); err != nil {
+		return nil, err
+	}
+
+	for _, diff := range report.Diffs {
+		switch diff.Kind {
+		case DiffMissing:
+			report.Missing++
+		case DiffExtra:
+			report.Extra++
+		case DiffChanged:
+			report.Changed++
+		}
+	}
+
+	log.Info("Reconciliation finished", zap.String("table", r.Table),
+		zap.Int("sourceRows", report.SourceRows),
+		zap.Int("targetRows", report.TargetRows),
+		zap.Int("missing", report.Missing),
+		zap.Int("extra", report.Extra),
+		zap.Int("changed", report.Changed))
+
+	return report, nil
+}
+
+func (r *ReconcilerImpl) compareRange(ctx context.Context, report *Report, afterID string, throughID string, source []Row) error {
+	log := logger.FromCtx(ctx)
+
+	target, err := r.Target.ListIDRange(ctx, afterID, throughID)
+	if err != nil {
+		return err
+	}
+	report.TargetRows += len(target)
+
+	result := RangeResult{
+		AfterID:        afterID,
+		ThroughID:      throughID,
+		SourceRows:     len(source),
+		TargetRows:     len(target),
+		SourceChecksum: Checksum(r.Table, source),
+		TargetChecksum: Checksum(r.Table, target),
+	}
+	result.Match = result.SourceChecksum == result.TargetChecksum
+	report.Ranges = append(report.Ranges, result)
+
+	if result.Match {
+		return nil
+	}
+
+	log.Info("Checksum mismatch, comparing rows", zap.String("table", r.Table), zap.String("afterID", afterID), zap.String("throughID", throughID))
+	report.Diffs = append(report.Diffs, DiffRows(r.Table, source, target)...)
+	return nil
+}
+
+// Checksum hashes the compared columns of the rows in order.
+func Checksum(table Table, rows []Row) string {
+	hash := sha256.New()
+	for _, row := range rows {
+		for _, column := range Columns[table] {
+			hash.Write([]byte(row.Fields[column]))
+			hash.Write([]byte{0x1f})
+		}
+		hash.Write([]byte{0x1e})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// DiffRows compares both sides row by row, the result follows the source
+// order with extra target rows at the end.
+func DiffRows(table Table, source []Row, target []Row) []Diff {
+	targetByID := make(map[string]*Row, len(target))
+	for i := range target {
+		targetByID[target[i].ID] = &target[i]
+	}
+
+	var diffs []Diff
+	seen := map[string]bool{}
+	for i := range source {
+		sourceRow := &source[i]
+		seen[sourceRow.ID] = true
+
+		targetRow, ok := targetByID[sourceRow.ID]
+		if !ok {
+			diffs = append(diffs, Diff{ID: sourceRow.ID, Kind: DiffMissing, Source: sourceRow})
+			continue
+		}
+
+		var fields []FieldDiff
+		for _, column := range Columns[table] {
+			if sourceRow.Fields[column] != targetRow.Fields[column] {
+				fields = append(fields, FieldDiff{
+					Column: column,
+					Source: sourceRow.Fields[column],
+					Target: targetRow.Fields[column],
+				})
+			}
+		}
+		if len(fields) > 0 {
+			diffs = append(diffs, Diff{ID: sourceRow.ID, Kind: DiffChanged, Fields: fields, Source: sourceRow, Target: targetRow})
+		}
+	}
+
+	for i := range target {
+		if !seen[target[i].ID] {
+			diffs = append(diffs, Diff{ID: target[i].ID, Kind: DiffExtra, Target: &target[i]})
+		}
+	}
+	return diffs
+}
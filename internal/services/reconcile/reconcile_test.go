@@ -0,0 +1,55 @@
+package reconcile_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/weeb-vip/character-staff-sync/config"
+	migrations "github.com/weeb-vip/character-staff-sync/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/reconcile"
+	"go.uber.org/zap"
+)
+
+func TestReconcileMixedCaseIDs(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+
+	database := db.NewDB(config.DBConfig{Dialect: db.DialectSQLite, SQLitePath: filepath.Join(t.TempDir(), "reconcile.db")})
+	if err := migrations.MigrateUpDB(database); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := anime_character.NewAnimeCharacterRepository(database)
+	ids := []string{"b", "A", "c", "B", "a"}
+	var source []reconcile.Row
+	for _, id := range ids {
+		if err := repo.Upsert(ctx, &anime_character.AnimeCharacter{ID: id, AnimeID: "anime", Name: "Name " + id, Role: "Main"}); err != nil {
+			t.Fatal(err)
+		}
+		fields := map[string]string{}
+		for _, column := range reconcile.Columns[reconcile.TableCharacter] {
+			fields[column] = ""
+		}
+		fields["id"], fields["anime_id"], fields["name"], fields["role"] = id, "anime", "Name "+id, "Main"
+		source = append(source, reconcile.Row{ID: id, Fields: fields})
+	}
+	// the order LoadSource returns
+	source = []reconcile.Row{source[1], source[3], source[4], source[0], source[2]}
+
+	reconciler := reconcile.NewReconciler(reconcile.Options{RangeSize: 2}, reconcile.TableCharacter, reconcile.NewCharacterTarget(repo))
+	report, err := reconciler.Reconcile(ctx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Diffs) != 0 {
+		t.Errorf("diffs = %+v, want none", report.Diffs)
+	}
+	for _, r := range report.Ranges {
+		if !r.Match {
+			t.Errorf("range (%q, %q] does not match", r.AfterID, r.ThroughID)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package reconcile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+)
+
+// LoadSource reads a JSONL or CSV export of the upstream table. The format is
+// taken from the file extension unless given. Rows are returned sorted by id
+// byte by byte, the order the target lists its id ranges in.
+func LoadSource(path string, format string, table Table) ([]Row, error) {
+	if format == "" {
+		if strings.HasSuffix(path, ".csv") {
+			format = "csv"
+		} else {
+			format = "jsonl"
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []Row
+	switch format {
+	case "jsonl":
+		rows, err = readJSONL(f, table)
+	case "csv":
+		rows, err = readCSV(f, table)
+	default:
+		return nil, fmt.Errorf("unknown source format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].ID < rows[j].ID
+	})
+	return rows, nil
+}
+
+func readJSONL(r io.Reader, table Table) ([]Row, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var rows []Row
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		values := map[string]string{}
+		for column, value := range record {
+			values[column] = stringify(value)
+		}
+		row, err := newRow(table, values)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
+
+func readCSV(r io.Reader, table Table) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	var rows []Row
+	for lineNumber := 2; ; lineNumber++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		values := map[string]string{}
+		for i, column := range header {
+			if i < len(record) {
+				values[strings.TrimSpace(column)] = record[i]
+			}
+		}
+		row, err := newRow(table, values)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func newRow(table Table, values map[string]string) (Row, error) {
+	columns, ok := Columns[table]
+	if !ok {
+		return Row{}, fmt.Errorf("unknown table %q", table)
+	}
+
+	row := Row{Fields: map[string]string{}}
+	for _, column := range columns {
+		row.Fields[column] = values[column]
+	}
+	if table == TableLink && row.Fields["role_type"] == "" {
+		// exports that predate role types only hold voice credits, the
+		// pipeline stores them as such
+		row.Fields["role_type"] = anime_character_staff_link.RoleTypeVoice
+	}
+	row.ID = row.Fields["id"]
+	if row.ID == "" {
+		return Row{}, fmt.Errorf("row has no id")
+	}
+	return row, nil
+}
+
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
@@ -0,0 +1,129 @@
+package reconcile
+
+import (
+	"context"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_character_staff_link"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+)
+
+// Target lists the rows of the MySQL table within an id range.
+type Target interface {
+	ListIDRange(ctx context.Context, afterID string, throughID string) ([]Row, error)
+}
+
+type characterTarget struct {
+	repo anime_character.AnimeCharacterRepository
+}
+
+func NewCharacterTarget(repo anime_character.AnimeCharacterRepository) Target {
+	return &characterTarget{repo: repo}
+}
+
+func (t *characterTarget) ListIDRange(ctx context.Context, afterID string, throughID string) ([]Row, error) {
+	characters, err := t.repo.ListIDRange(ctx, afterID, throughID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]Row, 0, len(characters))
+	for _, c := range characters {
+		row, err := newRow(TableCharacter, map[string]string{
+			"id":             c.ID,
+			"anime_id":       c.AnimeID,
+			"name":           c.Name,
+			"role":           c.Role,
+			"birthday":       c.Birthday,
+			"zodiac":         c.Zodiac,
+			"gender":         c.Gender,
+			"race":           c.Race,
+			"height":         c.Height,
+			"weight":         c.Weight,
+			"title":          c.Title,
+			"martial_status": c.MartialStatus,
+			"summary":        c.Summary,
+			"image":          c.Image,
+		})
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+type staffTarget struct {
+	repo anime_staff.AnimeStaffRepository
+}
+
+func NewStaffTarget(repo anime_staff.AnimeStaffRepository) Target {
+	return &staffTarget{repo: repo}
+}
+
+func (t *staffTarget) ListIDRange(ctx context.Context, afterID string, throughID string) ([]Row, error) {
+	staff, err := t.repo.ListIDRange(ctx, afterID, throughID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]Row, 0, len(staff))
+	for _, s := range staff {
+		row, err := newRow(TableStaff, map[string]string{
+			"id":          s.ID,
+			"language":    s.Language,
+			"given_name":  s.GivenName,
+			"family_name": s.FamilyName,
+			"image":       s.Image,
+			"birthday":    s.Birthday,
+			"birth_place": s.BirthPlace,
+			"blood_type":  s.BloodType,
+			"hobbies":     s.Hobbies,
+			"summary":     s.Summary,
+		})
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+type linkTarget struct {
+	repo anime_character_staff_link.AnimeCharacterStaffLinkRepository
+}
+
+func NewLinkTarget(repo anime_character_staff_link.AnimeCharacterStaffLinkRepository) Target {
+	return &linkTarget{repo: repo}
+}
+
+func (t *linkTarget) ListIDRange(ctx context.Context, afterID string, throughID string) ([]Row, error) {
+	links, err := t.repo.ListIDRange(ctx, afterID, throughID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]Row, 0, len(links))
+	for _, l := range links {
+		notes := ""
+		if l.Notes != nil {
+			notes = *l.Notes
+		}
+		row, err := newRow(TableLink, map[string]string{
+			"id":                l.ID,
+			"character_id":      l.CharacterID,
+			"staff_id":          l.StaffID,
+			"character_name":    l.CharacterName,
+			"staff_given_name":  l.StaffGivenName,
+			"staff_family_name": l.StaffFamilyName,
+			"language":          l.Language,
+			"role_type":         l.RoleType,
+			"notes":             notes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
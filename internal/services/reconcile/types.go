@@ -0,0 +1,70 @@
+package reconcile
+
+type Table = string
+
+const (
+	TableCharacter Table = "character"
+	TableStaff     Table = "staff"
+	TableLink      Table = "link"
+)
+
+type DiffKind = string
+
+const (
+	// DiffMissing rows exist in the source but not in the target.
+	DiffMissing DiffKind = "missing"
+	// DiffExtra rows exist in the target but not in the source.
+	DiffExtra DiffKind = "extra"
+	// DiffChanged rows exist on both sides with different values.
+	DiffChanged DiffKind = "changed"
+)
+
+// Columns are the compared columns of every table. Timestamps are left out:
+// the target keeps the upstream created_at and updated_at, but exports write
+// them in formats and precisions that differ from the stored values.
+var Columns = map[Table][]string{
+	TableCharacter: {"id", "anime_id", "name", "role", "birthday", "zodiac", "gender", "race", "height", "weight", "title", "martial_status", "summary", "image"},
+	TableStaff:     {"id", "language", "given_name", "family_name", "image", "birthday", "birth_place", "blood_type", "hobbies", "summary"},
+	TableLink:      {"id", "character_id", "staff_id", "character_name", "staff_given_name", "staff_family_name", "language", "role_type", "notes"},
+}
+
+// Row is a table row reduced to the compared columns.
+type Row struct {
+	ID     string            `json:"id"`
+	Fields map[string]string `json:"fields"`
+}
+
+type FieldDiff struct {
+	Column string `json:"column"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+type Diff struct {
+	ID     string      `json:"id"`
+	Kind   DiffKind    `json:"kind"`
+	Fields []FieldDiff `json:"fields,omitempty"`
+	Source *Row        `json:"-"`
+	Target *Row        `json:"-"`
+}
+
+type RangeResult struct {
+	AfterID        string `json:"after_id"`
+	ThroughID      string `json:"through_id"`
+	SourceRows     int    `json:"source_rows"`
+	TargetRows     int    `json:"target_rows"`
+	SourceChecksum string `json:"source_checksum"`
+	TargetChecksum string `json:"target_checksum"`
+	Match          bool   `json:"match"`
+}
+
+type Report struct {
+	Table      Table         `json:"table"`
+	SourceRows int           `json:"source_rows"`
+	TargetRows int           `json:"target_rows"`
+	Ranges     []RangeResult `json:"ranges"`
+	Diffs      []Diff        `json:"diffs"`
+	Missing    int           `json:"missing"`
+	Extra      int           `json:"extra"`
+	Changed    int           `json:"changed"`
+}
@@ -0,0 +1,184 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/ThatCatDev/ep/v2/drivers"
+	"github.com/ThatCatDev/ep/v2/event"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"go.uber.org/zap"
+)
+
+// FileDriver is a drivers.Driver that reads captured messages from files
+// instead of a broker. Consume returns once every file has been read and the
+// messages sent to the retry topic have been consumed again.
+type FileDriver struct {
+	files      []string
+	format     Format
+	retryTopic string
+	stats      Stats
+	produced   map[string][]*kafka.Message
+	mu         sync.Mutex
+}
+
+func NewFileDriver(files []string, format Format, retryTopic string) *FileDriver {
+	return &FileDriver{
+		files:      files,
+		format:     format,
+		retryTopic: retryTopic,
+		produced:   map[string][]*kafka.Message{},
+	}
+}
+
+var _ drivers.Driver[*kafka.Message] = &FileDriver{}
+
+func (d *FileDriver) Consume(ctx context.Context, topic string, handler func(context.Context, *kafka.Message, []byte) error) error {
+	log := logger.FromCtx(ctx)
+
+	for _, path := range d.files {
+		if err := d.consumeFile(ctx, path, topic, handler); err != nil {
+			return err
+		}
+		d.mu.Lock()
+		d.stats.Files++
+		d.mu.Unlock()
+		log.Info("Replayed file", zap.String("file", path))
+	}
+	return d.consumeRetries(ctx, handler)
+}
+
+// consumeRetries feeds the messages the backoff retry middleware published back
+// to the handler, the way the retry consumer of the live pipeline does, until
+// no retry is left.
+func (d *FileDriver) consumeRetries(ctx context.Context, handler func(context.Context, *kafka.Message, []byte) error) error {
+	log := logger.FromCtx(ctx)
+
+	for next := 0; ctx.Err() == nil; next++ {
+		d.mu.Lock()
+		if next >= len(d.produced[d.retryTopic]) {
+			d.mu.Unlock()
+			return nil
+		}
+		message := d.produced[d.retryTopic][next]
+		d.mu.Unlock()
+
+		retry := *message
+		retry.TopicPartition = kafka.TopicPartition{Topic: &d.retryTopic, Offset: kafka.Offset(next)}
+		if err := handler(ctx, &retry, retry.Value); err != nil {
+			log.Error("Failed to replay retried message", zap.Int("offset", next), zap.Error(err))
+			d.countFailure()
+		}
+	}
+	return nil
+}
+
+func (d *FileDriver) consumeFile(ctx context.Context, path string, topic string, handler func(context.Context, *kafka.Message, []byte) error) error {
+	log := logger.FromCtx(ctx)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		lineNumber++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		message, err := ParseLine(line, d.format)
+		if err != nil {
+			log.Error("Failed to parse line", zap.String("file", path), zap.Int("line", lineNumber), zap.Error(err))
+			d.countFailure()
+			continue
+		}
+		message.TopicPartition = kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(lineNumber)}
+
+		d.mu.Lock()
+		d.stats.Messages++
+		d.mu.Unlock()
+
+		if err := handler(ctx, message, message.Value); err != nil {
+			log.Error("Failed to replay message", zap.String("file", path), zap.Int("line", lineNumber), zap.Error(err))
+			d.countFailure()
+		}
+	}
+	return scanner.Err()
+}
+
+func (d *FileDriver) countFailure() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stats.Failed++
+}
+
+// Produce keeps messages in memory, both the outbound events of the
+// processors and the messages the backoff retry middleware sends to the retry
+// topic.
+func (d *FileDriver) Produce(ctx context.Context, topic string, message *kafka.Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.produced[topic] = append(d.produced[topic], message)
+	if topic == d.retryTopic {
+		d.stats.Retried++
+	}
+	return nil
+}
+
+func (d *FileDriver) Produced(topic string) []*kafka.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.produced[topic]
+}
+
+func (d *FileDriver) CreateTopic(ctx context.Context, topic string) error {
+	return nil
+}
+
+func (d *FileDriver) Close() error {
+	return nil
+}
+
+// ExtractEvent mirrors the kafka driver so the middleware chain sees the same
+// RawData as it does live.
+func (d *FileDriver) ExtractEvent(data *kafka.Message) (*event.SubData[*kafka.Message], error) {
+	eventData := &event.SubData[*kafka.Message]{
+		DriverMessage: data,
+	}
+	headers := map[string]string{}
+	for _, v := range data.Headers {
+		headers[v.Key] = string(v.Value)
+	}
+	eventData.Headers = headers
+
+	msgByte, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(msgByte, &eventData.RawData)
+
+	return eventData, err
+}
+
+func (d *FileDriver) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.stats
+}
@@ -0,0 +1,61 @@
+package replay_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/services/replay"
+	"go.uber.org/zap"
+)
+
+func TestFileDriverConsumesRetries(t *testing.T) {
+	ctx := logger.WithCtx(context.Background(), zap.NewNop())
+
+	input := filepath.Join(t.TempDir(), "messages.jsonl")
+	if err := os.WriteFile(input, []byte("{\"payload\":{\"id\":\"a\"}}\n{\"payload\":{\"id\":\"b\"}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	driver := replay.NewFileDriver([]string{input}, replay.FormatDecoded, "characters-retry")
+
+	var handled []string
+	err := driver.Consume(ctx, "characters", func(ctx context.Context, message *kafka.Message, _ []byte) error {
+		handled = append(handled, *message.TopicPartition.Topic+" "+string(message.Value))
+		if err := driver.Produce(ctx, "outbound", &kafka.Message{Value: message.Value}); err != nil {
+			return err
+		}
+		if string(message.Value) == `{"payload":{"id":"b"}}` && *message.TopicPartition.Topic == "characters" {
+			return driver.Produce(ctx, "characters-retry", &kafka.Message{Value: message.Value})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`characters {"payload":{"id":"a"}}`,
+		`characters {"payload":{"id":"b"}}`,
+		`characters-retry {"payload":{"id":"b"}}`,
+	}
+	if len(handled) != len(want) {
+		t.Fatalf("handled %q, want %q", handled, want)
+	}
+	for i := range want {
+		if handled[i] != want[i] {
+			t.Errorf("handled[%d] = %q, want %q", i, handled[i], want[i])
+		}
+	}
+
+	stats := driver.Stats()
+	if stats.Messages != 2 || stats.Retried != 1 || stats.Failed != 0 {
+		t.Errorf("stats = %+v, want 2 messages and 1 retry", stats)
+	}
+	if got := len(driver.Produced("outbound")); got != 3 {
+		t.Errorf("outbound = %d, want 3", got)
+	}
+}
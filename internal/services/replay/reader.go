@@ -0,0 +1,121 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// ListFiles returns the input file, or the .jsonl/.json files of the input
+// directory sorted by name.
+func ListFiles(input string) ([]string, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{input}, nil
+	}
+
+	entries, err := os.ReadDir(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(entry.Name(), ".jsonl") || strings.HasSuffix(entry.Name(), ".json") {
+			files = append(files, filepath.Join(input, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+type rawLine struct {
+	Key     *string `json:"Key"`
+	Value   *string `json:"Value"`
+	Headers []struct {
+		Key   string `json:"Key"`
+		Value string `json:"Value"`
+	} `json:"Headers"`
+}
+
+// ParseLine turns a captured line into the kafka message the live driver
+// would have received.
+func ParseLine(line []byte, format Format) (*kafka.Message, error) {
+	line = bytes.TrimSpace(line)
+
+	if format == FormatAuto {
+		format = detectFormat(line)
+	}
+
+	switch format {
+	case FormatRaw:
+		var raw rawLine
+		if err := json.Unmarshal(line, &raw); err != nil {
+			return nil, err
+		}
+		if raw.Value == nil {
+			return nil, fmt.Errorf("raw line has no Value")
+		}
+		value, err := base64.StdEncoding.DecodeString(*raw.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base64 value: %w", err)
+		}
+
+		message := &kafka.Message{Value: value}
+		if raw.Key != nil {
+			key, err := base64.StdEncoding.DecodeString(*raw.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode base64 key: %w", err)
+			}
+			message.Key = key
+		}
+		for _, header := range raw.Headers {
+			headerValue, err := base64.StdEncoding.DecodeString(header.Value)
+			if err != nil {
+				headerValue = []byte(header.Value)
+			}
+			message.Headers = append(message.Headers, kafka.Header{Key: header.Key, Value: headerValue})
+		}
+		return message, nil
+	case FormatDecoded:
+		var envelope map[string]json.RawMessage
+		if err := json.Unmarshal(line, &envelope); err != nil {
+			return nil, err
+		}
+		if _, ok := envelope["payload"]; ok {
+			return &kafka.Message{Value: append([]byte(nil), line...)}, nil
+		}
+		// bare payload, wrap it the way Debezium does
+		value, err := json.Marshal(map[string]json.RawMessage{"payload": line})
+		if err != nil {
+			return nil, err
+		}
+		return &kafka.Message{Value: value}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func detectFormat(line []byte) Format {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(line, &probe); err != nil {
+		return FormatDecoded
+	}
+	if value, ok := probe["Value"]; ok && len(value) > 0 && value[0] == '"' {
+		return FormatRaw
+	}
+	return FormatDecoded
+}
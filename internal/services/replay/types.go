@@ -0,0 +1,21 @@
+package replay
+
+type Format = string
+
+const (
+	// FormatAuto detects the format of every line.
+	FormatAuto Format = "auto"
+	// FormatRaw lines are kafka messages as captured by the driver, with the
+	// Debezium message base64 encoded in Value.
+	FormatRaw Format = "raw"
+	// FormatDecoded lines are Debezium messages, either the full
+	// {"schema", "payload"} envelope or the bare payload.
+	FormatDecoded Format = "decoded"
+)
+
+type Stats struct {
+	Files    int
+	Messages int
+	Failed   int
+	Retried  int
+}
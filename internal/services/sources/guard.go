@@ -0,0 +1,97 @@
+package sources
+
+import (
+	"context"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/field_source"
+)
+
+type GuardImpl struct {
+	Registry   *Registry
+	Repository field_source.FieldSourceRepository
+	source     Source
+}
+
+func NewGuard(registry *Registry, repo field_source.FieldSourceRepository, source string) Guard {
+	return &GuardImpl{
+		Registry:   registry,
+		Repository: repo,
+		source:     registry.Source(source),
+	}
+}
+
+func (g *GuardImpl) Source() Source {
+	return g.source
+}
+
+// outranks reports whether the owner wins over the guarded source. Owners
+// are ranked by the current priority of their source, so changing the
+// priorities applies to fields that were already claimed.
+func (g *GuardImpl) outranks(owner field_source.FieldSource) bool {
+	return owner.Source != g.source.Name && g.Registry.Priority(owner.Source) > g.source.Priority
+}
+
+func (g *GuardImpl) Protect(ctx context.Context, entityType string, entityID string, stored interface{}, next interface{}, changed []string) ([]string, error) {
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	owners, err := g.Repository.List(ctx, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	isChanged := map[string]bool{}
+	for _, field := range changed {
+		isChanged[field] = true
+	}
+	var protected []string
+	for _, owner := range owners {
+		if isChanged[owner.Field] && g.outranks(owner) {
+			protected = append(protected, owner.Field)
+		}
+	}
+	if len(protected) == 0 {
+		return nil, nil
+	}
+	if err := db.CopyColumns(next, stored, protected); err != nil {
+		return nil, err
+	}
+	return protected, nil
+}
+
+func (g *GuardImpl) Claim(ctx context.Context, entityType string, entityID string, row interface{}, fields []string) error {
+	empty, err := db.EmptyColumns(row, fields)
+	if err != nil {
+		return err
+	}
+	isEmpty := map[string]bool{}
+	for _, field := range empty {
+		isEmpty[field] = true
+	}
+	claimed := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !isEmpty[field] {
+			claimed = append(claimed, field)
+		}
+	}
+	return g.Repository.Claim(ctx, entityType, entityID, claimed, g.source.Name, g.source.Priority)
+}
+
+func (g *GuardImpl) Outranked(ctx context.Context, entityType string, entityID string) (*field_source.FieldSource, error) {
+	owners, err := g.Repository.List(ctx, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	var top *field_source.FieldSource
+	for i, owner := range owners {
+		if g.outranks(owner) && (top == nil || g.Registry.Priority(owner.Source) > g.Registry.Priority(top.Source)) {
+			top = &owners[i]
+		}
+	}
+	return top, nil
+}
+
+func (g *GuardImpl) Release(ctx context.Context, entityType string, entityID string) error {
+	return g.Repository.Delete(ctx, entityType, entityID)
+}
@@ -0,0 +1,43 @@
+package sources
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Registry holds the priority of every known source.
+type Registry struct {
+	priorities map[string]int
+}
+
+// NewRegistry parses a comma separated list of name:priority.
+func NewRegistry(priorities string) (*Registry, error) {
+	registry := &Registry{priorities: map[string]int{}}
+	for _, entry := range strings.Split(priorities, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid source priority %q, expected name:priority", entry)
+		}
+		priority, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid priority of source %s: %w", name, err)
+		}
+		registry.priorities[name] = priority
+	}
+	return registry, nil
+}
+
+// Priority returns the priority of the source, 0 for unknown sources.
+func (r *Registry) Priority(name string) int {
+	return r.priorities[name]
+}
+
+func (r *Registry) Source(name string) Source {
+	return Source{Name: name, Priority: r.Priority(name)}
+}
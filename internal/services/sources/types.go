@@ -0,0 +1,32 @@
+package sources
+
+import (
+	"context"
+
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/field_source"
+)
+
+// Source is the upstream a pipeline ingests from, e.g. a scraper or curated
+// edits, ranked by priority.
+type Source struct {
+	Name     string
+	Priority int
+}
+
+// Guard keeps fields set by a source with a higher priority when a lower
+// priority source writes the same row, so the sources can feed one table.
+type Guard interface {
+	// Protect resets the changed fields of next that are owned by a source
+	// with a higher priority to their value in stored and returns them.
+	// Fields that are not columns, like aliases, are only returned.
+	Protect(ctx context.Context, entityType string, entityID string, stored interface{}, next interface{}, changed []string) ([]string, error)
+	// Claim records the source as owner of the written fields, empty columns
+	// are not claimed so they can be filled by any source.
+	Claim(ctx context.Context, entityType string, entityID string, row interface{}, fields []string) error
+	// Outranked returns the owner with the highest priority above the source,
+	// nil when there is none. A row with such an owner is not deleted.
+	Outranked(ctx context.Context, entityType string, entityID string) (*field_source.FieldSource, error)
+	// Release forgets the owners of a deleted row.
+	Release(ctx context.Context, entityType string, entityID string) error
+	Source() Source
+}
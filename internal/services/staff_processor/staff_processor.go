@@ -3,17 +3,48 @@ package staff_processor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/ThatCatDev/ep/v2/event"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/weeb-vip/character-staff-sync/internal/db"
 	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/anime_staff"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_alias"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_history"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/entity_redirect"
+	"github.com/weeb-vip/character-staff-sync/internal/db/repositories/field_source"
+	"github.com/weeb-vip/character-staff-sync/internal/debezium"
+	"github.com/weeb-vip/character-staff-sync/internal/images"
 	"github.com/weeb-vip/character-staff-sync/internal/logger"
+	"github.com/weeb-vip/character-staff-sync/internal/metrics"
 	"github.com/weeb-vip/character-staff-sync/internal/producer"
+	"github.com/weeb-vip/character-staff-sync/internal/services/attributes"
+	"github.com/weeb-vip/character-staff-sync/internal/services/cast_events"
+	"github.com/weeb-vip/character-staff-sync/internal/services/history"
+	"github.com/weeb-vip/character-staff-sync/internal/services/image_dedupe"
+	"github.com/weeb-vip/character-staff-sync/internal/services/sources"
 	"go.uber.org/zap"
-	"time"
+	"gorm.io/gorm"
 )
 
 type Options struct {
 	NoErrorOnDelete bool
+	// CastChanges, when set, is told about every updated or deleted staff
+	// member, new staff members are in no cast until they are linked.
+	CastChanges cast_events.Recorder
+	// Redirects, when set, rewrites the ids of merged staff members to the
+	// staff member they were merged into.
+	Redirects entity_redirect.Resolver
+	// Sources, when set, keeps the fields owned by a source with a higher
+	// priority than the source of the pipeline.
+	Sources sources.Guard
+	// History, when set, records every applied change.
+	History history.Recorder
+
+	// Images checks and normalizes the image urls sent to image-sync, nil
+	// uses the default allowlist.
+	Images *images.Validator
+	// ImageRequests, when set, skips images already sent for the entity.
+	ImageRequests image_dedupe.Cache
 }
 
 type StaffProcessor interface {
@@ -39,6 +70,20 @@ func (p *StaffProcessorImpl) Process(ctx context.Context, data event.Event[*kafk
 
 	payload := data.Payload
 
+	redirected, err := p.followRedirects(ctx, &payload)
+	if err != nil {
+		return data, err
+	}
+	if redirected && payload.After == nil {
+		// the staff member it was merged into is still live
+		log.Info("Skipping delete of merged staff", zap.String("ID", payload.Before.Id))
+		metrics.Redirects.WithLabelValues(entity_redirect.EntityTypeStaff, "delete").Inc()
+		return data, nil
+	}
+	if redirected {
+		metrics.Redirects.WithLabelValues(entity_redirect.EntityTypeStaff, "upsert").Inc()
+	}
+
 	if payload.Before == nil && payload.After != nil {
 		newStaff, err := p.parseToEntity(ctx, *payload.After)
 		if err != nil {
@@ -47,31 +92,60 @@ func (p *StaffProcessorImpl) Process(ctx context.Context, data event.Event[*kafk
 		log.Info("INFO: newStaff", zap.String("ID", newStaff.ID),
 			zap.String("GivenName", newStaff.GivenName),
 			zap.String("FamilyName", newStaff.FamilyName))
-		if err := p.Repository.Upsert(newStaff); err != nil {
+		_, claimed, err := p.protect(ctx, newStaff)
+		if err != nil {
+			return data, err
+		}
+		// snapshot re-reads and replays create rows that are already stored
+		changed, _, err := p.changes(ctx, newStaff, nil)
+		if err != nil {
+			return data, err
+		}
+		if len(changed) == 0 {
+			log.Info("Skipping unchanged staff", zap.String("ID", newStaff.ID))
+			return data, nil
+		}
+		before, err := p.historyBefore(ctx, newStaff.ID)
+		if err != nil {
+			return data, err
+		}
+		if err := p.Repository.Upsert(ctx, newStaff); err != nil {
+			return data, err
+		}
+		if err := p.recordHistory(ctx, data, before, newStaff); err != nil {
+			return data, err
+		}
+		if err := p.claim(ctx, newStaff, claimed); err != nil {
 			return data, err
 		}
 
 		if err != nil {
 			return data, err
 		}
-		image := ""
+		image, sendImage := "", false
 		if payload.After.Image != nil {
-			image = *payload.After.Image
+			image, sendImage = p.Options.Images.Accept(ctx, images.EntityStaff, newStaff.ID, newStaff.Image)
 		}
 		imagePayload := producer.ImagePayload{
 			Data: producer.ImageSchema{
-				Name: *payload.After.GivenName + "_" + *payload.After.FamilyName,
+				Name: newStaff.GivenName + "_" + newStaff.FamilyName,
 				URL:  image,
 				Type: producer.DataTypeStaff,
 			},
 		}
 
 		payloadBytes, err := json.Marshal(imagePayload)
-		if payload.After.Image != nil {
+		if sendImage {
 			log.Info("Sending update to producer", zap.String("title", imagePayload.Data.Name), zap.String("imageURL", imagePayload.Data.URL))
 
-			err = p.Producer(ctx, &kafka.Message{
-				Value: payloadBytes,
+			key := image_dedupe.NewKey(images.EntityStaff, newStaff.ID, image)
+			// sent once the write committed, a rolled back write requests nothing
+			err = db.AfterCommit(ctx, func(ctx context.Context) error {
+				return image_dedupe.Send(ctx, p.Options.ImageRequests, key, func(ctx context.Context) error {
+					return p.Producer(ctx, &kafka.Message{
+						Value: payloadBytes,
+					})
+				})
 			})
 
 			if err != nil {
@@ -86,13 +160,40 @@ func (p *StaffProcessorImpl) Process(ctx context.Context, data event.Event[*kafk
 		if err != nil {
 			return data, err
 		}
-		if err := p.Repository.Delete(oldStaff); err != nil {
+		if p.Options.Sources != nil {
+			owner, err := p.Options.Sources.Outranked(ctx, field_source.EntityTypeStaff, oldStaff.ID)
+			if err != nil {
+				return data, err
+			}
+			if owner != nil {
+				log.Info("Skipping delete of staff owned by a higher priority source",
+					zap.String("ID", oldStaff.ID), zap.String("owner", owner.Source))
+				metrics.ProtectedDeletes.WithLabelValues(field_source.EntityTypeStaff, p.Options.Sources.Source().Name).Inc()
+				return data, nil
+			}
+		}
+		if err := p.recordCast(ctx, oldStaff.ID, cast_events.ChangeRemoved); err != nil {
+			return data, err
+		}
+		before, err := p.historyBefore(ctx, oldStaff.ID)
+		if err != nil {
+			return data, err
+		}
+		if err := p.Repository.Delete(ctx, oldStaff); err != nil {
 			if p.Options.NoErrorOnDelete {
 				log.Warn("WARN: error deleting from db:", zap.Error(err))
 				return data, nil
 			}
 			return data, err
 		}
+		if p.Options.Sources != nil {
+			if err := p.Options.Sources.Release(ctx, field_source.EntityTypeStaff, oldStaff.ID); err != nil {
+				return data, err
+			}
+		}
+		if err := p.recordHistory(ctx, data, before, nil); err != nil {
+			return data, err
+		}
 		return data, nil
 	}
 
@@ -105,7 +206,32 @@ func (p *StaffProcessorImpl) Process(ctx context.Context, data event.Event[*kafk
 		log.Info("INFO: newStaff", zap.String("ID", newStaff.ID),
 			zap.String("GivenName", newStaff.GivenName),
 			zap.String("FamilyName", newStaff.FamilyName))
-		if err := p.Repository.Upsert(newStaff); err != nil {
+		_, claimed, err := p.protect(ctx, newStaff)
+		if err != nil {
+			return data, err
+		}
+		changed, _, err := p.changes(ctx, newStaff, payload.Before)
+		if err != nil {
+			return data, err
+		}
+		if len(changed) == 0 {
+			log.Info("Skipping unchanged staff", zap.String("ID", newStaff.ID))
+			return data, nil
+		}
+		before, err := p.historyBefore(ctx, newStaff.ID)
+		if err != nil {
+			return data, err
+		}
+		if err := p.Repository.Upsert(ctx, newStaff); err != nil {
+			return data, err
+		}
+		if err := p.recordHistory(ctx, data, before, newStaff); err != nil {
+			return data, err
+		}
+		if err := p.claim(ctx, newStaff, claimed); err != nil {
+			return data, err
+		}
+		if err := p.recordCast(ctx, newStaff.ID, cast_events.ChangeUpdated); err != nil {
 			return data, err
 		}
 	}
@@ -117,8 +243,167 @@ func (p *StaffProcessorImpl) Process(ctx context.Context, data event.Event[*kafk
 	return data, nil
 }
 
+// followRedirects rewrites the ids of the before and after images when the
+// staff member was merged into another one.
+func (p *StaffProcessorImpl) followRedirects(ctx context.Context, payload *Payload) (bool, error) {
+	if p.Options.Redirects == nil {
+		return false, nil
+	}
+
+	redirected := false
+	for _, row := range []**Schema{&payload.Before, &payload.After} {
+		if *row == nil {
+			continue
+		}
+		id, err := p.Options.Redirects.Resolve(ctx, entity_redirect.EntityTypeStaff, (*row).Id)
+		if err != nil {
+			return false, err
+		}
+		if id != (*row).Id {
+			rewritten := **row
+			rewritten.Id = id
+			*row = &rewritten
+			redirected = true
+		}
+	}
+	return redirected, nil
+}
+
+// protect keeps the stored values of the fields owned by a source with a
+// higher priority than the source of the pipeline. It returns the protected
+// fields and the fields the staff member changes, all of them when it is not
+// stored yet, which the source claims once the staff member is written.
+func (p *StaffProcessorImpl) protect(ctx context.Context, next *anime_staff.AnimeStaff) ([]string, []string, error) {
+	if p.Options.Sources == nil {
+		return nil, nil, nil
+	}
+	stored, err := p.Repository.FindByID(ctx, next.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		changed, _, err := diff(nil, next)
+		return nil, changed, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changed, _, err := diff(stored, next)
+	if err != nil {
+		return nil, nil, err
+	}
+	protected, err := p.Options.Sources.Protect(ctx, field_source.EntityTypeStaff, next.ID, stored, next, changed)
+	if err != nil || len(protected) == 0 {
+		return nil, changed, err
+	}
+
+	source := p.Options.Sources.Source().Name
+	for _, field := range protected {
+		if field == aliasesField {
+			next.Aliases = nil
+		}
+		metrics.ProtectedFields.WithLabelValues(field_source.EntityTypeStaff, source, field).Inc()
+	}
+	// the derived columns and the hobby list follow the restored values
+	attributes.NormalizeStaff(ctx, next)
+	logger.FromCtx(ctx).Info("Keeping fields owned by a higher priority source",
+		zap.String("ID", next.ID), zap.String("source", source), zap.Strings("fields", protected))
+
+	changed, _, err = diff(stored, next)
+	return protected, changed, err
+}
+
+// claim records the source of the pipeline as the owner of the written
+// fields.
+func (p *StaffProcessorImpl) claim(ctx context.Context, staff *anime_staff.AnimeStaff, fields []string) error {
+	if p.Options.Sources == nil {
+		return nil
+	}
+	return p.Options.Sources.Claim(ctx, field_source.EntityTypeStaff, staff.ID, staff, fields)
+}
+
+// historyBefore returns the stored staff member the history records as the state
+// before a write, nil when the history is disabled or it is not stored.
+func (p *StaffProcessorImpl) historyBefore(ctx context.Context, id string) (*anime_staff.AnimeStaff, error) {
+	if p.Options.History == nil {
+		return nil, nil
+	}
+	stored, err := p.Repository.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return stored, err
+}
+
+// recordHistory records the write of the message, the newer of before and
+// after identifies the staff member.
+func (p *StaffProcessorImpl) recordHistory(ctx context.Context, data event.Event[*kafka.Message, Payload], before *anime_staff.AnimeStaff, after *anime_staff.AnimeStaff) error {
+	if p.Options.History == nil {
+		return nil
+	}
+	source := history.Source{
+		TxID: int64(data.Payload.Source.TxId),
+		Lsn:  int64(data.Payload.Source.Lsn),
+		TsMs: data.Payload.Source.TsMs,
+	}
+	change := history.Change{
+		EntityType: entity_history.EntityTypeStaff,
+		Before:     before,
+		After:      after,
+		Message:    data.DriverMessage,
+		Source:     source,
+	}
+	for _, row := range []*anime_staff.AnimeStaff{before, after} {
+		if row != nil {
+			change.EntityID = row.ID
+		}
+	}
+	return p.Options.History.Record(ctx, change)
+}
+
+func (p *StaffProcessorImpl) recordCast(ctx context.Context, staffID string, change cast_events.ChangeType) error {
+	if p.Options.CastChanges == nil {
+		return nil
+	}
+	return p.Options.CastChanges.StaffChanged(ctx, staffID, change)
+}
+
+// changes compares the staff member with the stored one, see db.StoredChanges.
+func (p *StaffProcessorImpl) changes(ctx context.Context, next *anime_staff.AnimeStaff, before *Schema) ([]string, map[string]interface{}, error) {
+	var previous *anime_staff.AnimeStaff
+	if before != nil {
+		var err error
+		previous, err = p.parseToEntity(ctx, *before)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	find := func() (*anime_staff.AnimeStaff, error) {
+		return p.Repository.FindByID(ctx, next.ID)
+	}
+	return db.StoredChanges(next, previous, find, diff)
+}
+
+// diff compares the columns and, when the source sent aliases, the alias
+// names. A nil previous staff member reports every column as changed.
+func diff(previous *anime_staff.AnimeStaff, next *anime_staff.AnimeStaff) ([]string, map[string]interface{}, error) {
+	changed, values, err := db.Changes(previous, next)
+	if err != nil {
+		return nil, nil, err
+	}
+	if previous == nil {
+		if len(next.Aliases) > 0 {
+			changed = append(changed, aliasesField)
+		}
+		return changed, values, nil
+	}
+	if entity_alias.Changed(previous.Aliases, next.Aliases) {
+		changed = append(changed, aliasesField)
+		values[aliasesField] = entity_alias.Names(previous.Aliases)
+	}
+	return changed, values, nil
+}
+
 func (p *StaffProcessorImpl) parseToEntity(ctx context.Context, data Schema) (*anime_staff.AnimeStaff, error) {
-	return &anime_staff.AnimeStaff{
+	staff := &anime_staff.AnimeStaff{
 		ID:         data.Id,
 		Language:   ptrToString(data.Language),
 		GivenName:  ptrToString(data.GivenName),
@@ -129,9 +414,13 @@ func (p *StaffProcessorImpl) parseToEntity(ctx context.Context, data Schema) (*a
 		BloodType:  ptrToString(data.BloodType),
 		Hobbies:    ptrToString(data.Hobbies),
 		Summary:    ptrToString(data.Summary),
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-	}, nil
+		CreatedAt:  debezium.CreatedAt(data.CreatedAt),
+		UpdatedAt:  debezium.UpdatedAt(data.UpdatedAt),
+		Aliases: entity_alias.Build(entity_alias.NativeLanguage(ptrToString(data.Language)),
+			data.NameNative, data.NameRomaji, data.NameEnglish, data.Nicknames),
+	}
+	attributes.NormalizeStaff(ctx, staff)
+	return staff, nil
 }
 func ptrToString(s *string) string {
 	if s == nil {